@@ -1,6 +1,7 @@
 package gowindams
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -9,7 +10,7 @@ import (
 type Asset struct {
 	Id            *string            `json:"id"`
 	Attributes    map[string] string `json:"attributes"`
-	DateOfInstall *string            `json:"dateOfInstall"`
+	DateOfInstall *WindAMSDate       `json:"dateOfInstall"`
 	Location      *GeoPoint          `json:"location"`
 	Make          *string            `json:"make"`
 	Model         *string            `json:"model"`
@@ -17,13 +18,18 @@ type Asset struct {
 	SerialNumber  *string            `json:"serialNumber"`
 	SiteId        *string            `json:"siteId"`
 	Type          *string            `json:"type"`
+	Version       *int64             `json:"version,omitempty"`
 }
 
 type AssetSearchCriteria struct {
+	SearchPage
+	SearchSort
 	AssetId       *string            `json:"assetId"`
 	SiteId        *string            `json:"siteId"`
 	AssetType     *string            `json:"assetType"`
+	AssetTypes    []string           `json:"assetTypes,omitempty"`
 	Name          *string            `json:"name"`
+	NamePrefix    *string            `json:"namePrefix,omitempty"`
 	SerialNumber  *string            `json:"serialNumber"`
 }
 
@@ -31,6 +37,7 @@ const ASSET_TYPE_SOLAR_PANEL = "Solar_Panel"
 const ASSET_TYPE_WIND_TURBINE = "Wind_Turbine_Tower"
 
 const assetRootURI = "%s/asset"
+const assetDeleteURI = assetRootURI + "/%s"
 const assetGetURI = assetRootURI + "/%s"
 const assetSaveURI = assetRootURI
 const assetSearchURI = assetRootURI + "/search"
@@ -40,12 +47,23 @@ type AssetServiceClient struct {
 }
 
 func (client AssetServiceClient) Create(obj *Asset) error {
+	if obj.Id == nil && client.env.GenerateIds {
+		id := NewUUID()
+		obj.Id = &id
+	}
 	data, err := json.Marshal(obj)
 	if err != nil {
 		return err
 	}
 	url := fmt.Sprintf(assetSaveURI, client.env.ServiceURI)
-	err = executeRestCall(client.env, "PUT", url, data, nil)
+	err = executeSaveCall(client.env, "PUT", url, data, obj)
+	return err
+}
+
+func (client AssetServiceClient) Delete(id string) error {
+	log.Printf("Deleting asset %s", id)
+	url := fmt.Sprintf(assetDeleteURI, client.env.ServiceURI, id)
+	err := executeRestCall(client.env, "DELETE", url, nil, nil)
 	return err
 }
 
@@ -68,12 +86,37 @@ func (client AssetServiceClient) Search(criteria *AssetSearchCriteria) ([]Asset,
 	return results, err
 }
 
+type AssetIterator struct {
+	*searchIterator
+	value *Asset
+}
+
+func (it *AssetIterator) Next() bool {
+	it.value = new(Asset)
+	return it.next(it.value)
+}
+
+func (it *AssetIterator) Value() *Asset {
+	return it.value
+}
+
+func (client AssetServiceClient) Iterate(ctx context.Context, criteria *AssetSearchCriteria, pageSize int) *AssetIterator {
+	c := AssetSearchCriteria{}
+	if criteria != nil {
+		c = *criteria
+	}
+	url := fmt.Sprintf(assetSearchURI, client.env.ServiceURI)
+	return &AssetIterator{
+		searchIterator: newSearchIterator(ctx, client.env, url, &c, &c.SearchPage, pageSize),
+	}
+}
+
 func (client AssetServiceClient) Update(obj *Asset) error {
 	data, err := json.Marshal(obj)
 	if err != nil {
 		return err
 	}
 	url := fmt.Sprintf(assetSaveURI, client.env.ServiceURI)
-	err = executeRestCall(client.env, "POST", url, data, nil)
+	err = executeUpdateCall(client.env, url, data, obj, obj.Version)
 	return err
 }
@@ -1,6 +1,7 @@
 package gowindams
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -10,21 +11,28 @@ type AssetInspection struct {
 	Id               *string            `json:"id"`
 	AssetId          *string            `json:"assetId"`
 	Attributes       map[string] string `json:"attributes"`
-	DateOfInspection *string            `json:"dateOfInspection"`
-	FailureDate      *string            `json:"failureDate"`
+	DateOfInspection *WindAMSDate       `json:"dateOfInspection"`
+	FailureDate      *WindAMSDate       `json:"failureDate"`
 	OrderNumber      *string            `json:"orderNumber"`
 	ProcessedBy      *string            `json:"processedBy"`
 	Resources        []string           `json:"resources"`
 	SiteId           *string            `json:"siteId"`
 	Status           *string            `json:"status"`
 	Type             *string            `json:"type"`
+	Version          *int64             `json:"version,omitempty"`
 }
 
 type AssetInspectionSearchCriteria struct {
-	AssetId          *string            `json:"assetId"`
-	SiteId           *string            `json:"siteId"`
-	OrderNumber      *string            `json:"orderNumber"`
-	Status           *string            `json:"status"`
+	SearchPage
+	SearchSort
+	AssetId              *string      `json:"assetId"`
+	SiteId               *string      `json:"siteId"`
+	OrderNumber          *string      `json:"orderNumber"`
+	Status               *string      `json:"status"`
+	Statuses             []string     `json:"statuses,omitempty"`
+	Types                []string     `json:"types,omitempty"`
+	DateOfInspectionFrom *WindAMSDate `json:"dateOfInspectionFrom,omitempty"`
+	DateOfInspectionTo   *WindAMSDate `json:"dateOfInspectionTo,omitempty"`
 }
 
 const ASSET_INSPECTION_STATUS_IN_PROCESS = "In_Process"
@@ -37,6 +45,7 @@ const ASSET_INSPECTION_TYPE_DRONE = "DroneBladeInspection"
 const ASSET_INSPECTION_TYPE_GROUND = "GroundBladeInspection"
 
 const assetInspectionRootURI = "%s/assetInspection"
+const assetInspectionDeleteURI = assetInspectionRootURI + "/%s"
 const assetInspectionGetURI = assetInspectionRootURI + "/%s"
 const assetInspectionSaveURI = assetInspectionRootURI
 const assetInspectionSearchURI = assetInspectionRootURI + "/search"
@@ -46,12 +55,23 @@ type AssetInspectionServiceClient struct {
 }
 
 func (client AssetInspectionServiceClient) Create(obj *AssetInspection) error {
+	if obj.Id == nil && client.env.GenerateIds {
+		id := NewUUID()
+		obj.Id = &id
+	}
 	data, err := json.Marshal(obj)
 	if err != nil {
 		return err
 	}
 	url := fmt.Sprintf(assetInspectionSaveURI, client.env.ServiceURI)
-	err = executeRestCall(client.env, "PUT", url, data, nil)
+	err = executeSaveCall(client.env, "PUT", url, data, obj)
+	return err
+}
+
+func (client AssetInspectionServiceClient) Delete(id string) error {
+	log.Printf("Deleting asset inspection %s", id)
+	url := fmt.Sprintf(assetInspectionDeleteURI, client.env.ServiceURI, id)
+	err := executeRestCall(client.env, "DELETE", url, nil, nil)
 	return err
 }
 
@@ -74,12 +94,37 @@ func (client AssetInspectionServiceClient) Search(criteria *AssetInspectionSearc
 	return results, err
 }
 
+type AssetInspectionIterator struct {
+	*searchIterator
+	value *AssetInspection
+}
+
+func (it *AssetInspectionIterator) Next() bool {
+	it.value = new(AssetInspection)
+	return it.next(it.value)
+}
+
+func (it *AssetInspectionIterator) Value() *AssetInspection {
+	return it.value
+}
+
+func (client AssetInspectionServiceClient) Iterate(ctx context.Context, criteria *AssetInspectionSearchCriteria, pageSize int) *AssetInspectionIterator {
+	c := AssetInspectionSearchCriteria{}
+	if criteria != nil {
+		c = *criteria
+	}
+	url := fmt.Sprintf(assetInspectionSearchURI, client.env.ServiceURI)
+	return &AssetInspectionIterator{
+		searchIterator: newSearchIterator(ctx, client.env, url, &c, &c.SearchPage, pageSize),
+	}
+}
+
 func (client AssetInspectionServiceClient) Update(obj *AssetInspection) error {
 	data, err := json.Marshal(obj)
 	if err != nil {
 		return err
 	}
 	url := fmt.Sprintf(assetInspectionSaveURI, client.env.ServiceURI)
-	err = executeRestCall(client.env, "POST", url, data, nil)
+	err = executeUpdateCall(client.env, url, data, obj, obj.Version)
 	return err
 }
@@ -0,0 +1,437 @@
+package gowindams
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"sort"
+	"time"
+)
+
+// Version of the backup archive layout.  Archives written by a later version are refused.
+const BackupFormatVersion = 1
+
+const backupManifestFile = "manifest.json"
+const backupBinariesDir = "binaries"
+
+const backupSiteFile = "site.json"
+const backupWorkOrdersFile = "workOrders.json"
+const backupAssetsFile = "assets.json"
+const backupComponentsFile = "components.json"
+const backupAssetInspectionsFile = "assetInspections.json"
+const backupComponentInspectionsFile = "componentInspections.json"
+const backupResourcesFile = "resources.json"
+const backupInspectionEventResourcesFile = "inspectionEventResources.json"
+
+type BackupOptions struct {
+	// Only back up inspections, resources and work orders for this order number.
+	OrderNumber  *string
+	SkipBinaries bool
+	Parallelism  int
+}
+
+type BackupFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Written last to the archive, describing where it came from and holding a checksum for every other file.
+type BackupManifest struct {
+	FormatVersion int            `json:"formatVersion"`
+	CreatedAt     WindAMSTime    `json:"createdAt"`
+	Environment   string         `json:"environment"`
+	ServiceURI    string         `json:"serviceURI"`
+	SiteId        string         `json:"siteId"`
+	OrderNumber   *string        `json:"orderNumber,omitempty"`
+	Counts        map[string]int `json:"counts"`
+	Files         []BackupFile   `json:"files"`
+}
+
+// Writes a site with everything beneath it, and the images of its resources, to a zip archive.  Entities are stored
+// as JSON arrays, one file per type, and binaries under binaries/ named by resource id.
+func BackupSite(env *Environment, siteId string, w io.Writer, options *BackupOptions) (*BackupManifest, error) {
+	if options == nil {
+		options = &BackupOptions{}
+	}
+	source := environmentSource{env: env}
+	snapshot, err := loadSnapshot(source, siteId, options.OrderNumber, options.Parallelism)
+	if err != nil {
+		return nil, err
+	}
+
+	bw := &backupWriter{zw: zip.NewWriter(w)}
+	manifest := &BackupManifest{
+		FormatVersion: BackupFormatVersion,
+		CreatedAt:     WindAMSTime(time.Now()),
+		Environment:   env.Name,
+		ServiceURI:    env.ServiceURI,
+		SiteId:        siteId,
+		OrderNumber:   options.OrderNumber,
+		Counts:        snapshot.counts(),
+	}
+	for _, part := range []struct {
+		name  string
+		value interface{}
+	}{
+		{backupSiteFile, snapshot.site},
+		{backupWorkOrdersFile, snapshot.workOrders},
+		{backupAssetsFile, snapshot.assets},
+		{backupComponentsFile, snapshot.components},
+		{backupAssetInspectionsFile, snapshot.assetInspections},
+		{backupComponentInspectionsFile, snapshot.componentInspections},
+		{backupResourcesFile, snapshot.resources},
+		{backupInspectionEventResourcesFile, snapshot.inspectionEventResources},
+	} {
+		if err = bw.writeJSON(part.name, part.value); err != nil {
+			return nil, err
+		}
+	}
+	if !options.SkipBinaries {
+		for _, rmeta := range snapshot.resources {
+			if rmeta.ResourceId == nil {
+				continue
+			}
+			if err = bw.writeBinary(source, *rmeta.ResourceId); err != nil {
+				return nil, err
+			}
+			manifest.Counts[idMappingResourceBinary]++
+		}
+	}
+	manifest.Files = bw.files
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	f, err := bw.zw.Create(backupManifestFile)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = f.Write(data); err != nil {
+		return nil, err
+	}
+	if err = bw.zw.Close(); err != nil {
+		return nil, err
+	}
+	log.Printf("GOWINDAMS: Backed up site %s from %s: %v", siteId, env.Name, manifest.Counts)
+	return manifest, nil
+}
+
+type backupWriter struct {
+	zw    *zip.Writer
+	files []BackupFile
+}
+
+func (bw *backupWriter) create(name string) (io.Writer, func(), error) {
+	f, err := bw.zw.Create(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	h := sha256.New()
+	counter := &countingWriter{}
+	done := func() {
+		bw.files = append(bw.files, BackupFile{Path: name, Size: counter.n, SHA256: hex.EncodeToString(h.Sum(nil))})
+	}
+	return io.MultiWriter(f, h, counter), done, nil
+}
+
+func (bw *backupWriter) writeJSON(name string, v interface{}) error {
+	w, done, err := bw.create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(v); err != nil {
+		return err
+	}
+	done()
+	return nil
+}
+
+func (bw *backupWriter) writeBinary(source copySource, resourceId string) error {
+	body, err := source.download(resourceId)
+	if err != nil {
+		return fmt.Errorf("Unable to download resource %s: %w", resourceId, err)
+	}
+	defer body.Close()
+	w, done, err := bw.create(path.Join(backupBinariesDir, resourceId))
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, body); err != nil {
+		return err
+	}
+	done()
+	return nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// The entities of a site as flat lists, as stored in a backup.
+type siteSnapshot struct {
+	site                     *Site
+	workOrders               []WorkOrder
+	assets                   []Asset
+	components               []Component
+	assetInspections         []AssetInspection
+	componentInspections     []ComponentInspection
+	resources                []ResourceMetadata
+	inspectionEventResources []InspectionEventResource
+}
+
+func snapshotOf(tree *SiteTree) *siteSnapshot {
+	s := &siteSnapshot{site: tree.Site}
+	for _, an := range tree.Assets {
+		s.assets = append(s.assets, *an.Asset)
+		for _, ain := range an.Inspections {
+			s.assetInspections = append(s.assetInspections, *ain.AssetInspection)
+		}
+		for _, cn := range an.Components {
+			s.components = append(s.components, *cn.Component)
+			for _, cin := range cn.Inspections {
+				s.componentInspections = append(s.componentInspections, *cin.ComponentInspection)
+			}
+		}
+	}
+	for _, rn := range tree.Resources() {
+		s.resources = append(s.resources, *rn.Resource)
+	}
+	return s
+}
+
+// Loads a site with its work orders and everything beneath it, restricted to one order number when given.
+func loadSnapshot(source copySource, siteId string, orderNumber *string, parallelism int) (*siteSnapshot, error) {
+	tree, err := source.siteTree(siteId, &CopyOptions{OrderNumber: orderNumber, Parallelism: parallelism})
+	if err != nil {
+		return nil, err
+	}
+	if tree.Site == nil {
+		return nil, fmt.Errorf("The site %s was not found in %s", siteId, source.sourceName())
+	}
+	snapshot := snapshotOf(tree)
+	if err = snapshot.loadRelated(source, siteId, orderNumber); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Adds the entities which are not held in a site tree: the site's work orders and the inspection event resources of
+// its resources.
+func (s *siteSnapshot) loadRelated(source copySource, siteId string, orderNumber *string) error {
+	workOrders, err := source.workOrders(siteId)
+	if err != nil {
+		return err
+	}
+	s.workOrders = make([]WorkOrder, 0, len(workOrders))
+	for _, wo := range workOrders {
+		if orderNumber == nil || stringValue(wo.OrderNumber) == *orderNumber {
+			s.workOrders = append(s.workOrders, wo)
+		}
+	}
+	for _, rmeta := range s.resources {
+		if rmeta.ResourceId == nil {
+			continue
+		}
+		iers, err := source.inspectionEventResources(*rmeta.ResourceId)
+		if err != nil {
+			return err
+		}
+		for _, ier := range iers {
+			if orderNumber == nil || ier.OrderNumber == nil || *ier.OrderNumber == *orderNumber {
+				s.inspectionEventResources = append(s.inspectionEventResources, ier)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *siteSnapshot) counts() map[string]int {
+	return map[string]int{
+		EntityTypeSite:                    1,
+		EntityTypeWorkOrder:               len(s.workOrders),
+		EntityTypeAsset:                   len(s.assets),
+		EntityTypeComponent:               len(s.components),
+		EntityTypeAssetInspection:         len(s.assetInspections),
+		EntityTypeComponentInspection:     len(s.componentInspections),
+		EntityTypeResource:                len(s.resources),
+		EntityTypeInspectionEventResource: len(s.inspectionEventResources),
+	}
+}
+
+// An open backup archive.  Close must be called when done.
+type Backup struct {
+	Manifest *BackupManifest
+
+	zr       *zip.ReadCloser
+	files    map[string]*zip.File
+	snapshot *siteSnapshot
+}
+
+func OpenBackup(archivePath string) (*Backup, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	b := &Backup{zr: zr, files: make(map[string]*zip.File)}
+	for _, f := range zr.File {
+		b.files[f.Name] = f
+	}
+	b.Manifest = new(BackupManifest)
+	if err = b.readJSON(backupManifestFile, b.Manifest); err != nil {
+		zr.Close()
+		return nil, fmt.Errorf("Unable to read the backup manifest: %w", err)
+	}
+	if b.Manifest.FormatVersion < 1 || b.Manifest.FormatVersion > BackupFormatVersion {
+		zr.Close()
+		return nil, fmt.Errorf("Unsupported backup format version %d", b.Manifest.FormatVersion)
+	}
+	return b, nil
+}
+
+func (b *Backup) Close() error {
+	return b.zr.Close()
+}
+
+func (b *Backup) open(name string) (io.ReadCloser, error) {
+	f, ok := b.files[name]
+	if !ok {
+		return nil, fmt.Errorf("The backup has no file %s", name)
+	}
+	return f.Open()
+}
+
+func (b *Backup) readJSON(name string, v interface{}) error {
+	r, err := b.open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return json.NewDecoder(r).Decode(v)
+}
+
+// Checks every file listed in the manifest is present with the recorded size and checksum.
+func (b *Backup) Verify() error {
+	problems := make([]string, 0)
+	for _, file := range b.Manifest.Files {
+		r, err := b.open(file.Path)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		h := sha256.New()
+		n, err := io.Copy(h, r)
+		r.Close()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", file.Path, err))
+		} else if n != file.Size || hex.EncodeToString(h.Sum(nil)) != file.SHA256 {
+			problems = append(problems, fmt.Sprintf("%s does not match its checksum", file.Path))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("The backup is damaged: %v", problems)
+	}
+	return nil
+}
+
+func (b *Backup) load() (*siteSnapshot, error) {
+	if b.snapshot != nil {
+		return b.snapshot, nil
+	}
+	s := &siteSnapshot{}
+	for _, part := range []struct {
+		name  string
+		value interface{}
+	}{
+		{backupSiteFile, &s.site},
+		{backupWorkOrdersFile, &s.workOrders},
+		{backupAssetsFile, &s.assets},
+		{backupComponentsFile, &s.components},
+		{backupAssetInspectionsFile, &s.assetInspections},
+		{backupComponentInspectionsFile, &s.componentInspections},
+		{backupResourcesFile, &s.resources},
+		{backupInspectionEventResourcesFile, &s.inspectionEventResources},
+	} {
+		if err := b.readJSON(part.name, part.value); err != nil {
+			return nil, fmt.Errorf("Unable to read %s from the backup: %w", part.name, err)
+		}
+	}
+	b.snapshot = s
+	return s, nil
+}
+
+// The backed up site as a tree.
+func (b *Backup) SiteTree() (*SiteTree, error) {
+	s, err := b.load()
+	if err != nil {
+		return nil, err
+	}
+	return BuildSiteTree(s.site, s.assets, s.components, s.assetInspections, s.componentInspections, s.resources), nil
+}
+
+func (b *Backup) sourceName() string {
+	return "backup of " + b.Manifest.Environment
+}
+
+func (b *Backup) siteTree(siteId string, options *CopyOptions) (*SiteTree, error) {
+	if siteId != b.Manifest.SiteId {
+		return nil, fmt.Errorf("The backup holds site %s, not %s", b.Manifest.SiteId, siteId)
+	}
+	return b.SiteTree()
+}
+
+func (b *Backup) workOrders(siteId string) ([]WorkOrder, error) {
+	s, err := b.load()
+	if err != nil {
+		return nil, err
+	}
+	return s.workOrders, nil
+}
+
+func (b *Backup) inspectionEventResources(resourceId string) ([]InspectionEventResource, error) {
+	s, err := b.load()
+	if err != nil {
+		return nil, err
+	}
+	found := make([]InspectionEventResource, 0)
+	for _, ier := range s.inspectionEventResources {
+		if stringValue(ier.ResourceId) == resourceId {
+			found = append(found, ier)
+		}
+	}
+	return found, nil
+}
+
+func (b *Backup) download(resourceId string) (io.ReadCloser, error) {
+	return b.open(path.Join(backupBinariesDir, resourceId))
+}
+
+// Recreates a backed up site in an environment after verifying the archive.  The restore works as a copy from the
+// backup, so by default new ids are assigned, and the options' KeepIds and OnConflict control restoring over existing
+// data.  Binaries missing from the archive, as when it was made with SkipBinaries, are not restored.
+func RestoreSite(env *Environment, backup *Backup, ids *IdMapping, options *CopyOptions) (*CopyReport, error) {
+	if err := backup.Verify(); err != nil {
+		return nil, err
+	}
+	restoreOptions := CopyOptions{}
+	if options != nil {
+		restoreOptions = *options
+	}
+	if backup.Manifest.Counts[idMappingResourceBinary] == 0 {
+		restoreOptions.SkipBinaries = true
+	}
+	return newCopier(backup, env, ids, &restoreOptions).CopySite(backup.Manifest.SiteId)
+}
@@ -0,0 +1,146 @@
+package gowindams
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+var ErrBatchAborted = errors.New("Skipped after an earlier failure in the batch")
+
+type BatchOptions struct {
+	// Maximum number of requests in flight, defaults to DEFAULT_PARALLELISM.
+	Parallelism int
+	// Maximum number of requests started per second, unlimited if zero or above a billion.
+	RequestsPerSecond float64
+	// Stop starting new requests after the first failure.  Items not attempted are reported with ErrBatchAborted.
+	StopOnError bool
+}
+
+type BatchResult struct {
+	Index int
+	Id    *string
+	Err   error
+}
+
+type BatchResults []BatchResult
+
+func (results BatchResults) Failed() BatchResults {
+	failed := make(BatchResults, 0)
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// Nil if every item succeeded, otherwise an error summarizing the failures.
+func (results BatchResults) Err() error {
+	failed := results.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d batch items failed, the first (item %d) with: %s", len(failed), len(results), failed[0].Index, failed[0].Err)
+}
+
+// Calls fn for each index from 0 to n-1 with bounded concurrency and an optional rate limit, collecting the id and
+// error returned for each item.  Results are in index order.
+func RunBatch(n int, options *BatchOptions, fn func(i int) (*string, error)) BatchResults {
+	if options == nil {
+		options = &BatchOptions{}
+	}
+	parallelism := options.Parallelism
+	if parallelism <= 0 {
+		parallelism = DEFAULT_PARALLELISM
+	}
+	var ticker *time.Ticker
+	// Rates too high for the interval to be measured in nanoseconds are as good as unlimited.
+	if interval := time.Duration(float64(time.Second) / options.RequestsPerSecond); options.RequestsPerSecond > 0 && interval > 0 {
+		ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+	}
+
+	results := make(BatchResults, n)
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	aborted := false
+	for i := 0; i < n; i++ {
+		results[i].Index = i
+		sem <- struct{}{}
+		mu.Lock()
+		skip := aborted
+		mu.Unlock()
+		if skip {
+			<-sem
+			results[i].Err = ErrBatchAborted
+			continue
+		}
+		if ticker != nil && i > 0 {
+			<-ticker.C
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			id, err := fn(i)
+			results[i].Id = id
+			results[i].Err = err
+			if err != nil {
+				log.Printf("GOWINDAMS: Batch item %d failed: %s\n", i, err)
+				if options.StopOnError {
+					mu.Lock()
+					aborted = true
+					mu.Unlock()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+func (client AssetServiceClient) CreateBatch(objs []Asset, options *BatchOptions) BatchResults {
+	return RunBatch(len(objs), options, func(i int) (*string, error) {
+		err := client.Create(&objs[i])
+		return objs[i].Id, err
+	})
+}
+
+func (client AssetServiceClient) UpdateBatch(objs []Asset, options *BatchOptions) BatchResults {
+	return RunBatch(len(objs), options, func(i int) (*string, error) {
+		err := client.Update(&objs[i])
+		return objs[i].Id, err
+	})
+}
+
+func (client ComponentServiceClient) CreateBatch(objs []Component, options *BatchOptions) BatchResults {
+	return RunBatch(len(objs), options, func(i int) (*string, error) {
+		err := client.Create(&objs[i])
+		return objs[i].Id, err
+	})
+}
+
+func (client ComponentServiceClient) UpdateBatch(objs []Component, options *BatchOptions) BatchResults {
+	return RunBatch(len(objs), options, func(i int) (*string, error) {
+		err := client.Update(&objs[i])
+		return objs[i].Id, err
+	})
+}
+
+func (client WorkOrderServiceClient) CreateBatch(objs []WorkOrder, options *BatchOptions) BatchResults {
+	return RunBatch(len(objs), options, func(i int) (*string, error) {
+		err := client.Create(&objs[i])
+		return objs[i].OrderNumber, err
+	})
+}
+
+func (client WorkOrderServiceClient) UpdateBatch(objs []WorkOrder, options *BatchOptions) BatchResults {
+	return RunBatch(len(objs), options, func(i int) (*string, error) {
+		err := client.Update(&objs[i])
+		return objs[i].OrderNumber, err
+	})
+}
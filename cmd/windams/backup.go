@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func init() {
+	commands["backup"] = &command{
+		description: "Back up a site to a zip archive and restore it",
+		actions: map[string]action{
+			"site":    {"-out backup.zip [-order number] [-skip-binaries] <site id>", backupSite},
+			"verify":  {"<backup.zip>", backupVerify},
+			"restore": {"-mapping ids.ndjson [-keep-ids] [-on-conflict skip|overwrite|fail] <backup.zip>", backupRestore},
+		},
+	}
+}
+
+func backupSite(_ *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	out := fs.String("out", "", "Archive to write")
+	orderNumber := fs.String("order", "", "Only back up inspections and resources for this work order")
+	skipBinaries := fs.Bool("skip-binaries", false, "Back up resource metadata without the images")
+	siteId, err := oneArg(fs, args, "site id")
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return usagef("An archive to write is required")
+	}
+	env, err := loadEnvironment()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	options := gowindams.BackupOptions{SkipBinaries: *skipBinaries}
+	if *orderNumber != "" {
+		options.OrderNumber = orderNumber
+	}
+	manifest, err := gowindams.BackupSite(env, siteId, f, &options)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*out)
+		return err
+	}
+	return output(manifest.Counts)
+}
+
+func backupVerify(_ *gowindams.Environment, args []string) error {
+	path, err := oneArg(flag.NewFlagSet("verify", flag.ContinueOnError), args, "archive")
+	if err != nil {
+		return err
+	}
+	backup, err := gowindams.OpenBackup(path)
+	if err != nil {
+		return err
+	}
+	defer backup.Close()
+	if err = backup.Verify(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d files verified\n", path, len(backup.Manifest.Files))
+	return nil
+}
+
+func backupRestore(_ *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	mappingFile := fs.String("mapping", "", "File recording the ids of restored entities, reused to resume a restore")
+	keepIds := fs.Bool("keep-ids", false, "Restore entities with their original ids")
+	onConflict := fs.String("on-conflict", gowindams.ConflictSkip, "With -keep-ids, what to do with entities which already exist: skip, overwrite or fail")
+	path, err := oneArg(fs, args, "archive")
+	if err != nil {
+		return err
+	}
+	if *mappingFile == "" {
+		return usagef("A mapping file is required")
+	}
+	if err = checkConflictPolicy(*onConflict); err != nil {
+		return err
+	}
+	env, err := loadEnvironment()
+	if err != nil {
+		return err
+	}
+	backup, err := gowindams.OpenBackup(path)
+	if err != nil {
+		return err
+	}
+	defer backup.Close()
+	ids, err := gowindams.OpenIdMapping(*mappingFile)
+	if err != nil {
+		return err
+	}
+	defer ids.Close()
+	report, err := gowindams.RestoreSite(env, backup, ids, &gowindams.CopyOptions{KeepIds: *keepIds, OnConflict: *onConflict})
+	if report != nil {
+		if oerr := output(report); oerr != nil && err == nil {
+			err = oerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func init() {
+	commands["copy"] = &command{
+		description: "Copy data from the selected environment to another",
+		needsEnv:    true,
+		actions: map[string]action{
+			"site": {"-to env -mapping ids.ndjson [-order number] [-skip-binaries] [-keep-ids] [-on-conflict skip|overwrite|fail] <site id>", copySite},
+		},
+	}
+}
+
+func copySite(env *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("copy", flag.ContinueOnError)
+	to := fs.String("to", "", "Environment to copy to")
+	mappingFile := fs.String("mapping", "", "File recording the ids of copied entities, reused to resume a copy")
+	orderNumber := fs.String("order", "", "Only copy inspections and resources for this work order")
+	skipBinaries := fs.Bool("skip-binaries", false, "Copy resource metadata without the images")
+	parallelism := fs.Int("parallelism", gowindams.DEFAULT_PARALLELISM, "Resources copied at a time")
+	keepIds := fs.Bool("keep-ids", false, "Create entities with their ids from the source environment")
+	onConflict := fs.String("on-conflict", gowindams.ConflictSkip, "With -keep-ids, what to do with entities which already exist: skip, overwrite or fail")
+	siteId, err := oneArg(fs, args, "site id")
+	if err != nil {
+		return err
+	}
+	if *to == "" || *mappingFile == "" {
+		return usagef("A target environment and a mapping file are required")
+	}
+	if err = checkConflictPolicy(*onConflict); err != nil {
+		return err
+	}
+	envs, err := loadEnvironments()
+	if err != nil {
+		return err
+	}
+	target := envs.Find(*to)
+	if target == nil {
+		return fmt.Errorf("Unable to locate environment with name %q in %s", *to, configFile)
+	}
+	ids, err := gowindams.OpenIdMapping(*mappingFile)
+	if err != nil {
+		return err
+	}
+	defer ids.Close()
+	options := gowindams.CopyOptions{SkipBinaries: *skipBinaries, Parallelism: *parallelism, KeepIds: *keepIds, OnConflict: *onConflict}
+	if *orderNumber != "" {
+		options.OrderNumber = orderNumber
+	}
+	report, err := gowindams.NewCopier(env, target, ids, &options).CopySite(siteId)
+	if report != nil {
+		if oerr := output(report); oerr != nil && err == nil {
+			err = oerr
+		}
+	}
+	return err
+}
+
+func checkConflictPolicy(onConflict string) error {
+	switch onConflict {
+	case gowindams.ConflictSkip, gowindams.ConflictOverwrite, gowindams.ConflictFail:
+		return nil
+	}
+	return usagef("Unknown conflict policy %q", onConflict)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func init() {
+	commands["diff"] = &command{
+		description: "Compare the data held by the selected environment with another",
+		needsEnv:    true,
+		actions: map[string]action{
+			"site": {"-with env [-mapping ids.ndjson] [-ignore Type:field,...] [-order number] <site id>", diffSite},
+		},
+	}
+}
+
+func diffSite(env *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	with := fs.String("with", "", "Environment to compare with")
+	mappingFile := fs.String("mapping", "", "Id mapping recorded when copying between the environments")
+	ignore := fs.String("ignore", "", "Comma separated fields to ignore, optionally prefixed by an entity type, such as Asset:attributes.*,name")
+	orderNumber := fs.String("order", "", "Only compare inspections and resources for this work order")
+	siteId, err := oneArg(fs, args, "site id")
+	if err != nil {
+		return err
+	}
+	if *with == "" {
+		return usagef("An environment to compare with is required")
+	}
+	envs, err := loadEnvironments()
+	if err != nil {
+		return err
+	}
+	other := envs.Find(*with)
+	if other == nil {
+		return fmt.Errorf("Unable to locate environment with name %q in %s", *with, configFile)
+	}
+	options := gowindams.DiffOptions{Ignore: make(map[string][]string)}
+	if *mappingFile != "" {
+		if options.Ids, err = gowindams.LoadIdMapping(*mappingFile); err != nil {
+			return err
+		}
+	}
+	if *orderNumber != "" {
+		options.OrderNumber = orderNumber
+	}
+	for _, rule := range strings.Split(*ignore, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		entityType := ""
+		if i := strings.Index(rule, ":"); i >= 0 {
+			entityType, rule = rule[:i], rule[i+1:]
+		}
+		options.Ignore[entityType] = append(options.Ignore[entityType], rule)
+	}
+	report, err := gowindams.DiffSites(env, other, siteId, &options)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(stdout, report)
+	if len(report.Diffs) > 0 {
+		return fmt.Errorf("The environments differ")
+	}
+	return nil
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/Inspectools/gowindams"
+)
+
+// The operations shared by the entity service clients.  Criteria and entities are passed as JSON and decoded into the
+// client's own types.
+type entityOps struct {
+	description string
+	idName      string
+	filters     map[string]searchFilter
+	get         func(env *gowindams.Environment, id string) (interface{}, error)
+	search      func(env *gowindams.Environment, criteria []byte) (interface{}, error)
+	create      func(env *gowindams.Environment, data []byte) (interface{}, error)
+	update      func(env *gowindams.Environment, data []byte) (interface{}, error)
+}
+
+func init() {
+	commands["site"] = entityCommand(entityOps{
+		description: "Sites",
+		idName:      "site id",
+		get: func(env *gowindams.Environment, id string) (interface{}, error) {
+			return env.SiteServiceClient().Get(id)
+		},
+		search: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var criteria gowindams.SiteSearchCriteria
+			if err := json.Unmarshal(data, &criteria); err != nil {
+				return nil, err
+			}
+			return env.SiteServiceClient().Search(&criteria)
+		},
+		create: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var obj gowindams.Site
+			if err := json.Unmarshal(data, &obj); err != nil {
+				return nil, err
+			}
+			return &obj, env.SiteServiceClient().Create(&obj)
+		},
+		update: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var obj gowindams.Site
+			if err := json.Unmarshal(data, &obj); err != nil {
+				return nil, err
+			}
+			return &obj, env.SiteServiceClient().Update(&obj)
+		},
+	})
+	commands["asset"] = entityCommand(entityOps{
+		description: "Assets",
+		idName:      "asset id",
+		filters:     map[string]searchFilter{"site": {key: "siteId"}},
+		get: func(env *gowindams.Environment, id string) (interface{}, error) {
+			return env.AssetServiceClient().Get(id)
+		},
+		search: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var criteria gowindams.AssetSearchCriteria
+			if err := json.Unmarshal(data, &criteria); err != nil {
+				return nil, err
+			}
+			return env.AssetServiceClient().Search(&criteria)
+		},
+		create: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var obj gowindams.Asset
+			if err := json.Unmarshal(data, &obj); err != nil {
+				return nil, err
+			}
+			return &obj, env.AssetServiceClient().Create(&obj)
+		},
+		update: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var obj gowindams.Asset
+			if err := json.Unmarshal(data, &obj); err != nil {
+				return nil, err
+			}
+			return &obj, env.AssetServiceClient().Update(&obj)
+		},
+	})
+	commands["component"] = entityCommand(entityOps{
+		description: "Components of assets",
+		idName:      "component id",
+		filters:     map[string]searchFilter{"site": {key: "siteId"}, "asset": {key: "assetId"}},
+		get: func(env *gowindams.Environment, id string) (interface{}, error) {
+			return env.ComponentServiceClient().Get(id)
+		},
+		search: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var criteria gowindams.ComponentSearchCriteria
+			if err := json.Unmarshal(data, &criteria); err != nil {
+				return nil, err
+			}
+			return env.ComponentServiceClient().Search(&criteria)
+		},
+		create: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var obj gowindams.Component
+			if err := json.Unmarshal(data, &obj); err != nil {
+				return nil, err
+			}
+			return &obj, env.ComponentServiceClient().Create(&obj)
+		},
+		update: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var obj gowindams.Component
+			if err := json.Unmarshal(data, &obj); err != nil {
+				return nil, err
+			}
+			return &obj, env.ComponentServiceClient().Update(&obj)
+		},
+	})
+	commands["inspection"] = entityCommand(entityOps{
+		description: "Asset inspections",
+		idName:      "asset inspection id",
+		filters:     map[string]searchFilter{"site": {key: "siteId"}, "asset": {key: "assetId"}, "order": {key: "orderNumber"}, "status": {key: "status"}},
+		get: func(env *gowindams.Environment, id string) (interface{}, error) {
+			return env.AssetInspectionServiceClient().Get(id)
+		},
+		search: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var criteria gowindams.AssetInspectionSearchCriteria
+			if err := json.Unmarshal(data, &criteria); err != nil {
+				return nil, err
+			}
+			return env.AssetInspectionServiceClient().Search(&criteria)
+		},
+		create: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var obj gowindams.AssetInspection
+			if err := json.Unmarshal(data, &obj); err != nil {
+				return nil, err
+			}
+			return &obj, env.AssetInspectionServiceClient().Create(&obj)
+		},
+		update: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var obj gowindams.AssetInspection
+			if err := json.Unmarshal(data, &obj); err != nil {
+				return nil, err
+			}
+			return &obj, env.AssetInspectionServiceClient().Update(&obj)
+		},
+	})
+	commands["component-inspection"] = entityCommand(entityOps{
+		description: "Component inspections",
+		idName:      "component inspection id",
+		filters:     map[string]searchFilter{"site": {key: "siteId"}, "order": {key: "orderNumber"}, "status": {key: "status"}},
+		get: func(env *gowindams.Environment, id string) (interface{}, error) {
+			return env.ComponentInspectionServiceClient().Get(id)
+		},
+		search: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var criteria gowindams.ComponentInspectionSearchCriteria
+			if err := json.Unmarshal(data, &criteria); err != nil {
+				return nil, err
+			}
+			return env.ComponentInspectionServiceClient().Search(&criteria)
+		},
+		create: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var obj gowindams.ComponentInspection
+			if err := json.Unmarshal(data, &obj); err != nil {
+				return nil, err
+			}
+			return &obj, env.ComponentInspectionServiceClient().Create(&obj)
+		},
+		update: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var obj gowindams.ComponentInspection
+			if err := json.Unmarshal(data, &obj); err != nil {
+				return nil, err
+			}
+			return &obj, env.ComponentInspectionServiceClient().Update(&obj)
+		},
+	})
+	commands["workorder"] = entityCommand(entityOps{
+		description: "Work orders",
+		idName:      "order number",
+		filters:     map[string]searchFilter{"site": {key: "siteId"}, "status": {key: "statuses", list: true}},
+		get: func(env *gowindams.Environment, id string) (interface{}, error) {
+			return env.WorkOrderServiceClient().Get(id)
+		},
+		search: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var criteria gowindams.WorkOrderSearchCriteria
+			if err := json.Unmarshal(data, &criteria); err != nil {
+				return nil, err
+			}
+			return env.WorkOrderServiceClient().Search(&criteria)
+		},
+		create: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var obj gowindams.WorkOrder
+			if err := json.Unmarshal(data, &obj); err != nil {
+				return nil, err
+			}
+			return &obj, env.WorkOrderServiceClient().Create(&obj)
+		},
+		update: func(env *gowindams.Environment, data []byte) (interface{}, error) {
+			var obj gowindams.WorkOrder
+			if err := json.Unmarshal(data, &obj); err != nil {
+				return nil, err
+			}
+			return &obj, env.WorkOrderServiceClient().Update(&obj)
+		},
+	})
+}
+
+func entityCommand(ops entityOps) *command {
+	return &command{
+		description: ops.description,
+		needsEnv:    true,
+		actions: map[string]action{
+			"get": {"<" + ops.idName + ">", func(env *gowindams.Environment, args []string) error {
+				id, err := oneArg(flag.NewFlagSet("get", flag.ContinueOnError), args, ops.idName)
+				if err != nil {
+					return err
+				}
+				result, err := ops.get(env, id)
+				if err != nil {
+					return err
+				}
+				return output(result)
+			}},
+			"search": {searchUsage(ops.filters), func(env *gowindams.Environment, args []string) error {
+				criteria, err := searchCriteria(args, ops.filters)
+				if err != nil {
+					return err
+				}
+				result, err := ops.search(env, criteria)
+				if err != nil {
+					return err
+				}
+				return output(result)
+			}},
+			"create": {"[-f entity.json]", func(env *gowindams.Environment, args []string) error {
+				return saveEntity(env, args, ops.create)
+			}},
+			"update": {"[-f entity.json]", func(env *gowindams.Environment, args []string) error {
+				return saveEntity(env, args, ops.update)
+			}},
+		},
+	}
+}
+
+// A criteria field set by one of the search filter flags.  A list field is set to the single value given.
+type searchFilter struct {
+	key  string
+	list bool
+}
+
+// The search filter flags, in the order they are listed in usage, with the name of their value.
+var searchFilterFlags = []struct {
+	name  string
+	value string
+	usage string
+}{
+	{"site", "id", "Site id"},
+	{"asset", "id", "Asset id"},
+	{"order", "number", "Work order number"},
+	{"status", "status", "Status"},
+}
+
+func searchUsage(filters map[string]searchFilter) string {
+	usage := "[-f criteria.json] [-q json]"
+	for _, f := range searchFilterFlags {
+		if _, ok := filters[f.name]; ok {
+			usage += fmt.Sprintf(" [-%s %s]", f.name, f.value)
+		}
+	}
+	return usage
+}
+
+// Builds search criteria from a JSON file or inline JSON, overlaid with the filters given as flags.  Filters which the
+// criteria type does not have are refused rather than silently dropped when it is decoded.
+func searchCriteria(args []string, filters map[string]searchFilter) ([]byte, error) {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	file := fs.String("f", "", "File holding the search criteria as JSON, - for standard input")
+	inline := fs.String("q", "", "Search criteria as JSON")
+	values := make(map[string]*string)
+	for _, f := range searchFilterFlags {
+		values[f.name] = fs.String(f.name, "", f.usage)
+	}
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(positional) > 0 {
+		return nil, usagef("Unexpected arguments %v", positional)
+	}
+	criteria := make(map[string]interface{})
+	var data []byte
+	if *file != "" {
+		if data, err = readInput(*file); err != nil {
+			return nil, err
+		}
+	} else if *inline != "" {
+		data = []byte(*inline)
+	}
+	if data != nil {
+		if err = json.Unmarshal(data, &criteria); err != nil {
+			return nil, usagef("Invalid search criteria: %s", err)
+		}
+	}
+	for _, f := range searchFilterFlags {
+		value := *values[f.name]
+		if value == "" {
+			continue
+		}
+		filter, ok := filters[f.name]
+		switch {
+		case !ok:
+			return nil, usagef("Unable to filter on -%s when searching these entities", f.name)
+		case filter.list:
+			criteria[filter.key] = []string{value}
+		default:
+			criteria[filter.key] = value
+		}
+	}
+	return json.Marshal(criteria)
+}
+
+func saveEntity(env *gowindams.Environment, args []string, save func(*gowindams.Environment, []byte) (interface{}, error)) error {
+	fs := flag.NewFlagSet("save", flag.ContinueOnError)
+	file := fs.String("f", "-", "File holding the entity as JSON, - for standard input")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usagef("Unexpected arguments %v", positional)
+	}
+	data, err := readInput(*file)
+	if err != nil {
+		return err
+	}
+	result, err := save(env, data)
+	if err != nil {
+		return err
+	}
+	return output(result)
+}
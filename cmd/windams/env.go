@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/Inspectools/gowindams"
+)
+
+type environmentSummary struct {
+	Name           string   `json:"name"`
+	ServiceURI     string   `json:"serviceURI"`
+	ClientId       string   `json:"clientId,omitempty"`
+	TenantId       string   `json:"tenantId,omitempty"`
+	ServiceAppId   string   `json:"serviceAppId,omitempty"`
+	SearchFeatures []string `json:"searchFeatures,omitempty"`
+	GenerateIds    bool     `json:"generateIds,omitempty"`
+	UserId         string   `json:"userId,omitempty"`
+}
+
+func summarize(env *gowindams.Environment) environmentSummary {
+	return environmentSummary{
+		Name:           env.Name,
+		ServiceURI:     env.ServiceURI,
+		ClientId:       env.ClientId,
+		TenantId:       env.TenantId,
+		ServiceAppId:   env.ServiceAppId,
+		SearchFeatures: env.SearchFeatures,
+		GenerateIds:    env.GenerateIds,
+		UserId:         env.UserId,
+	}
+}
+
+func init() {
+	commands["env"] = &command{
+		description: "Environments defined in the config file",
+		actions: map[string]action{
+			"list": {"", func(_ *gowindams.Environment, args []string) error {
+				if _, err := parseFlags(flag.NewFlagSet("list", flag.ContinueOnError), args); err != nil {
+					return err
+				}
+				envs, err := loadEnvironments()
+				if err != nil {
+					return err
+				}
+				summaries := make([]environmentSummary, len(*envs))
+				for i := range *envs {
+					summaries[i] = summarize(&(*envs)[i])
+				}
+				return output(summaries)
+			}},
+			"show": {"[name]", func(_ *gowindams.Environment, args []string) error {
+				positional, err := parseFlags(flag.NewFlagSet("show", flag.ContinueOnError), args)
+				if err != nil {
+					return err
+				}
+				name := environmentName
+				if len(positional) == 1 {
+					name = positional[0]
+				} else if len(positional) > 1 {
+					return usagef("Expected at most one environment name")
+				}
+				envs, err := loadEnvironments()
+				if err != nil {
+					return err
+				}
+				env := envs.Find(name)
+				if env == nil {
+					return fmt.Errorf("Unable to locate environment with name %q in %s", name, configFile)
+				}
+				return output(summarize(env))
+			}},
+		},
+	}
+}
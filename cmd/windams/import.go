@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func init() {
+	commands["import"] = &command{
+		description: "Bulk import of assets and components from spreadsheets",
+		needsEnv:    true,
+		actions: map[string]action{
+			"run": {"-mapping mapping.yaml [-dry-run] [-sheet name] <file.csv|file.xlsx>", importRun},
+		},
+	}
+}
+
+func importRun(env *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	mappingFile := fs.String("mapping", "", "YAML file mapping columns to fields")
+	dryRun := fs.Bool("dry-run", false, "Report the changes without saving them")
+	sheet := fs.String("sheet", "", "Sheet to read from a workbook, overriding the mapping")
+	path, err := oneArg(fs, args, "file")
+	if err != nil {
+		return err
+	}
+	if *mappingFile == "" {
+		return usagef("A mapping file is required")
+	}
+	mapping, err := gowindams.LoadImportMapping(*mappingFile)
+	if err != nil {
+		return err
+	}
+	if *sheet != "" {
+		mapping.Sheet = *sheet
+	}
+	report, err := gowindams.ImportFile(env, path, mapping, &gowindams.ImportOptions{DryRun: *dryRun})
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(stdout, report)
+	if n := report.Count(gowindams.ImportActionError); n > 0 {
+		return fmt.Errorf("%d rows could not be imported", n)
+	}
+	return nil
+}
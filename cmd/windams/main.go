@@ -0,0 +1,219 @@
+// Command windams is a command line client for the WindAMS services.
+//
+// Usage:
+//
+//	windams [-c config] [-env name] <command> <action> [flags] [args]
+//
+// Run windams without arguments for the list of commands.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Inspectools/gowindams"
+)
+
+const envEnvironmentName = "WINDAMS_ENV"
+const envConfigFile = "WINDAMS_CONFIG"
+const defaultEnvironmentName = "Local Dev"
+
+// A subcommand receives the selected environment, which is nil for commands that do not need one, and the arguments
+// following its action.
+type action struct {
+	usage string
+	run   func(env *gowindams.Environment, args []string) error
+}
+
+type command struct {
+	description string
+	needsEnv    bool
+	actions     map[string]action
+}
+
+var commands = map[string]*command{}
+
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+var (
+	configFile      string
+	environmentName string
+	outputOptions   gowindams.FormatOptions
+	columns         string
+	stdout          io.Writer = os.Stdout
+)
+
+func main() {
+	flag.StringVar(&configFile, "c", defaultConfigFile(), "Environments config file")
+	flag.StringVar(&environmentName, "env", defaultEnvironment(), "Environment to connect to, defaults to $"+envEnvironmentName)
+	flag.StringVar(&outputOptions.Format, "o", gowindams.FormatJSON, "Output format: "+strings.Join(gowindams.Formats, ", "))
+	flag.StringVar(&columns, "columns", "", "Comma separated fields to output, such as id,name,location.latitude")
+	flag.StringVar(&outputOptions.Template, "template", "", "Go template executed for each result, implies -o template")
+	flag.BoolVar(&outputOptions.NoHeader, "no-header", false, "Leave out the header row of table and CSV output")
+	flag.Usage = usage
+	flag.Parse()
+	if columns != "" {
+		outputOptions.Columns = strings.Split(columns, ",")
+	}
+	if outputOptions.Template != "" {
+		outputOptions.Format = gowindams.FormatTemplate
+	}
+	if !gowindams.ValidFormat(outputOptions.Format) {
+		fmt.Fprintf(os.Stderr, "Unknown output format %q\n", outputOptions.Format)
+		usage()
+		os.Exit(2)
+	}
+	args := flag.Args()
+	if len(args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n", args[0])
+		usage()
+		os.Exit(2)
+	}
+	act, ok := cmd.actions[args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown action %q for %s, expected one of: %s\n", args[1], args[0], strings.Join(actionNames(cmd), ", "))
+		os.Exit(2)
+	}
+	var env *gowindams.Environment
+	if cmd.needsEnv {
+		var err error
+		if env, err = loadEnvironment(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if err := act.run(env, args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if _, ok := err.(usageError); ok {
+			fmt.Fprintf(os.Stderr, "Usage: windams %s %s %s\n", args[0], args[1], act.usage)
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: windams [flags] <command> <action> [args]\n\nFlags:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cmd := commands[name]
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", name, cmd.description)
+		for _, a := range actionNames(cmd) {
+			fmt.Fprintf(os.Stderr, "      %s %s\n", a, cmd.actions[a].usage)
+		}
+	}
+}
+
+func actionNames(cmd *command) []string {
+	names := make([]string, 0, len(cmd.actions))
+	for name := range cmd.actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func defaultConfigFile() string {
+	if path := os.Getenv(envConfigFile); path != "" {
+		return path
+	}
+	if u, err := user.Current(); err == nil {
+		return filepath.Join(u.HomeDir, ".windams", "environments.yaml")
+	}
+	return gowindams.DEFAULT_CONFIG_PATH
+}
+
+func defaultEnvironment() string {
+	if name := os.Getenv(envEnvironmentName); name != "" {
+		return name
+	}
+	return defaultEnvironmentName
+}
+
+func loadEnvironments() (*gowindams.Environments, error) {
+	envs, err := gowindams.LoadEnvironments(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load environments config file from %s: %s", configFile, err)
+	}
+	return envs, nil
+}
+
+func loadEnvironment() (*gowindams.Environment, error) {
+	envs, err := loadEnvironments()
+	if err != nil {
+		return nil, err
+	}
+	env := envs.Find(environmentName)
+	if env == nil {
+		return nil, fmt.Errorf("Unable to locate environment with name %q in %s", environmentName, configFile)
+	}
+	return env, nil
+}
+
+// Reads JSON input from a file, or standard input when the path is "-".
+func readInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(path)
+}
+
+func output(v interface{}) error {
+	return gowindams.WriteFormatted(stdout, v, &outputOptions)
+}
+
+// Parses flags which may appear before or after the positional arguments, returning the positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(ioutil.Discard)
+	positional := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usagef("%s", err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func oneArg(fs *flag.FlagSet, args []string, name string) (string, error) {
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return "", err
+	}
+	if len(positional) != 1 {
+		return "", usagef("Expected a single %s", name)
+	}
+	return positional[0], nil
+}
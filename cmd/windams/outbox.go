@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func init() {
+	commands["outbox"] = &command{
+		description: "Inspect and replay operations recorded offline",
+		actions: map[string]action{
+			"status": {"-dir outbox [-pending]", outboxStatus},
+			"replay": {"-dir outbox [-on-conflict skip|overwrite|fail] [-max-attempts n]", outboxReplay},
+			"purge":  {"-dir outbox", outboxPurge},
+		},
+	}
+}
+
+func parseOutboxFlags(fs *flag.FlagSet, dir *string, args []string) error {
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usagef("Unexpected arguments %v", positional)
+	}
+	if *dir == "" {
+		return usagef("An outbox directory is required")
+	}
+	return nil
+}
+
+func openOutbox(fs *flag.FlagSet, dir *string, args []string) (*gowindams.Outbox, error) {
+	if err := parseOutboxFlags(fs, dir, args); err != nil {
+		return nil, err
+	}
+	return gowindams.OpenOutbox(*dir)
+}
+
+func outboxStatus(_ *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	dir := fs.String("dir", "", "Outbox directory")
+	pendingOnly := fs.Bool("pending", false, "Only list operations still to be replayed")
+	outbox, err := openOutbox(fs, dir, args)
+	if err != nil {
+		return err
+	}
+	defer outbox.Close()
+	ops := make([]gowindams.OutboxOperation, 0)
+	for _, op := range outbox.Operations() {
+		if !*pendingOnly || op.Status == gowindams.OutboxStatusPending {
+			ops = append(ops, op)
+		}
+	}
+	return output(ops)
+}
+
+func outboxReplay(_ *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	dir := fs.String("dir", "", "Outbox directory")
+	onConflict := fs.String("on-conflict", gowindams.ConflictSkip, "What to do when the services hold a conflicting change: skip, overwrite or fail")
+	maxAttempts := fs.Int("max-attempts", 0, "Give up on an operation after this many failed attempts, 0 to keep retrying")
+	if err := parseOutboxFlags(fs, dir, args); err != nil {
+		return err
+	}
+	if err := checkConflictPolicy(*onConflict); err != nil {
+		return err
+	}
+	outbox, err := gowindams.OpenOutbox(*dir)
+	if err != nil {
+		return err
+	}
+	defer outbox.Close()
+	env, err := loadEnvironment()
+	if err != nil {
+		return err
+	}
+	report, err := outbox.Replay(env, &gowindams.ReplayOptions{OnConflict: *onConflict, MaxAttempts: *maxAttempts})
+	if report != nil {
+		if oerr := output(report.Operations); oerr != nil && err == nil {
+			err = oerr
+		}
+	}
+	return err
+}
+
+func outboxPurge(_ *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ContinueOnError)
+	dir := fs.String("dir", "", "Outbox directory")
+	outbox, err := openOutbox(fs, dir, args)
+	if err != nil {
+		return err
+	}
+	defer outbox.Close()
+	return outbox.Purge()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"strconv"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func init() {
+	commands["queue"] = &command{
+		description: "The process queue",
+		needsEnv:    true,
+		actions: map[string]action{
+			"claim":   {"-processor id <process type>", queueClaim},
+			"enqueue": {"[-f entries.json]", queueEnqueue},
+			"status":  {"[-error message] <entry id> processed|errored", queueStatus},
+		},
+	}
+}
+
+func queueClaim(env *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("claim", flag.ContinueOnError)
+	processor := fs.String("processor", "", "Id of the processor claiming the entries")
+	processType, err := oneArg(fs, args, "process type")
+	if err != nil {
+		return err
+	}
+	if *processor == "" {
+		return usagef("A processor id is required")
+	}
+	entries, err := env.ProcessQueueServiceClient().Claim(*processor, processType)
+	if err != nil {
+		return err
+	}
+	return output(entries)
+}
+
+func queueEnqueue(env *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("enqueue", flag.ContinueOnError)
+	file := fs.String("f", "-", "File holding a JSON array of entries, - for standard input")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return usagef("Unexpected arguments %v", positional)
+	}
+	data, err := readInput(*file)
+	if err != nil {
+		return err
+	}
+	var entries []gowindams.ProcessQueueEntry
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	return env.ProcessQueueServiceClient().Enqueue(entries)
+}
+
+func queueStatus(env *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	message := fs.String("error", "", "Error message for an errored entry")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return usagef("Expected an entry id and a status")
+	}
+	id, err := strconv.ParseInt(positional[0], 10, 64)
+	if err != nil {
+		return usagef("Invalid entry id %q", positional[0])
+	}
+	switch positional[1] {
+	case "processed":
+		return env.ProcessQueueServiceClient().MarkProcessed([]gowindams.ProcessQueueEntry{{Id: &id}})
+	case "errored":
+		return env.ProcessQueueServiceClient().MarkErrored(id, *message)
+	default:
+		return usagef("Unknown status %q", positional[1])
+	}
+}
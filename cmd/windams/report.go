@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func init() {
+	commands["report"] = &command{
+		description: "Write damage reports as HTML or PDF",
+		actions: map[string]action{
+			"inspection": {"-out report.pdf [-format html|pdf] [-title title] [-all-images] <asset inspection id>", reportInspection},
+			"workorder":  {"-out report.pdf [-format html|pdf] [-title title] [-all-images] <order number>", reportWorkOrder},
+			"backup":     {"-out report.pdf [-format html|pdf] [-title title] [-all-images] [-order number] [-inspection id] <backup.zip>", reportBackup},
+		},
+	}
+}
+
+type reportFlags struct {
+	out       *string
+	format    *string
+	title     *string
+	allImages *bool
+}
+
+func newReportFlags(fs *flag.FlagSet) reportFlags {
+	return reportFlags{
+		out:       fs.String("out", "", "Report to write"),
+		format:    fs.String("format", "", "Report format, html or pdf, defaults to the extension of -out"),
+		title:     fs.String("title", "", "Report title"),
+		allImages: fs.Bool("all-images", false, "Include images without any damage marked on them"),
+	}
+}
+
+func (rf reportFlags) options() (*gowindams.DamageReportOptions, error) {
+	if *rf.out == "" {
+		return nil, usagef("A report to write is required")
+	}
+	if *rf.format == "" {
+		*rf.format = "html"
+		if strings.HasSuffix(strings.ToLower(*rf.out), ".pdf") {
+			*rf.format = "pdf"
+		}
+	}
+	if *rf.format != "html" && *rf.format != "pdf" {
+		return nil, usagef("Unknown report format %q", *rf.format)
+	}
+	return &gowindams.DamageReportOptions{Title: *rf.title, IncludeAllImages: *rf.allImages}, nil
+}
+
+func (rf reportFlags) write(report *gowindams.DamageReport) error {
+	f, err := os.Create(*rf.out)
+	if err != nil {
+		return err
+	}
+	if *rf.format == "pdf" {
+		err = report.WritePDF(f)
+	} else {
+		err = report.WriteHTML(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*rf.out)
+	}
+	return err
+}
+
+func reportInspection(_ *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("inspection", flag.ContinueOnError)
+	rf := newReportFlags(fs)
+	id, err := oneArg(fs, args, "asset inspection id")
+	if err != nil {
+		return err
+	}
+	options, err := rf.options()
+	if err != nil {
+		return err
+	}
+	env, err := loadEnvironment()
+	if err != nil {
+		return err
+	}
+	report, err := gowindams.AssetInspectionDamageReport(env, id, options)
+	if err != nil {
+		return err
+	}
+	return rf.write(report)
+}
+
+func reportWorkOrder(_ *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("workorder", flag.ContinueOnError)
+	rf := newReportFlags(fs)
+	orderNumber, err := oneArg(fs, args, "order number")
+	if err != nil {
+		return err
+	}
+	options, err := rf.options()
+	if err != nil {
+		return err
+	}
+	env, err := loadEnvironment()
+	if err != nil {
+		return err
+	}
+	report, err := gowindams.WorkOrderDamageReport(env, orderNumber, options)
+	if err != nil {
+		return err
+	}
+	return rf.write(report)
+}
+
+func reportBackup(_ *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	rf := newReportFlags(fs)
+	orderNumber := fs.String("order", "", "Only report on this work order")
+	inspectionId := fs.String("inspection", "", "Only report on this asset inspection")
+	path, err := oneArg(fs, args, "archive")
+	if err != nil {
+		return err
+	}
+	options, err := rf.options()
+	if err != nil {
+		return err
+	}
+	backup, err := gowindams.OpenBackup(path)
+	if err != nil {
+		return err
+	}
+	defer backup.Close()
+	report, err := gowindams.BackupDamageReport(backup, *orderNumber, *inspectionId, options)
+	if err != nil {
+		return err
+	}
+	return rf.write(report)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func init() {
+	commands["resource"] = &command{
+		description: "Images and other resources",
+		needsEnv:    true,
+		actions: map[string]action{
+			"get": {"<resource id>", func(env *gowindams.Environment, args []string) error {
+				id, err := oneArg(flag.NewFlagSet("get", flag.ContinueOnError), args, "resource id")
+				if err != nil {
+					return err
+				}
+				rmeta, err := env.ResourceServiceClient().Get(id)
+				if err != nil {
+					return err
+				}
+				return output(rmeta)
+			}},
+			"search": {searchUsage(resourceFilters), func(env *gowindams.Environment, args []string) error {
+				data, err := searchCriteria(args, resourceFilters)
+				if err != nil {
+					return err
+				}
+				var criteria gowindams.ResourceSearchCriteria
+				if err = json.Unmarshal(data, &criteria); err != nil {
+					return err
+				}
+				results, err := env.ResourceServiceClient().Search(&criteria)
+				if err != nil {
+					return err
+				}
+				return output(results)
+			}},
+			"download": {"[-out file] <resource id>", resourceDownload},
+			"overlay":  {"[-out file] [-format png|jpeg|svg] [-order number] [-href url] <resource id>", resourceOverlay},
+			"upload":   {"[-type content-type] <resource id> <file>", resourceUpload},
+			"scale":    {"[-type PNG|JPEG|GIF] [-op operation] [-width w] [-height h] <resource id>", resourceScale},
+		},
+	}
+}
+
+var resourceFilters = map[string]searchFilter{
+	"site":   {key: "siteId"},
+	"asset":  {key: "assetId"},
+	"order":  {key: "orderNumber"},
+	"status": {key: "status"},
+}
+
+func resourceDownload(env *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("download", flag.ContinueOnError)
+	out := fs.String("out", "-", "File to write the resource to, - for standard output")
+	id, err := oneArg(fs, args, "resource id")
+	if err != nil {
+		return err
+	}
+	body, err := env.ResourceServiceClient().Download(id)
+	if err != nil {
+		return err
+	}
+	defer (*body).Close()
+	var w io.Writer = stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	_, err = io.Copy(w, *body)
+	return err
+}
+
+func resourceOverlay(env *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("overlay", flag.ContinueOnError)
+	out := fs.String("out", "-", "File to write the image to, - for standard output")
+	format := fs.String("format", "", "Image format: "+strings.Join(gowindams.OverlayFormats, ", ")+", defaults to the extension of -out or png")
+	orderNumber := fs.String("order", "", "Only draw the damage recorded for this work order")
+	href := fs.String("href", "", "For SVG, the URL of the image rather than embedding it")
+	id, err := oneArg(fs, args, "resource id")
+	if err != nil {
+		return err
+	}
+	options := gowindams.OverlayOptions{Format: *format, Href: *href}
+	if options.Format == "" && *out != "-" {
+		switch strings.ToLower(filepath.Ext(*out)) {
+		case ".jpg", ".jpeg":
+			options.Format = gowindams.OverlayFormatJPEG
+		case ".svg":
+			options.Format = gowindams.OverlayFormatSVG
+		}
+	}
+	if *orderNumber != "" {
+		options.OrderNumber = orderNumber
+	}
+	if *out == "-" {
+		return gowindams.RenderResourceOverlay(env, id, stdout, &options)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	err = gowindams.RenderResourceOverlay(env, id, f, &options)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(*out)
+	}
+	return err
+}
+
+func resourceUpload(env *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("upload", flag.ContinueOnError)
+	contentType := fs.String("type", "", "Content type, guessed from the file extension if not given")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return usagef("Expected a resource id and a file")
+	}
+	ct := *contentType
+	if ct == "" {
+		if ct = mime.TypeByExtension(filepath.Ext(positional[1])); ct == "" {
+			return usagef("Unable to tell the content type of %s, use -type", positional[1])
+		}
+	}
+	f, err := os.Open(positional[1])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if err = env.ResourceServiceClient().Upload(positional[0], ct, &r); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Uploaded %s to resource %s\n", positional[1], positional[0])
+	return nil
+}
+
+func resourceScale(env *gowindams.Environment, args []string) error {
+	fs := flag.NewFlagSet("scale", flag.ContinueOnError)
+	resultType := fs.String("type", gowindams.ImageScaleResultTypeJPG, "Result type: PNG, JPEG or GIF")
+	operation := fs.String("op", gowindams.ImageScaleOperationScaleToFit, "ScaleToFit, ScaleToHeight, ScaleToSize or ScaleToWidth")
+	width := fs.Float64("width", 0, "Width in pixels")
+	height := fs.Float64("height", 0, "Height in pixels")
+	id, err := oneArg(fs, args, "resource id")
+	if err != nil {
+		return err
+	}
+	request := gowindams.ImageScaleRequest{ResultType: *resultType, ScaleOperation: *operation}
+	if *width > 0 {
+		request.Width = width
+	}
+	if *height > 0 {
+		request.Height = height
+	}
+	rmeta, err := env.ResourceServiceClient().Scale(id, request)
+	if err != nil {
+		return err
+	}
+	return output(rmeta)
+}
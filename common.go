@@ -2,46 +2,148 @@ package gowindams
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"reflect"
 	"strings"
 	"time"
 )
 
-const DATE_FORMAT = "yyyyMMdd"
-                        //20060102T150405
-const DATE_TIME_FORMAT = "20060102T150405.000-0700" //"yyyyMMddTHHmmss.SSSZ"
-const TIME_FORMAT = "'T'HHmmss.SSSZ"
+const DATE_FORMAT = "20060102"                      //"yyyyMMdd"
+const DATE_TIME_FORMAT = "20060102T150405.000-0700" //"yyyyMMdd'T'HHmmss.SSSZ"
+const TIME_FORMAT = "T150405.000-0700"              //"'T'HHmmss.SSSZ"
+
+const EntityTypeAsset = "Asset"
+const EntityTypeAssetInspection = "AssetInspection"
+const EntityTypeComponent = "Component"
+const EntityTypeComponentInspection = "ComponentInspection"
+const EntityTypeInspectionEventResource = "InspectionEventResource"
+const EntityTypeResource = "Resource"
+const EntityTypeSite = "Site"
+const EntityTypeWorkOrder = "WorkOrder"
 
 const INSPECTION_POSITION_BLADE_TIP_DOWN = "bladeTipDown"
 const INSPECTION_POSITION_BLADE_TIP_UP = "bladeTipUp"
 
+// Layouts accepted when parsing timestamps from the services, in order of preference.  Z0700 matches both a "Z"
+// suffix and a numeric offset.
+var dateTimeParseFormats = []string{
+	"20060102T150405.000Z0700",
+	"20060102T150405Z0700",
+	"20060102T150405.000Z07:00",
+	"20060102T150405Z07:00",
+	time.RFC3339Nano,
+}
+
+// Layouts accepted when parsing dates from the services, in order of preference.
+var dateParseFormats = []string{
+	DATE_FORMAT,
+	"2006-01-02",
+}
+
 type WindAMSTime time.Time
 
 func (t WindAMSTime) Format(layout string) string {
 	return time.Time(t).Format(layout)
 }
 
-func (t *WindAMSTime) MarshalJSON() ([]byte, error) {
-	s := "\"" + t.Format(DATE_TIME_FORMAT) + "\""
-	return []byte(s), nil
+func (t WindAMSTime) Time() time.Time {
+	return time.Time(t)
+}
+
+func (t WindAMSTime) String() string {
+	return t.Format(DATE_TIME_FORMAT)
+}
+
+func (t WindAMSTime) MarshalJSON() ([]byte, error) {
+	if time.Time(t).IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte("\"" + t.Format(DATE_TIME_FORMAT) + "\""), nil
 }
 
 func (t *WindAMSTime) UnmarshalJSON(data []byte) error {
-	s := string(data[:])
-	if strings.HasPrefix(s, "\"") && strings.HasSuffix(s, "\"") {
-		s := string([]rune(s)[1:len(s) - 1]) // Strip the enclosing quotes
-		u, err := time.Parse(DATE_TIME_FORMAT, s)
-		v := WindAMSTime(u)
-		t = &v
-		return err
-	} else {
-		return errors.New(fmt.Sprintf("Timestamp value (%s) must be enclosed with double-quotes in the JSON.", s))
+	u, err := parseJSONTime(data, dateTimeParseFormats, "Timestamp")
+	if err == nil {
+		*t = WindAMSTime(u)
+	}
+	return err
+}
+
+func ParseWindAMSTime(s string) (WindAMSTime, error) {
+	u, err := parseTime(s, dateTimeParseFormats)
+	return WindAMSTime(u), err
+}
+
+type WindAMSDate time.Time
+
+func (d WindAMSDate) Format(layout string) string {
+	return time.Time(d).Format(layout)
+}
+
+func (d WindAMSDate) Time() time.Time {
+	return time.Time(d)
+}
+
+func (d WindAMSDate) String() string {
+	return d.Format(DATE_FORMAT)
+}
+
+func (d WindAMSDate) MarshalJSON() ([]byte, error) {
+	if time.Time(d).IsZero() {
+		return []byte("null"), nil
 	}
+	return []byte("\"" + d.Format(DATE_FORMAT) + "\""), nil
+}
+
+func (d *WindAMSDate) UnmarshalJSON(data []byte) error {
+	u, err := parseJSONTime(data, dateParseFormats, "Date")
+	if err == nil {
+		*d = WindAMSDate(u)
+	}
+	return err
+}
+
+func ParseWindAMSDate(s string) (WindAMSDate, error) {
+	u, err := parseTime(s, dateParseFormats)
+	return WindAMSDate(u), err
+}
+
+func NewWindAMSDate(year int, month time.Month, day int) WindAMSDate {
+	return WindAMSDate(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// Parses a quoted JSON string using the given layouts.  A JSON null results in the zero time.
+func parseJSONTime(data []byte, layouts []string, what string) (time.Time, error) {
+	s := string(data)
+	if s == "null" {
+		return time.Time{}, nil
+	}
+	if len(s) < 2 || !strings.HasPrefix(s, "\"") || !strings.HasSuffix(s, "\"") {
+		return time.Time{}, fmt.Errorf("%s value (%s) must be enclosed with double-quotes in the JSON.", what, s)
+	}
+	s = s[1 : len(s)-1] // Strip the enclosing quotes
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return parseTime(s, layouts)
+}
+
+func parseTime(s string, layouts []string) (time.Time, error) {
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		t, err = time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Unable to parse the value \"%s\" as a date/time: %s", s, err)
 }
 
 type Dimension struct {
@@ -51,55 +153,126 @@ type Dimension struct {
 }
 
 type GeoPoint struct {
-	Accuracy *float64 `json:"accuracy"`
-	Altitude *float64 `json:"altitude"`
-	Latitude *float64 `json:"latitude"`
+	Accuracy  *float64 `json:"accuracy"`
+	Altitude  *float64 `json:"altitude"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
 }
 
 func executeRestCall(env *Environment, action string, url string, data []byte, results interface{}) error {
-	client := &http.Client{
-	}
-
-	req, err := http.NewRequest(action, url, bytes.NewBuffer(data))
-	if err != nil {
-		log.Printf("GOWINDAMS: Error building http request for %s against %s: %s\n", action, url, err)
-		return err
-	}
-	token, err := env.ObtainAccessToken()
-	if err != nil {
-		return err
-	}
+	return doRestCall(env, action, url, data, results, false, nil)
+}
 
-	log.Printf("GOWINDAMS: Executing %s against endpoint %s", action, url)
+// Like executeRestCall, but for creates and updates where the services may or may not echo back the saved entity.  If
+// they do, it is decoded into result.  If they return only the entity's id as a JSON string, it is set as the Id of
+// result.  Otherwise result is left untouched.  Any 2xx status counts as success.
+func executeSaveCall(env *Environment, action string, url string, data []byte, result interface{}) error {
+	return doRestCall(env, action, url, data, result, true, nil)
+}
 
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Add("accept", "application/json")
-	req.Header.Add("content-type", "application/json")
-	resp, err := client.Do(req)
-	defer resp.Body.Close()
+// Like executeSaveCall, for updates of entities carrying a version.  The version is sent as an If-Match precondition so
+// that the services reject the update with a ConflictError if the entity has been changed since it was loaded.
+func executeUpdateCall(env *Environment, url string, data []byte, result interface{}, version *int64) error {
+	return doRestCall(env, "POST", url, data, result, true, versionHeader(version))
+}
 
-	log.Printf("GOWINDAMS: Response with status code %d for %s against endpoint %s", resp.StatusCode, action, url)
+func versionHeader(version *int64) http.Header {
+	header := http.Header{}
+	if version != nil {
+		header.Set("If-Match", fmt.Sprintf("\"%d\"", *version))
+	}
+	return header
+}
 
+func doRestCall(env *Environment, action string, url string, data []byte, results interface{}, optional bool, header http.Header) error {
+	resp, err := openRestCall(context.Background(), env, action, url, data, header)
 	if err != nil {
-		log.Printf("GOWINDAMS: Got error for %s against %s: %s\n", action, url, err)
 		return err
 	}
+	defer resp.Body.Close()
+
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		log.Printf("GOWINDAMS: Got error getting response body for %s against %s: %s\n", action, url, err)
 		return err
 	}
-	if resp.StatusCode != 200 {
+	if resp.StatusCode != 200 && !(optional && resp.StatusCode >= 200 && resp.StatusCode < 300) {
 		if resp.StatusCode == 204 && results == nil {
 			// This is fine.  Processed ok, no content, but we don't expect any.
+			return nil
 		} else {
 			s := string(body)
 			log.Printf("GOWINDAMS: Got status code %d for %s against %s: %s\n", resp.StatusCode, action, url, s)
+			if resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusPreconditionFailed {
+				return &ConflictError{URL: url, StatusCode: resp.StatusCode, Message: s}
+			}
 			return errors.New(s)
 		}
 	}
 	if results != nil {
+		// Only an object echoes back the saved entity, and a string is the id it was saved under.  Anything else, such
+		// as an empty body, is ignored.
+		if trimmed := bytes.TrimSpace(body); optional && (len(trimmed) == 0 || trimmed[0] != '{') {
+			if len(trimmed) > 0 && trimmed[0] == '"' {
+				return setSavedId(results, trimmed)
+			}
+			return nil
+		}
 		err = json.Unmarshal(body, results)
 	}
 	return err
 }
+
+// Sets the Id field of the entity result points to from a JSON string.  Results without such a field are left untouched.
+func setSavedId(result interface{}, data []byte) error {
+	var id string
+	if err := json.Unmarshal(data, &id); err != nil {
+		return err
+	}
+	v := reflect.ValueOf(result)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	if field := v.Elem().FieldByName("Id"); field.IsValid() && field.CanSet() && field.Type() == reflect.TypeOf(&id) {
+		field.Set(reflect.ValueOf(&id))
+	}
+	return nil
+}
+
+// Sends an authenticated request to the services and returns the response without reading the body.  The caller is
+// responsible for checking the status code and closing the body.
+func openRestCall(ctx context.Context, env *Environment, action string, url string, data []byte, header http.Header) (*http.Response, error) {
+	client := &http.Client{
+	}
+
+	req, err := http.NewRequest(action, url, bytes.NewBuffer(data))
+	if err != nil {
+		log.Printf("GOWINDAMS: Error building http request for %s against %s: %s\n", action, url, err)
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	token, err := env.ObtainAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("GOWINDAMS: Executing %s against endpoint %s", action, url)
+
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Add("accept", "application/json")
+	req.Header.Add("content-type", "application/json")
+	for name, values := range header {
+		req.Header.Del(name)
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("GOWINDAMS: Got error for %s against %s: %s\n", action, url, err)
+		return nil, err
+	}
+
+	log.Printf("GOWINDAMS: Response with status code %d for %s against endpoint %s", resp.StatusCode, action, url)
+	return resp, nil
+}
@@ -1,6 +1,7 @@
 package gowindams
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -15,14 +16,18 @@ type Component struct {
 	SerialNumber  *string            `json:"serialNumber"`
 	SiteId        *string            `json:"siteId"`
 	Type          *string            `json:"type"`
+	Version       *int64             `json:"version,omitempty"`
 }
 
 type ComponentSearchCriteria struct {
-	AssetId       *string            `json:"assetId"`
-	ComponentId   *string            `json:"componentId"`
-	SerialNumber  *string            `json:"serialNumber"`
-	SiteId        *string            `json:"siteId"`
-	ComponentType *string            `json:"componentType"`
+	SearchPage
+	SearchSort
+	AssetId        *string            `json:"assetId"`
+	ComponentId    *string            `json:"componentId"`
+	SerialNumber   *string            `json:"serialNumber"`
+	SiteId         *string            `json:"siteId"`
+	ComponentType  *string            `json:"componentType"`
+	ComponentTypes []string           `json:"componentTypes,omitempty"`
 }
 
 const COMPONENT_TYPE_BLADE_A = "BladeA"
@@ -33,6 +38,7 @@ const COMPONENT_TYPE_BLADE_E = "BladeE"
 const COMPONENT_TYPE_GEARBOX = "Gearbox"
 
 const componentRootURI = "%s/component"
+const componentDeleteURI = componentRootURI + "/%s"
 const componentGetURI = componentRootURI + "/%s"
 const componentSaveURI = componentRootURI
 const componentSearchURI = componentRootURI + "/search"
@@ -42,12 +48,23 @@ type ComponentServiceClient struct {
 }
 
 func (client ComponentServiceClient) Create(obj *Component) error {
+	if obj.Id == nil && client.env.GenerateIds {
+		id := NewUUID()
+		obj.Id = &id
+	}
 	data, err := json.Marshal(obj)
 	if err != nil {
 		return err
 	}
 	url := fmt.Sprintf(componentSaveURI, client.env.ServiceURI)
-	err = executeRestCall(client.env, "PUT", url, data, nil)
+	err = executeSaveCall(client.env, "PUT", url, data, obj)
+	return err
+}
+
+func (client ComponentServiceClient) Delete(id string) error {
+	log.Printf("Deleting component %s", id)
+	url := fmt.Sprintf(componentDeleteURI, client.env.ServiceURI, id)
+	err := executeRestCall(client.env, "DELETE", url, nil, nil)
 	return err
 }
 
@@ -70,12 +87,37 @@ func (client ComponentServiceClient) Search(criteria *ComponentSearchCriteria) (
 	return results, err
 }
 
+type ComponentIterator struct {
+	*searchIterator
+	value *Component
+}
+
+func (it *ComponentIterator) Next() bool {
+	it.value = new(Component)
+	return it.next(it.value)
+}
+
+func (it *ComponentIterator) Value() *Component {
+	return it.value
+}
+
+func (client ComponentServiceClient) Iterate(ctx context.Context, criteria *ComponentSearchCriteria, pageSize int) *ComponentIterator {
+	c := ComponentSearchCriteria{}
+	if criteria != nil {
+		c = *criteria
+	}
+	url := fmt.Sprintf(componentSearchURI, client.env.ServiceURI)
+	return &ComponentIterator{
+		searchIterator: newSearchIterator(ctx, client.env, url, &c, &c.SearchPage, pageSize),
+	}
+}
+
 func (client ComponentServiceClient) Update(obj *Component) error {
 	data, err := json.Marshal(obj)
 	if err != nil {
 		return err
 	}
 	url := fmt.Sprintf(componentSaveURI, client.env.ServiceURI)
-	err = executeRestCall(client.env, "POST", url, data, nil)
+	err = executeUpdateCall(client.env, url, data, obj, obj.Version)
 	return err
 }
@@ -1,6 +1,7 @@
 package gowindams
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -30,13 +31,18 @@ type ComponentInspection struct {
 	StatusHistory        []StatusEvent      `json:"statusHistory"`
 	Type                 *string            `json:"type"`
 	VendorId             *string            `json:"vendorId"`
+	Version              *int64             `json:"version,omitempty"`
 }
 
 type ComponentInspectionSearchCriteria struct {
+	SearchPage
+	SearchSort
 	ComponentId          *string            `json:"componentId"`
 	SiteId               *string            `json:"siteId"`
 	OrderNumber          *string            `json:"orderNumber"`
 	Status               *string            `json:"status"`
+	Statuses             []string           `json:"statuses,omitempty"`
+	Types                []string           `json:"types,omitempty"`
 }
 
 const COMP_INSPECTION_SOURCE_PH          = "InspecTools"
@@ -53,6 +59,7 @@ const COMP_INSPECTION_TYPE_DRONE = "DroneBladeInspection"
 const COMP_INSPECTION_TYPE_GROUND = "GroundBladeInspection"
 
 const componentInspectionRootURI = "%s/componentInspection"
+const componentInspectionDeleteURI = componentInspectionRootURI + "/%s"
 const componentInspectionGetURI = componentInspectionRootURI + "/%s"
 const componentInspectionSaveURI = componentInspectionRootURI
 const componentInspectionSearchURI = componentInspectionRootURI + "/search"
@@ -62,12 +69,23 @@ type ComponentInspectionServiceClient struct {
 }
 
 func (client ComponentInspectionServiceClient) Create(obj *ComponentInspection) error {
+	if obj.Id == nil && client.env.GenerateIds {
+		id := NewUUID()
+		obj.Id = &id
+	}
 	data, err := json.Marshal(obj)
 	if err != nil {
 		return err
 	}
 	url := fmt.Sprintf(componentInspectionSaveURI, client.env.ServiceURI)
-	err = executeRestCall(client.env, "PUT", url, data, nil)
+	err = executeSaveCall(client.env, "PUT", url, data, obj)
+	return err
+}
+
+func (client ComponentInspectionServiceClient) Delete(id string) error {
+	log.Printf("Deleting component inspection %s", id)
+	url := fmt.Sprintf(componentInspectionDeleteURI, client.env.ServiceURI, id)
+	err := executeRestCall(client.env, "DELETE", url, nil, nil)
 	return err
 }
 
@@ -90,12 +108,37 @@ func (client ComponentInspectionServiceClient) Search(criteria *ComponentInspect
 	return results, err
 }
 
+type ComponentInspectionIterator struct {
+	*searchIterator
+	value *ComponentInspection
+}
+
+func (it *ComponentInspectionIterator) Next() bool {
+	it.value = new(ComponentInspection)
+	return it.next(it.value)
+}
+
+func (it *ComponentInspectionIterator) Value() *ComponentInspection {
+	return it.value
+}
+
+func (client ComponentInspectionServiceClient) Iterate(ctx context.Context, criteria *ComponentInspectionSearchCriteria, pageSize int) *ComponentInspectionIterator {
+	c := ComponentInspectionSearchCriteria{}
+	if criteria != nil {
+		c = *criteria
+	}
+	url := fmt.Sprintf(componentInspectionSearchURI, client.env.ServiceURI)
+	return &ComponentInspectionIterator{
+		searchIterator: newSearchIterator(ctx, client.env, url, &c, &c.SearchPage, pageSize),
+	}
+}
+
 func (client ComponentInspectionServiceClient) Update(obj *ComponentInspection) error {
 	data, err := json.Marshal(obj)
 	if err != nil {
 		return err
 	}
 	url := fmt.Sprintf(componentInspectionSaveURI, client.env.ServiceURI)
-	err = executeRestCall(client.env, "POST", url, data, nil)
+	err = executeUpdateCall(client.env, url, data, obj, obj.Version)
 	return err
 }
@@ -0,0 +1,126 @@
+package gowindams
+
+import (
+	"sort"
+	"time"
+)
+
+// The order in which a component inspection moves through its statuses.
+var ComponentInspectionStatusOrder = []string{
+	COMP_INSPECTION_STATUS_STARTED,
+	COMP_INSPECTION_STATUS_COMPLETED,
+	COMP_INSPECTION_STATUS_SUBMITTED,
+	COMP_INSPECTION_STATUS_APPROVED,
+	COMP_INSPECTION_STATUS_LOCKED,
+	COMP_INSPECTION_STATUS_TRANSMITTED,
+}
+
+// The status of the most recent event in the status history, or nil if there is no history.
+func (obj *ComponentInspection) CurrentStatus() *string {
+	var current *StatusEvent
+	for i := range obj.StatusHistory {
+		event := &obj.StatusHistory[i]
+		if current == nil || current.Timestamp == nil ||
+			(event.Timestamp != nil && !event.Timestamp.Time().Before(current.Timestamp.Time())) {
+			current = event
+		}
+	}
+	if current == nil {
+		return nil
+	}
+	return current.Status
+}
+
+func componentInspectionStatusIndex(status string) int {
+	for i, s := range ComponentInspectionStatusOrder {
+		if s == status {
+			return i
+		}
+	}
+	return -1
+}
+
+// Checks that a component inspection may move from one status to another.  Inspections move forward one status at a
+// time, and an inspection without a status may only be started.
+func ValidateComponentInspectionTransition(id string, from *string, to string) error {
+	current := stringValue(from)
+	j := componentInspectionStatusIndex(to)
+	allowed := j >= 0 && ((current == "" && j == 0) || (current != "" && componentInspectionStatusIndex(current)+1 == j))
+	if !allowed {
+		return &TransitionError{EntityType: EntityTypeComponentInspection, Id: id, From: current, To: to}
+	}
+	return nil
+}
+
+// The time spent in each status of the history up to now.  The current status accrues time until now; events without
+// a timestamp are ignored.
+func (obj *ComponentInspection) TimeInStatus(now time.Time) map[string]time.Duration {
+	events := make([]StatusEvent, 0, len(obj.StatusHistory))
+	for _, event := range obj.StatusHistory {
+		if event.Status != nil && event.Timestamp != nil {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Time().Before(events[j].Timestamp.Time())
+	})
+	durations := make(map[string]time.Duration)
+	for i, event := range events {
+		end := now
+		if i+1 < len(events) {
+			end = events[i+1].Timestamp.Time()
+		}
+		if d := end.Sub(event.Timestamp.Time()); d > 0 {
+			durations[*event.Status] += d
+		} else if _, ok := durations[*event.Status]; !ok {
+			durations[*event.Status] = 0
+		}
+	}
+	return durations
+}
+
+func (client ComponentInspectionServiceClient) Start(id string) (*ComponentInspection, error) {
+	return client.transition(id, COMP_INSPECTION_STATUS_STARTED)
+}
+
+func (client ComponentInspectionServiceClient) Complete(id string) (*ComponentInspection, error) {
+	return client.transition(id, COMP_INSPECTION_STATUS_COMPLETED)
+}
+
+func (client ComponentInspectionServiceClient) Submit(id string) (*ComponentInspection, error) {
+	return client.transition(id, COMP_INSPECTION_STATUS_SUBMITTED)
+}
+
+func (client ComponentInspectionServiceClient) Approve(id string) (*ComponentInspection, error) {
+	return client.transition(id, COMP_INSPECTION_STATUS_APPROVED)
+}
+
+func (client ComponentInspectionServiceClient) Lock(id string) (*ComponentInspection, error) {
+	return client.transition(id, COMP_INSPECTION_STATUS_LOCKED)
+}
+
+func (client ComponentInspectionServiceClient) Transmit(id string) (*ComponentInspection, error) {
+	return client.transition(id, COMP_INSPECTION_STATUS_TRANSMITTED)
+}
+
+// Appends a status event, recording the time and the environment's UserId, after checking the transition from the
+// current status is allowed.
+func (client ComponentInspectionServiceClient) transition(id string, toStatus string) (*ComponentInspection, error) {
+	return client.Modify(id, func(ci *ComponentInspection) error {
+		if err := ValidateComponentInspectionTransition(id, ci.CurrentStatus(), toStatus); err != nil {
+			return err
+		}
+		ci.StatusHistory = append(ci.StatusHistory, newStatusEvent(client.env, toStatus))
+		return nil
+	}, DEFAULT_UPDATE_ATTEMPTS)
+}
+
+func newStatusEvent(env *Environment, status string) StatusEvent {
+	now := WindAMSTime(time.Now())
+	event := StatusEvent{Status: &status, Timestamp: &now}
+	if env.UserId != "" {
+		userId := env.UserId
+		event.UserId = &userId
+	}
+	return event
+}
@@ -0,0 +1,113 @@
+package gowindams
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+const DEFAULT_UPDATE_ATTEMPTS = 3
+
+// Returned when the services reject an update because the entity was modified after it was loaded.
+type ConflictError struct {
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("Conflicting update (status %d) against %s: %s", e.StatusCode, e.URL, e.Message)
+}
+
+func IsConflict(err error) bool {
+	var conflict *ConflictError
+	return errors.As(err, &conflict)
+}
+
+// Calls attempt until it succeeds, fails with an error other than a conflict, or maxAttempts is reached.
+func retryOnConflict(maxAttempts int, attempt func() error) error {
+	if maxAttempts <= 0 {
+		maxAttempts = DEFAULT_UPDATE_ATTEMPTS
+	}
+	var err error
+	for i := 1; i <= maxAttempts; i++ {
+		err = attempt()
+		if !IsConflict(err) {
+			return err
+		}
+		log.Printf("GOWINDAMS: Update conflict on attempt %d of %d: %s", i, maxAttempts, err)
+	}
+	return err
+}
+
+// The load, mutate and update cycle behind each client's Modify, returning the entity as last loaded and mutated.
+func modify(maxAttempts int, load func() (interface{}, error), mutate func(interface{}) error, update func(interface{}) error) (interface{}, error) {
+	var obj interface{}
+	err := retryOnConflict(maxAttempts, func() error {
+		var err error
+		if obj, err = load(); err != nil {
+			return err
+		}
+		if err = mutate(obj); err != nil {
+			return err
+		}
+		return update(obj)
+	})
+	return obj, err
+}
+
+// Loads the asset, applies mutate and updates it, starting over from a fresh copy whenever the update conflicts with
+// a concurrent change.  The mutate function may be called several times and must not have other side effects.
+func (client AssetServiceClient) Modify(id string, mutate func(*Asset) error, maxAttempts int) (*Asset, error) {
+	obj, err := modify(maxAttempts,
+		func() (interface{}, error) { return client.Get(id) },
+		func(obj interface{}) error { return mutate(obj.(*Asset)) },
+		func(obj interface{}) error { return client.Update(obj.(*Asset)) })
+	asset, _ := obj.(*Asset)
+	return asset, err
+}
+
+func (client AssetInspectionServiceClient) Modify(id string, mutate func(*AssetInspection) error, maxAttempts int) (*AssetInspection, error) {
+	obj, err := modify(maxAttempts,
+		func() (interface{}, error) { return client.Get(id) },
+		func(obj interface{}) error { return mutate(obj.(*AssetInspection)) },
+		func(obj interface{}) error { return client.Update(obj.(*AssetInspection)) })
+	inspection, _ := obj.(*AssetInspection)
+	return inspection, err
+}
+
+func (client ComponentServiceClient) Modify(id string, mutate func(*Component) error, maxAttempts int) (*Component, error) {
+	obj, err := modify(maxAttempts,
+		func() (interface{}, error) { return client.Get(id) },
+		func(obj interface{}) error { return mutate(obj.(*Component)) },
+		func(obj interface{}) error { return client.Update(obj.(*Component)) })
+	component, _ := obj.(*Component)
+	return component, err
+}
+
+func (client ComponentInspectionServiceClient) Modify(id string, mutate func(*ComponentInspection) error, maxAttempts int) (*ComponentInspection, error) {
+	obj, err := modify(maxAttempts,
+		func() (interface{}, error) { return client.Get(id) },
+		func(obj interface{}) error { return mutate(obj.(*ComponentInspection)) },
+		func(obj interface{}) error { return client.Update(obj.(*ComponentInspection)) })
+	inspection, _ := obj.(*ComponentInspection)
+	return inspection, err
+}
+
+func (client SiteServiceClient) Modify(id string, mutate func(*Site) error, maxAttempts int) (*Site, error) {
+	obj, err := modify(maxAttempts,
+		func() (interface{}, error) { return client.Get(id) },
+		func(obj interface{}) error { return mutate(obj.(*Site)) },
+		func(obj interface{}) error { return client.Update(obj.(*Site)) })
+	site, _ := obj.(*Site)
+	return site, err
+}
+
+func (client WorkOrderServiceClient) Modify(orderNumber string, mutate func(*WorkOrder) error, maxAttempts int) (*WorkOrder, error) {
+	obj, err := modify(maxAttempts,
+		func() (interface{}, error) { return client.Get(orderNumber) },
+		func(obj interface{}) error { return mutate(obj.(*WorkOrder)) },
+		func(obj interface{}) error { return client.Update(obj.(*WorkOrder)) })
+	wo, _ := obj.(*WorkOrder)
+	return wo, err
+}
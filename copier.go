@@ -0,0 +1,520 @@
+package gowindams
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sync"
+)
+
+// Binaries are recorded in the id mapping separately from resource metadata, once uploaded.
+const idMappingResourceBinary = "ResourceBinary"
+
+const ConflictSkip = "skip"
+const ConflictOverwrite = "overwrite"
+const ConflictFail = "fail"
+
+type CopyOptions struct {
+	// Only copy inspections, resources and work orders for this order number.
+	OrderNumber  *string
+	SkipBinaries bool
+	Parallelism  int
+	// Create entities in the target with their ids from the source rather than new ones.
+	KeepIds bool
+	// What to do when an entity with a kept id already exists in the target: ConflictSkip (the default),
+	// ConflictOverwrite or ConflictFail.  Resources and inspection event resources are always saved over existing
+	// ones.
+	OnConflict string
+}
+
+type CopyReport struct {
+	// Entities copied and skipped because an earlier run had copied them, by entity type.
+	Copied  map[string]int
+	Skipped map[string]int
+}
+
+// Where a Copier reads from: another environment, or a backup archive.
+type copySource interface {
+	sourceName() string
+	siteTree(siteId string, options *CopyOptions) (*SiteTree, error)
+	workOrders(siteId string) ([]WorkOrder, error)
+	inspectionEventResources(resourceId string) ([]InspectionEventResource, error)
+	download(resourceId string) (io.ReadCloser, error)
+}
+
+type environmentSource struct {
+	env *Environment
+}
+
+func (s environmentSource) sourceName() string {
+	return s.env.Name
+}
+
+func (s environmentSource) siteTree(siteId string, options *CopyOptions) (*SiteTree, error) {
+	return LoadSiteTree(s.env, siteId, &SiteTreeOptions{
+		Depth:       SiteTreeDepthAll,
+		OrderNumber: options.OrderNumber,
+		Parallelism: options.Parallelism,
+	})
+}
+
+func (s environmentSource) workOrders(siteId string) ([]WorkOrder, error) {
+	return s.env.WorkOrderServiceClient().Search(&WorkOrderSearchCriteria{SiteId: &siteId})
+}
+
+func (s environmentSource) inspectionEventResources(resourceId string) ([]InspectionEventResource, error) {
+	return s.env.InspectionEventResourceServiceClient().Search(&InspectionEventResourceSearchCriteria{ResourceId: &resourceId})
+}
+
+func (s environmentSource) download(resourceId string) (io.ReadCloser, error) {
+	body, err := s.env.ResourceServiceClient().Download(resourceId)
+	if err != nil {
+		return nil, err
+	}
+	return *body, nil
+}
+
+// Copies a site and everything beneath it from one environment to another, assigning new ids in the target and
+// rewriting the references between entities to match.  Ids are recorded in the mapping as each entity is copied, so
+// a copy which fails part way can be resumed with the same mapping.
+type Copier struct {
+	// The environment copied from, nil when the copy is made from a backup.
+	Source  *Environment
+	Target  *Environment
+	Ids     *IdMapping
+	Options CopyOptions
+
+	source copySource
+	mu     sync.Mutex
+	report *CopyReport
+}
+
+func NewCopier(source *Environment, target *Environment, ids *IdMapping, options *CopyOptions) *Copier {
+	c := newCopier(nil, target, ids, options)
+	c.Source = source
+	return c
+}
+
+func newCopier(source copySource, target *Environment, ids *IdMapping, options *CopyOptions) *Copier {
+	if ids == nil {
+		ids = NewIdMapping()
+	}
+	c := &Copier{Target: target, Ids: ids, source: source}
+	if options != nil {
+		c.Options = *options
+	}
+	return c
+}
+
+func (c *Copier) CopySite(siteId string) (*CopyReport, error) {
+	c.report = &CopyReport{Copied: make(map[string]int), Skipped: make(map[string]int)}
+	if c.Source != nil {
+		c.source = environmentSource{env: c.Source}
+	}
+	tree, err := c.source.siteTree(siteId, &c.Options)
+	if err != nil {
+		return nil, err
+	}
+	if tree.Site == nil {
+		return nil, fmt.Errorf("The site %s was not found in %s", siteId, c.source.sourceName())
+	}
+	if err = c.copySite(tree.Site); err != nil {
+		return c.report, err
+	}
+	if err = c.copyWorkOrders(siteId); err != nil {
+		return c.report, err
+	}
+	for _, an := range tree.Assets {
+		if err = c.copyAsset(an.Asset); err != nil {
+			return c.report, err
+		}
+		for _, cn := range an.Components {
+			if err = c.copyComponent(cn.Component); err != nil {
+				return c.report, err
+			}
+		}
+		for _, ain := range an.Inspections {
+			if err = c.copyAssetInspection(ain.AssetInspection); err != nil {
+				return c.report, err
+			}
+			for _, cin := range ain.ComponentInspections {
+				if err = c.copyComponentInspection(cin.ComponentInspection); err != nil {
+					return c.report, err
+				}
+			}
+		}
+		// Component inspections whose asset inspection was not loaded
+		for _, cn := range an.Components {
+			for _, cin := range cn.Inspections {
+				if cin.AssetInspection == nil {
+					if err = c.copyComponentInspection(cin.ComponentInspection); err != nil {
+						return c.report, err
+					}
+				}
+			}
+		}
+	}
+
+	// Resources derived from others, such as scaled images, are copied after their sources so the link can be kept.
+	for _, derived := range []bool{false, true} {
+		g := newWorkGroup(c.Options.Parallelism)
+		for _, rn := range tree.Resources() {
+			rmeta := rn.Resource
+			if (rmeta.SourceResourceId != nil) != derived {
+				continue
+			}
+			g.Go(func() error {
+				return c.copyResource(rmeta)
+			})
+		}
+		if err = g.Wait(); err != nil {
+			return c.report, err
+		}
+	}
+	if err = c.relinkResources(tree); err != nil {
+		return c.report, err
+	}
+	for _, rn := range tree.Resources() {
+		if err = c.copyInspectionEventResources(rn.Resource); err != nil {
+			return c.report, err
+		}
+	}
+	log.Printf("GOWINDAMS: Copied site %s from %s to %s: %v", siteId, c.source.sourceName(), c.Target.Name, c.report.Copied)
+	return c.report, nil
+}
+
+// Copies an entity unless the mapping shows an earlier run did so.  The create function returns the id assigned in
+// the target.
+func (c *Copier) copyEntity(entityType string, sourceId *string, create func() (*string, error)) error {
+	if sourceId == nil {
+		return nil
+	}
+	if _, ok := c.Ids.Get(entityType, *sourceId); ok {
+		c.count(c.report.Skipped, entityType)
+		return nil
+	}
+	id, err := create()
+	if err != nil {
+		return fmt.Errorf("Unable to copy %s %s to %s: %w", entityType, *sourceId, c.Target.Name, err)
+	}
+	if id == nil {
+		return fmt.Errorf("No id was assigned to the copy of %s %s in %s", entityType, *sourceId, c.Target.Name)
+	}
+	if err = c.Ids.Set(entityType, *sourceId, *id); err != nil {
+		return err
+	}
+	c.count(c.report.Copied, entityType)
+	return nil
+}
+
+func (c *Copier) count(counts map[string]int, entityType string) {
+	c.mu.Lock()
+	counts[entityType]++
+	c.mu.Unlock()
+}
+
+// The id to create a copy with: nil, for the target to assign one, unless ids are being kept.
+func (c *Copier) newId(sourceId *string) *string {
+	if !c.Options.KeepIds || sourceId == nil {
+		return nil
+	}
+	id := *sourceId
+	return &id
+}
+
+// Creates an entity whose id may already be taken in the target when ids are kept.
+func (c *Copier) createOrOverwrite(entityType string, id *string, create func() error, update func() error) error {
+	err := create()
+	if !IsConflict(err) || !c.Options.KeepIds {
+		return err
+	}
+	switch c.Options.OnConflict {
+	case ConflictOverwrite:
+		log.Printf("GOWINDAMS: Overwriting %s %s in %s", entityType, stringValue(id), c.Target.Name)
+		return update()
+	case ConflictFail:
+		return err
+	default:
+		log.Printf("GOWINDAMS: Keeping the existing %s %s in %s", entityType, stringValue(id), c.Target.Name)
+		return nil
+	}
+}
+
+// Maps a reference which must already have been copied.
+func (c *Copier) mapRequired(entityType string, sourceId *string) (*string, error) {
+	if sourceId == nil {
+		return nil, nil
+	}
+	id := c.Ids.Map(entityType, sourceId)
+	if id == nil {
+		return nil, fmt.Errorf("The %s %s has not been copied", entityType, *sourceId)
+	}
+	return id, nil
+}
+
+func (c *Copier) copySite(site *Site) error {
+	return c.copyEntity(EntityTypeSite, site.Id, func() (*string, error) {
+		obj := *site
+		obj.Id = c.newId(site.Id)
+		obj.Version = nil
+		err := c.createOrOverwrite(EntityTypeSite, obj.Id, func() error {
+			return c.Target.SiteServiceClient().Create(&obj)
+		}, func() error {
+			return c.Target.SiteServiceClient().Update(&obj)
+		})
+		return obj.Id, err
+	})
+}
+
+// Work orders keep their order numbers, so one which already exists in the target is used as it is unless ids are kept
+// and the conflict policy says otherwise.
+func (c *Copier) copyWorkOrders(siteId string) error {
+	orders, err := c.source.workOrders(siteId)
+	if err != nil {
+		return err
+	}
+	for i := range orders {
+		wo := &orders[i]
+		if c.Options.OrderNumber != nil && stringValue(wo.OrderNumber) != *c.Options.OrderNumber {
+			continue
+		}
+		err = c.copyEntity(EntityTypeWorkOrder, wo.OrderNumber, func() (*string, error) {
+			obj := *wo
+			obj.Version = nil
+			var err error
+			if obj.SiteId, err = c.mapRequired(EntityTypeSite, wo.SiteId); err != nil {
+				return nil, err
+			}
+			err = c.Target.WorkOrderServiceClient().Create(&obj)
+			if IsConflict(err) {
+				if c.Options.KeepIds && c.Options.OnConflict == ConflictOverwrite {
+					return wo.OrderNumber, c.Target.WorkOrderServiceClient().Update(&obj)
+				}
+				if !c.Options.KeepIds || c.Options.OnConflict != ConflictFail {
+					log.Printf("GOWINDAMS: Work order %s already exists in %s", stringValue(wo.OrderNumber), c.Target.Name)
+					return wo.OrderNumber, nil
+				}
+			}
+			return wo.OrderNumber, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Copier) copyAsset(asset *Asset) error {
+	return c.copyEntity(EntityTypeAsset, asset.Id, func() (*string, error) {
+		obj := *asset
+		obj.Id = c.newId(asset.Id)
+		obj.Version = nil
+		var err error
+		if obj.SiteId, err = c.mapRequired(EntityTypeSite, asset.SiteId); err != nil {
+			return nil, err
+		}
+		err = c.createOrOverwrite(EntityTypeAsset, obj.Id, func() error {
+			return c.Target.AssetServiceClient().Create(&obj)
+		}, func() error {
+			return c.Target.AssetServiceClient().Update(&obj)
+		})
+		return obj.Id, err
+	})
+}
+
+func (c *Copier) copyComponent(component *Component) error {
+	return c.copyEntity(EntityTypeComponent, component.Id, func() (*string, error) {
+		obj := *component
+		obj.Id = c.newId(component.Id)
+		obj.Version = nil
+		var err error
+		if obj.SiteId, err = c.mapRequired(EntityTypeSite, component.SiteId); err != nil {
+			return nil, err
+		}
+		if obj.AssetId, err = c.mapRequired(EntityTypeAsset, component.AssetId); err != nil {
+			return nil, err
+		}
+		err = c.createOrOverwrite(EntityTypeComponent, obj.Id, func() error {
+			return c.Target.ComponentServiceClient().Create(&obj)
+		}, func() error {
+			return c.Target.ComponentServiceClient().Update(&obj)
+		})
+		return obj.Id, err
+	})
+}
+
+// Resource lists are filled in by relinkResources once the resources have been copied.
+func (c *Copier) copyAssetInspection(ai *AssetInspection) error {
+	return c.copyEntity(EntityTypeAssetInspection, ai.Id, func() (*string, error) {
+		obj := *ai
+		obj.Id = c.newId(ai.Id)
+		obj.Version = nil
+		obj.Resources = nil
+		var err error
+		if obj.SiteId, err = c.mapRequired(EntityTypeSite, ai.SiteId); err != nil {
+			return nil, err
+		}
+		if obj.AssetId, err = c.mapRequired(EntityTypeAsset, ai.AssetId); err != nil {
+			return nil, err
+		}
+		err = c.createOrOverwrite(EntityTypeAssetInspection, obj.Id, func() error {
+			return c.Target.AssetInspectionServiceClient().Create(&obj)
+		}, func() error {
+			return c.Target.AssetInspectionServiceClient().Update(&obj)
+		})
+		return obj.Id, err
+	})
+}
+
+func (c *Copier) copyComponentInspection(ci *ComponentInspection) error {
+	return c.copyEntity(EntityTypeComponentInspection, ci.Id, func() (*string, error) {
+		obj := *ci
+		obj.Id = c.newId(ci.Id)
+		obj.Version = nil
+		obj.Resources = nil
+		obj.PlateImageResourceId = nil
+		var err error
+		if obj.SiteId, err = c.mapRequired(EntityTypeSite, ci.SiteId); err != nil {
+			return nil, err
+		}
+		if obj.AssetId, err = c.mapRequired(EntityTypeAsset, ci.AssetId); err != nil {
+			return nil, err
+		}
+		if obj.ComponentId, err = c.mapRequired(EntityTypeComponent, ci.ComponentId); err != nil {
+			return nil, err
+		}
+		obj.AssetInspectionId = c.Ids.Map(EntityTypeAssetInspection, ci.AssetInspectionId)
+		err = c.createOrOverwrite(EntityTypeComponentInspection, obj.Id, func() error {
+			return c.Target.ComponentInspectionServiceClient().Create(&obj)
+		}, func() error {
+			return c.Target.ComponentInspectionServiceClient().Update(&obj)
+		})
+		return obj.Id, err
+	})
+}
+
+func (c *Copier) copyResource(rmeta *ResourceMetadata) error {
+	err := c.copyEntity(EntityTypeResource, rmeta.ResourceId, func() (*string, error) {
+		obj := *rmeta
+		obj.ResourceId = c.newId(rmeta.ResourceId)
+		if obj.ResourceId == nil && c.Target.GenerateIds {
+			id := NewUUID()
+			obj.ResourceId = &id
+		}
+		// Generated by the services for the copy
+		obj.DownloadURL = nil
+		obj.SourceURL = nil
+		obj.ZoomifyId = nil
+		obj.ZoomifyURL = nil
+		var err error
+		if obj.SiteId, err = c.mapRequired(EntityTypeSite, rmeta.SiteId); err != nil {
+			return nil, err
+		}
+		if obj.AssetId, err = c.mapRequired(EntityTypeAsset, rmeta.AssetId); err != nil {
+			return nil, err
+		}
+		obj.ComponentId = c.Ids.Map(EntityTypeComponent, rmeta.ComponentId)
+		obj.AssetInspectionId = c.Ids.Map(EntityTypeAssetInspection, rmeta.AssetInspectionId)
+		obj.ComponentInspectionId = c.Ids.Map(EntityTypeComponentInspection, rmeta.ComponentInspectionId)
+		obj.SourceResourceId = c.Ids.Map(EntityTypeResource, rmeta.SourceResourceId)
+		err = c.Target.ResourceServiceClient().Save(&obj)
+		return obj.ResourceId, err
+	})
+	if err != nil || c.Options.SkipBinaries || rmeta.ResourceId == nil {
+		return err
+	}
+	return c.copyEntity(idMappingResourceBinary, rmeta.ResourceId, func() (*string, error) {
+		targetId, _ := c.Ids.Get(EntityTypeResource, *rmeta.ResourceId)
+		body, err := c.source.download(*rmeta.ResourceId)
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		var r io.Reader = body
+		err = c.Target.ResourceServiceClient().Upload(targetId, stringValue(rmeta.ContentType), &r)
+		return &targetId, err
+	})
+}
+
+// Points the resource lists of the copied inspections at the copied resources.
+func (c *Copier) relinkResources(tree *SiteTree) error {
+	for _, an := range tree.Assets {
+		for _, ain := range an.Inspections {
+			ai := ain.AssetInspection
+			resources := c.mapIds(EntityTypeResource, ai.Resources)
+			if len(resources) == 0 {
+				continue
+			}
+			targetId, _ := c.Ids.Get(EntityTypeAssetInspection, stringValue(ai.Id))
+			_, err := c.Target.AssetInspectionServiceClient().Modify(targetId, func(obj *AssetInspection) error {
+				obj.Resources = resources
+				return nil
+			}, DEFAULT_UPDATE_ATTEMPTS)
+			if err != nil {
+				return err
+			}
+		}
+		for _, cn := range an.Components {
+			for _, cin := range cn.Inspections {
+				ci := cin.ComponentInspection
+				resources := c.mapIds(EntityTypeResource, ci.Resources)
+				plate := c.Ids.Map(EntityTypeResource, ci.PlateImageResourceId)
+				if len(resources) == 0 && plate == nil {
+					continue
+				}
+				targetId, _ := c.Ids.Get(EntityTypeComponentInspection, stringValue(ci.Id))
+				_, err := c.Target.ComponentInspectionServiceClient().Modify(targetId, func(obj *ComponentInspection) error {
+					obj.Resources = resources
+					obj.PlateImageResourceId = plate
+					return nil
+				}, DEFAULT_UPDATE_ATTEMPTS)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Copier) mapIds(entityType string, ids []string) []string {
+	mapped := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if target, ok := c.Ids.Get(entityType, id); ok {
+			mapped = append(mapped, target)
+		}
+	}
+	return mapped
+}
+
+func (c *Copier) copyInspectionEventResources(rmeta *ResourceMetadata) error {
+	if rmeta.ResourceId == nil {
+		return nil
+	}
+	iers, err := c.source.inspectionEventResources(*rmeta.ResourceId)
+	if err != nil {
+		return err
+	}
+	for i := range iers {
+		ier := &iers[i]
+		if c.Options.OrderNumber != nil && ier.OrderNumber != nil && *ier.OrderNumber != *c.Options.OrderNumber {
+			continue
+		}
+		err = c.copyEntity(EntityTypeInspectionEventResource, ier.Id, func() (*string, error) {
+			obj := *ier
+			obj.Id = c.newId(ier.Id)
+			var err error
+			if obj.ResourceId, err = c.mapRequired(EntityTypeResource, ier.ResourceId); err != nil {
+				return nil, err
+			}
+			obj.AssetId = c.Ids.Map(EntityTypeAsset, ier.AssetId)
+			obj.SiteId = c.Ids.Map(EntityTypeSite, ier.SiteId)
+			err = c.Target.InspectionEventResourceServiceClient().Save(&obj)
+			return obj.Id, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
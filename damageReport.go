@@ -0,0 +1,480 @@
+package gowindams
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DEFAULT_REPORT_IMAGE_SIZE = 1200
+
+type DamageReportOptions struct {
+	Title string
+	// Longest side in pixels of the images embedded in the report.
+	ImageSize int
+	// Include images without any damage marked on them.
+	IncludeAllImages bool
+	Parallelism      int
+}
+
+// The damage found on the turbines of a work order, or of a single asset inspection, ready to be written as HTML or
+// PDF.
+type DamageReport struct {
+	Title       string
+	GeneratedAt time.Time
+	Site        *Site
+	WorkOrder   *WorkOrder
+	Turbines    []*DamageReportTurbine
+	// The severities found, in increasing order, and the number of polygons of each per component.
+	Severities []int
+	Summary    []DamageSeverityRow
+}
+
+type DamageReportTurbine struct {
+	Asset           *Asset
+	AssetInspection *AssetInspection
+	Components      []*DamageReportComponent
+	// Images of the asset inspection not taken for one of its component inspections.
+	Images []*DamageReportImage
+}
+
+type DamageReportComponent struct {
+	Component  *Component
+	Inspection *ComponentInspection
+	Images     []*DamageReportImage
+}
+
+type DamageReportImage struct {
+	Resource *ResourceMetadata
+	Polygons []InspectionEventPolygon
+	// The image, scaled down and JPEG encoded, and the size of the original, which the polygons are drawn against.
+	JPEG   []byte
+	Width  int
+	Height int
+}
+
+type DamageSeverityRow struct {
+	Asset     string
+	Component string
+	// Aligned with the report's Severities.
+	Counts  []int
+	Unrated int
+	Total   int
+	// The highest severity found, zero if none were rated.
+	Highest int
+}
+
+// Reports the damage found by an asset inspection.
+func AssetInspectionDamageReport(env *Environment, assetInspectionId string, options *DamageReportOptions) (*DamageReport, error) {
+	ai, err := env.AssetInspectionServiceClient().Get(assetInspectionId)
+	if err != nil {
+		return nil, err
+	}
+	if ai.SiteId == nil {
+		return nil, fmt.Errorf("The asset inspection %s has no site", assetInspectionId)
+	}
+	return newDamageReport(environmentSource{env: env}, *ai.SiteId, ai.OrderNumber, &assetInspectionId, options)
+}
+
+// Reports the damage found on every turbine inspected for a work order.
+func WorkOrderDamageReport(env *Environment, orderNumber string, options *DamageReportOptions) (*DamageReport, error) {
+	wo, err := env.WorkOrderServiceClient().Get(orderNumber)
+	if err != nil {
+		return nil, err
+	}
+	if wo.SiteId == nil {
+		return nil, fmt.Errorf("The work order %s has no site", orderNumber)
+	}
+	return newDamageReport(environmentSource{env: env}, *wo.SiteId, &orderNumber, nil, options)
+}
+
+// Reports damage from a backup rather than the services, for a work order when orderNumber is not empty and for a
+// single asset inspection when assetInspectionId is not empty.
+func BackupDamageReport(backup *Backup, orderNumber string, assetInspectionId string, options *DamageReportOptions) (*DamageReport, error) {
+	var order, id *string
+	if orderNumber != "" {
+		order = &orderNumber
+	}
+	if assetInspectionId != "" {
+		id = &assetInspectionId
+	}
+	return newDamageReport(backup, backup.Manifest.SiteId, order, id, options)
+}
+
+func newDamageReport(source copySource, siteId string, orderNumber *string, assetInspectionId *string, options *DamageReportOptions) (*DamageReport, error) {
+	if options == nil {
+		options = &DamageReportOptions{}
+	}
+	tree, err := source.siteTree(siteId, &CopyOptions{OrderNumber: orderNumber, Parallelism: options.Parallelism})
+	if err != nil {
+		return nil, err
+	}
+	report := &DamageReport{Title: options.Title, GeneratedAt: time.Now(), Site: tree.Site}
+	if orderNumber != nil {
+		workOrders, err := source.workOrders(siteId)
+		if err != nil {
+			return nil, err
+		}
+		for i := range workOrders {
+			if stringValue(workOrders[i].OrderNumber) == *orderNumber {
+				report.WorkOrder = &workOrders[i]
+			}
+		}
+	}
+	if report.Title == "" {
+		report.Title = "Damage report"
+		if tree.Site != nil && tree.Site.Name != nil {
+			report.Title += " for " + *tree.Site.Name
+		}
+	}
+
+	var images []*DamageReportImage
+	addImages := func(resources []*ResourceNode) ([]*DamageReportImage, error) {
+		found := make([]*DamageReportImage, 0)
+		for _, rn := range resources {
+			img, err := reportImage(source, rn.Resource, orderNumber, options.IncludeAllImages)
+			if err != nil {
+				return nil, err
+			}
+			if img != nil {
+				found = append(found, img)
+				images = append(images, img)
+			}
+		}
+		return found, nil
+	}
+	for _, an := range tree.Assets {
+		for _, ain := range an.Inspections {
+			ai := ain.AssetInspection
+			if (assetInspectionId != nil && stringValue(ai.Id) != *assetInspectionId) ||
+				(orderNumber != nil && stringValue(ai.OrderNumber) != *orderNumber) {
+				continue
+			}
+			turbine := &DamageReportTurbine{Asset: an.Asset, AssetInspection: ai}
+			for _, cin := range ain.ComponentInspections {
+				component := &DamageReportComponent{Inspection: cin.ComponentInspection}
+				if cin.Component != nil {
+					component.Component = cin.Component.Component
+				}
+				if component.Images, err = addImages(cin.Resources); err != nil {
+					return nil, err
+				}
+				turbine.Components = append(turbine.Components, component)
+			}
+			if turbine.Images, err = addImages(ain.Resources); err != nil {
+				return nil, err
+			}
+			report.Turbines = append(report.Turbines, turbine)
+		}
+	}
+	if assetInspectionId != nil && len(report.Turbines) == 0 {
+		return nil, fmt.Errorf("The asset inspection %s was not found in %s", *assetInspectionId, source.sourceName())
+	}
+	if err = loadReportImages(source, images, options); err != nil {
+		return nil, err
+	}
+	report.summarize()
+	return report, nil
+}
+
+// The image of a resource with the polygons marked on it for the order, or nil if it should not be in the report.
+// Images derived from another, such as scaled copies, are only reported when damage is marked on them.
+func reportImage(source copySource, rmeta *ResourceMetadata, orderNumber *string, includeAll bool) (*DamageReportImage, error) {
+	if rmeta.ResourceId == nil || !strings.HasPrefix(stringValue(rmeta.ContentType), "image/") {
+		return nil, nil
+	}
+	polygons, err := resourcePolygons(source, *rmeta.ResourceId, orderNumber)
+	if err != nil {
+		return nil, err
+	}
+	img := &DamageReportImage{Resource: rmeta, Polygons: polygons}
+	if len(img.Polygons) == 0 && (!includeAll || rmeta.SourceResourceId != nil) {
+		return nil, nil
+	}
+	return img, nil
+}
+
+// Downloads the images concurrently, recording the size of each original and scaling it down for the report.
+func loadReportImages(source copySource, images []*DamageReportImage, options *DamageReportOptions) error {
+	size := options.ImageSize
+	if size <= 0 {
+		size = DEFAULT_REPORT_IMAGE_SIZE
+	}
+	var mu sync.Mutex
+	g := newWorkGroup(options.Parallelism)
+	for _, img := range images {
+		img := img
+		g.Go(func() error {
+			id := *img.Resource.ResourceId
+			body, err := source.download(id)
+			if err != nil {
+				return fmt.Errorf("Unable to download resource %s: %w", id, err)
+			}
+			data, err := ioutil.ReadAll(body)
+			body.Close()
+			if err != nil {
+				return err
+			}
+			config, _, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				return fmt.Errorf("Unable to decode resource %s: %w", id, err)
+			}
+			scaled, err := MakeThumbnail(bytes.NewReader(data), size)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			img.Width, img.Height, img.JPEG = config.Width, config.Height, scaled
+			mu.Unlock()
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+func (report *DamageReport) summarize() {
+	present := make(map[int]bool)
+	for _, t := range report.Turbines {
+		for _, img := range t.allImages() {
+			for _, poly := range img.Polygons {
+				if poly.Severity != nil && *poly.Severity > 0 {
+					present[int(*poly.Severity)] = true
+				}
+			}
+		}
+	}
+	report.Severities = make([]int, 0, len(present))
+	for s := range present {
+		report.Severities = append(report.Severities, s)
+	}
+	sort.Ints(report.Severities)
+	column := make(map[int]int)
+	for i, s := range report.Severities {
+		column[s] = i
+	}
+
+	report.Summary = make([]DamageSeverityRow, 0)
+	add := func(t *DamageReportTurbine, component string, images []*DamageReportImage) {
+		row := DamageSeverityRow{Asset: t.name(), Component: component, Counts: make([]int, len(report.Severities))}
+		for _, img := range images {
+			for _, poly := range img.Polygons {
+				row.Total++
+				if poly.Severity == nil || *poly.Severity <= 0 {
+					row.Unrated++
+					continue
+				}
+				row.Counts[column[int(*poly.Severity)]]++
+				if int(*poly.Severity) > row.Highest {
+					row.Highest = int(*poly.Severity)
+				}
+			}
+		}
+		report.Summary = append(report.Summary, row)
+	}
+	for _, t := range report.Turbines {
+		for _, c := range t.Components {
+			add(t, c.name(), c.Images)
+		}
+		if len(t.Images) > 0 {
+			add(t, "Other", t.Images)
+		}
+	}
+}
+
+func (t *DamageReportTurbine) name() string {
+	if t.Asset == nil {
+		return stringValue(t.AssetInspection.AssetId)
+	}
+	return firstNonEmpty(stringValue(t.Asset.Name), stringValue(t.Asset.SerialNumber), stringValue(t.Asset.Id))
+}
+
+func (t *DamageReportTurbine) allImages() []*DamageReportImage {
+	images := append([]*DamageReportImage{}, t.Images...)
+	for _, c := range t.Components {
+		images = append(images, c.Images...)
+	}
+	return images
+}
+
+func (c *DamageReportComponent) name() string {
+	if c.Component == nil {
+		return stringValue(c.Inspection.ComponentId)
+	}
+	name := firstNonEmpty(stringValue(c.Component.Type), stringValue(c.Component.Id))
+	if c.Component.SerialNumber != nil {
+		name += " (" + *c.Component.SerialNumber + ")"
+	}
+	return name
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// A caption describing where an image was taken.
+func (img *DamageReportImage) caption() string {
+	parts := []string{firstNonEmpty(stringValue(img.Resource.Name), stringValue(img.Resource.ResourceId))}
+	if p := img.Resource.Position; p != nil {
+		if p.Side != nil {
+			parts = append(parts, *p.Side)
+		}
+		if p.X != nil && p.Y != nil {
+			parts = append(parts, fmt.Sprintf("x %.2f, y %.2f", *p.X, *p.Y))
+		}
+	}
+	if img.Resource.Timestamp != nil {
+		parts = append(parts, img.Resource.Timestamp.Format("2006-01-02 15:04"))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func severityText(severity *int8) string {
+	if severity == nil || *severity <= 0 {
+		return "unrated"
+	}
+	return fmt.Sprintf("%d", *severity)
+}
+
+func severityLevelColor(severity int) color.RGBA {
+	v := int8(severity)
+	return severityColor(&v)
+}
+
+func dateText(d *WindAMSDate) string {
+	if d == nil {
+		return ""
+	}
+	return d.Format("2006-01-02")
+}
+
+var damageReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"date":       dateText,
+	"str":        stringValue,
+	"severity":   severityText,
+	"color":      func(s *int8) string { return hexColor(severityColor(s)) },
+	"levelColor": func(s int) string { return hexColor(severityLevelColor(s)) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #bbb; padding: 0.3em 0.6em; text-align: left; }
+td.n { text-align: right; }
+figure { margin: 1em 0; max-width: 60em; page-break-inside: avoid; }
+figcaption { color: #555; font-size: 0.9em; }
+.swatch { display: inline-block; width: 0.8em; height: 0.8em; margin-right: 0.3em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{with .Site}}Site {{str .Name}}. {{end}}{{with .WorkOrder}}Work order {{str .OrderNumber}}{{with .Description}}: {{.}}{{end}}. {{end}}Generated {{.GeneratedAt.Format "2006-01-02 15:04"}}.</p>
+<h2>Severity summary</h2>
+<table>
+<tr><th>Turbine</th><th>Component</th>{{range .Severities}}<th><span class="swatch" style="background: {{levelColor .}}"></span>{{.}}</th>{{end}}<th>Unrated</th><th>Total</th><th>Highest</th></tr>
+{{range .Summary}}<tr><td>{{.Asset}}</td><td>{{.Component}}</td>{{range .Counts}}<td class="n">{{.}}</td>{{end}}<td class="n">{{.Unrated}}</td><td class="n">{{.Total}}</td><td class="n">{{if .Highest}}{{.Highest}}{{end}}</td></tr>
+{{end}}</table>
+{{range .Turbines}}
+<h2>{{.Name}}</h2>
+{{with .AssetInspection}}<p>Inspected {{date .DateOfInspection}}{{with .Status}}, status {{.}}{{end}}{{with .ProcessedBy}}, processed by {{.}}{{end}}.</p>{{end}}
+{{range .Components}}
+<h3>{{.Name}}</h3>
+{{with .Inspection}}<table>
+{{with .Type}}<tr><th>Inspection</th><td>{{.}}</td></tr>{{end}}
+{{with .CurrentStatus}}<tr><th>Status</th><td>{{.}}</td></tr>{{end}}
+{{with .InspectionPosition}}<tr><th>Position</th><td>{{.}}</td></tr>{{end}}
+{{with .ReasonForService}}<tr><th>Reason for service</th><td>{{.}}</td></tr>{{end}}
+{{with .Description}}<tr><th>Description</th><td>{{.}}</td></tr>{{end}}
+</table>{{end}}
+{{range .Images}}{{template "image" .}}{{end}}
+{{end}}
+{{if .Images}}<h3>Other images</h3>{{range .Images}}{{template "image" .}}{{end}}{{end}}
+{{end}}
+</body>
+</html>
+{{define "image"}}<figure>
+{{.SVG}}
+<figcaption>{{.Caption}}</figcaption>
+{{if .Polygons}}<table>
+<tr><th>Damage</th><th>Severity</th><th>Notes</th></tr>
+{{range .Polygons}}<tr><td><span class="swatch" style="background: {{color .Severity}}"></span>{{str .Name}}</td><td>{{severity .Severity}}</td><td>{{str .Text}}</td></tr>
+{{end}}</table>{{end}}
+</figure>
+{{end}}`))
+
+// Views over the report's parts giving the template what it needs.
+type htmlReport struct {
+	*DamageReport
+	Turbines []htmlTurbine
+}
+
+type htmlTurbine struct {
+	*DamageReportTurbine
+	Name       string
+	Components []htmlComponent
+	Images     []htmlImage
+}
+
+type htmlComponent struct {
+	*DamageReportComponent
+	Name   string
+	Images []htmlImage
+}
+
+type htmlImage struct {
+	*DamageReportImage
+	SVG     template.HTML
+	Caption string
+}
+
+func htmlImages(images []*DamageReportImage) ([]htmlImage, error) {
+	views := make([]htmlImage, 0, len(images))
+	for _, img := range images {
+		var sb strings.Builder
+		href := "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(img.JPEG)
+		if err := WriteSVGOverlay(&sb, href, img.Width, img.Height, img.Polygons); err != nil {
+			return nil, err
+		}
+		views = append(views, htmlImage{DamageReportImage: img, SVG: template.HTML(sb.String()), Caption: img.caption()})
+	}
+	return views, nil
+}
+
+// Writes the report as a single HTML page, with the images embedded and the damage drawn over them in SVG.
+func (report *DamageReport) WriteHTML(w io.Writer) error {
+	view := htmlReport{DamageReport: report}
+	var err error
+	for _, t := range report.Turbines {
+		ht := htmlTurbine{DamageReportTurbine: t, Name: t.name()}
+		for _, c := range t.Components {
+			hc := htmlComponent{DamageReportComponent: c, Name: c.name()}
+			if hc.Images, err = htmlImages(c.Images); err != nil {
+				return err
+			}
+			ht.Components = append(ht.Components, hc)
+		}
+		if ht.Images, err = htmlImages(t.Images); err != nil {
+			return err
+		}
+		view.Turbines = append(view.Turbines, ht)
+	}
+	log.Printf("GOWINDAMS: Writing HTML damage report for %d turbines", len(report.Turbines))
+	return damageReportTemplate.Execute(w, view)
+}
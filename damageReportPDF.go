@@ -0,0 +1,235 @@
+package gowindams
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"log"
+	"math"
+)
+
+const pdfMargin = 40.0
+const pdfLineGap = 1.35
+const pdfMaxImageHeight = 380.0
+
+var pdfBlack = color.RGBA{A: 255}
+var pdfGrey = color.RGBA{R: 100, G: 100, B: 100, A: 255}
+var pdfRule = color.RGBA{R: 190, G: 190, B: 190, A: 255}
+var pdfWhite = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+// Lays out the report top to bottom, starting new pages as they fill.
+type pdfReportWriter struct {
+	doc  *pdfDocument
+	page *pdfPage
+	y    float64
+}
+
+func (pw *pdfReportWriter) contentWidth() float64 {
+	return pw.doc.width - 2*pdfMargin
+}
+
+func (pw *pdfReportWriter) newPage() {
+	pw.page = pw.doc.addPage()
+	pw.y = pw.doc.height - pdfMargin
+	n := len(pw.doc.pages)
+	label := fmt.Sprintf("Page %d", n)
+	pw.page.text(pw.doc.width-pdfMargin-pdfTextWidth(label, pdfFontRegular, 8), pdfMargin/2, pdfFontRegular, 8, pdfGrey, label)
+}
+
+// Starts a new page unless there is room for height more points on this one.
+func (pw *pdfReportWriter) ensure(height float64) {
+	if pw.page == nil || pw.y-height < pdfMargin {
+		pw.newPage()
+	}
+}
+
+func (pw *pdfReportWriter) paragraph(s string, font string, size float64, c color.RGBA) {
+	for _, line := range pdfWrap(s, font, size, pw.contentWidth()) {
+		pw.ensure(size * pdfLineGap)
+		pw.y -= size * pdfLineGap
+		pw.page.text(pdfMargin, pw.y, font, size, c, line)
+	}
+}
+
+func (pw *pdfReportWriter) space(points float64) {
+	pw.y -= points
+}
+
+// Draws a table with columns of the given widths.  Cells which do not fit are wrapped, and the header is repeated at
+// the top of each page the table runs onto.
+func (pw *pdfReportWriter) table(widths []float64, header []string, rows [][]string, size float64) {
+	pw.tableRow(widths, header, pdfFontBold, size, nil)
+	for _, cells := range rows {
+		pw.tableRow(widths, cells, pdfFontRegular, size, header)
+	}
+}
+
+func (pw *pdfReportWriter) tableRow(widths []float64, cells []string, font string, size float64, header []string) {
+	pad := 3.0
+	wrapped := make([][]string, len(cells))
+	lines := 1
+	for i, cell := range cells {
+		wrapped[i] = pdfWrap(cell, font, size, widths[i]-2*pad)
+		if len(wrapped[i]) > lines {
+			lines = len(wrapped[i])
+		}
+	}
+	height := float64(lines)*size*pdfLineGap + 2*pad
+	if pw.page == nil || pw.y-height < pdfMargin {
+		pw.newPage()
+		if header != nil {
+			pw.tableRow(widths, header, pdfFontBold, size, nil)
+		}
+	}
+	x := pdfMargin
+	for i, lines := range wrapped {
+		for j, line := range lines {
+			pw.page.text(x+pad, pw.y-pad-float64(j+1)*size*pdfLineGap+size*0.3, font, size, pdfBlack, line)
+		}
+		x += widths[i]
+	}
+	pw.y -= height
+	pw.page.line(pdfMargin, pw.y, x, pw.y, 0.5, pdfRule)
+}
+
+// Draws an image scaled to fit the width of the page with the damage outlined over it.
+func (pw *pdfReportWriter) image(img *DamageReportImage) {
+	if img.JPEG == nil || img.Width <= 0 || img.Height <= 0 {
+		return
+	}
+	// The embedded copy is smaller than the original, whose size the layout and the polygons are based on.
+	config, err := jpeg.DecodeConfig(bytes.NewReader(img.JPEG))
+	if err != nil {
+		return
+	}
+	scale := math.Min(pw.contentWidth()/float64(img.Width), pdfMaxImageHeight/float64(img.Height))
+	w, h := float64(img.Width)*scale, float64(img.Height)*scale
+	pw.ensure(h + 4)
+	pw.y -= h + 4
+	x, y := pdfMargin, pw.y
+	pw.page.image(pw.doc.addJPEG(img.JPEG, config.Width, config.Height), x, y, w, h)
+	for _, shape := range overlayShapes(img.Polygons, img.Width, img.Height) {
+		points := make([][2]float64, len(shape.points))
+		for i, p := range shape.points {
+			points[i] = [2]float64{x + p[0]*scale, y + h - p[1]*scale}
+		}
+		pw.page.strokePolygon(points, 1.5, shape.color)
+		if shape.label != "" {
+			lx, ly := shape.labelAnchor()
+			lx, ly = x+lx*scale, math.Min(y+h-ly*scale+2, y+h-9)
+			lw := pdfTextWidth(shape.label, pdfFontBold, 8)
+			pw.page.fillRect(lx-1, ly-2, lw+2, 10, pdfWhite)
+			pw.page.text(lx, ly, pdfFontBold, 8, shape.color, shape.label)
+		}
+	}
+}
+
+// Writes the report as a PDF document with the images embedded and the damage outlined over them.
+func (report *DamageReport) WritePDF(w io.Writer) error {
+	pw := &pdfReportWriter{doc: newPDFDocument(pdfA4Width, pdfA4Height)}
+	pw.paragraph(report.Title, pdfFontBold, 18, pdfBlack)
+	pw.space(4)
+	subtitle := ""
+	if report.Site != nil {
+		subtitle += "Site " + stringValue(report.Site.Name) + ". "
+	}
+	if report.WorkOrder != nil {
+		subtitle += "Work order " + stringValue(report.WorkOrder.OrderNumber)
+		if report.WorkOrder.Description != nil {
+			subtitle += ": " + *report.WorkOrder.Description
+		}
+		subtitle += ". "
+	}
+	pw.paragraph(subtitle+"Generated "+report.GeneratedAt.Format("2006-01-02 15:04")+".", pdfFontRegular, 10, pdfGrey)
+
+	pw.space(10)
+	pw.paragraph("Severity summary", pdfFontBold, 14, pdfBlack)
+	pw.space(4)
+	header := []string{"Turbine", "Component"}
+	for _, s := range report.Severities {
+		header = append(header, fmt.Sprintf("%d", s))
+	}
+	header = append(header, "Unrated", "Total", "Highest")
+	fixed := 3 * 45.0
+	each := math.Min(35, (pw.contentWidth()-fixed-240)/math.Max(1, float64(len(report.Severities))))
+	first := (pw.contentWidth() - fixed - each*float64(len(report.Severities))) / 2
+	widths := []float64{first, first}
+	rows := make([][]string, 0, len(report.Summary))
+	for _, row := range report.Summary {
+		cells := []string{row.Asset, row.Component}
+		for _, n := range row.Counts {
+			cells = append(cells, fmt.Sprintf("%d", n))
+		}
+		highest := ""
+		if row.Highest > 0 {
+			highest = fmt.Sprintf("%d", row.Highest)
+		}
+		rows = append(rows, append(cells, fmt.Sprintf("%d", row.Unrated), fmt.Sprintf("%d", row.Total), highest))
+	}
+	for range report.Severities {
+		widths = append(widths, each)
+	}
+	widths = append(widths, 45, 45, 45)
+	pw.table(widths, header, rows, 9)
+
+	for _, t := range report.Turbines {
+		pw.newPage()
+		pw.paragraph(t.name(), pdfFontBold, 16, pdfBlack)
+		if ai := t.AssetInspection; ai != nil {
+			details := "Inspected " + dateText(ai.DateOfInspection)
+			if ai.Status != nil {
+				details += ", status " + *ai.Status
+			}
+			pw.paragraph(details+".", pdfFontRegular, 10, pdfGrey)
+		}
+		for _, c := range t.Components {
+			pw.space(10)
+			pw.ensure(60)
+			pw.paragraph(c.name(), pdfFontBold, 13, pdfBlack)
+			if ci := c.Inspection; ci != nil {
+				for _, field := range []struct {
+					name  string
+					value *string
+				}{
+					{"Inspection", ci.Type},
+					{"Status", ci.CurrentStatus()},
+					{"Position", ci.InspectionPosition},
+					{"Reason for service", ci.ReasonForService},
+					{"Description", ci.Description},
+				} {
+					if field.value != nil && *field.value != "" {
+						pw.paragraph(field.name+": "+*field.value, pdfFontRegular, 10, pdfBlack)
+					}
+				}
+			}
+			pw.images(c.Images)
+		}
+		if len(t.Images) > 0 {
+			pw.space(10)
+			pw.paragraph("Other images", pdfFontBold, 13, pdfBlack)
+			pw.images(t.Images)
+		}
+	}
+	log.Printf("GOWINDAMS: Writing PDF damage report for %d turbines on %d pages", len(report.Turbines), len(pw.doc.pages))
+	_, err := pw.doc.WriteTo(w)
+	return err
+}
+
+func (pw *pdfReportWriter) images(images []*DamageReportImage) {
+	for _, img := range images {
+		pw.space(8)
+		pw.image(img)
+		pw.paragraph(img.caption(), pdfFontRegular, 9, pdfGrey)
+		if len(img.Polygons) == 0 {
+			continue
+		}
+		pw.space(2)
+		rows := make([][]string, 0, len(img.Polygons))
+		for _, poly := range img.Polygons {
+			rows = append(rows, []string{stringValue(poly.Name), severityText(poly.Severity), stringValue(poly.Text)})
+		}
+		pw.table([]float64{130, 60, pw.contentWidth() - 190}, []string{"Damage", "Severity", "Notes"}, rows, 9)
+	}
+}
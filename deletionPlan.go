@@ -0,0 +1,133 @@
+package gowindams
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+type DeletionStep struct {
+	EntityType string
+	Id         string
+	Deleted    bool
+}
+
+// An ordered list of entities to delete, dependents first.
+type DeletionPlan struct {
+	Steps []DeletionStep
+}
+
+func (plan *DeletionPlan) add(entityType string, id string) {
+	for _, step := range plan.Steps {
+		if step.EntityType == entityType && step.Id == id {
+			return
+		}
+	}
+	plan.Steps = append(plan.Steps, DeletionStep{EntityType: entityType, Id: id})
+}
+
+func (plan *DeletionPlan) String() string {
+	var sb strings.Builder
+	for i, step := range plan.Steps {
+		status := "pending"
+		if step.Deleted {
+			status = "deleted"
+		}
+		fmt.Fprintf(&sb, "%d\t%s\t%s\t%s\n", i+1, step.EntityType, step.Id, status)
+	}
+	return sb.String()
+}
+
+// Deletes each step in order, stopping at the first failure.  Steps already deleted are skipped, so a failed plan
+// may be executed again.
+func (plan *DeletionPlan) Execute(env *Environment) error {
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		if step.Deleted {
+			continue
+		}
+		var err error
+		switch step.EntityType {
+		case EntityTypeAsset:
+			err = env.AssetServiceClient().Delete(step.Id)
+		case EntityTypeAssetInspection:
+			err = env.AssetInspectionServiceClient().Delete(step.Id)
+		case EntityTypeComponent:
+			err = env.ComponentServiceClient().Delete(step.Id)
+		case EntityTypeComponentInspection:
+			err = env.ComponentInspectionServiceClient().Delete(step.Id)
+		case EntityTypeInspectionEventResource:
+			err = env.InspectionEventResourceServiceClient().Delete(step.Id)
+		case EntityTypeResource:
+			err = env.ResourceServiceClient().Delete(step.Id)
+		case EntityTypeSite:
+			err = env.SiteServiceClient().Delete(step.Id)
+		case EntityTypeWorkOrder:
+			err = env.WorkOrderServiceClient().Delete(step.Id)
+		default:
+			err = fmt.Errorf("Unable to delete entities of type %s", step.EntityType)
+		}
+		if err != nil {
+			log.Printf("GOWINDAMS: Error deleting %s %s: %s\n", step.EntityType, step.Id, err)
+			return err
+		}
+		step.Deleted = true
+	}
+	return nil
+}
+
+// Plans the deletion of a component inspection together with its resources and their inspection event resources.  If
+// dryRun is true the plan is returned without deleting anything.  Deleting a resource removes its metadata only; the
+// services keep the uploaded binary.
+func (client ComponentInspectionServiceClient) DeleteCascade(id string, dryRun bool) (*DeletionPlan, error) {
+	inspection, err := client.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := client.env.ResourceServiceClient().Search(&ResourceSearchCriteria{ComponentInspectionId: &id})
+	if err != nil {
+		return nil, err
+	}
+	// The inspection's list of resources and the search usually overlap.
+	resourceIds := make([]string, 0, len(inspection.Resources)+len(resources))
+	seen := make(map[string]bool)
+	addResource := func(resourceId string) {
+		if !seen[resourceId] {
+			seen[resourceId] = true
+			resourceIds = append(resourceIds, resourceId)
+		}
+	}
+	for _, resourceId := range inspection.Resources {
+		addResource(resourceId)
+	}
+	for _, rmeta := range resources {
+		if rmeta.ResourceId != nil {
+			addResource(*rmeta.ResourceId)
+		}
+	}
+
+	plan := new(DeletionPlan)
+	for _, resourceId := range resourceIds {
+		rid := resourceId
+		iers, err := client.env.InspectionEventResourceServiceClient().Search(&InspectionEventResourceSearchCriteria{ResourceId: &rid})
+		if err != nil {
+			return nil, err
+		}
+		for _, ier := range iers {
+			if ier.Id != nil {
+				plan.add(EntityTypeInspectionEventResource, *ier.Id)
+			}
+		}
+	}
+	for _, resourceId := range resourceIds {
+		plan.add(EntityTypeResource, resourceId)
+	}
+	plan.add(EntityTypeComponentInspection, id)
+
+	if dryRun {
+		log.Printf("GOWINDAMS: Dry run, %d entities would be deleted for component inspection %s", len(plan.Steps), id)
+		return plan, nil
+	}
+	err = plan.Execute(client.env)
+	return plan, err
+}
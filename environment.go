@@ -16,6 +16,9 @@ type EnvironmentConfig struct {
 	TenantId string            `json:"tenantId"            yaml:"tenantId"`
 	ServiceAppId string        `json:"serviceAppId"        yaml:"serviceAppId"`
 	AccessTokenProvider string `json:"accessTokenProvider" yaml:"accessTokenProvider"`
+	SearchFeatures []string    `json:"searchFeatures"      yaml:"searchFeatures"`
+	GenerateIds bool           `json:"generateIds"         yaml:"generateIds"`
+	UserId string              `json:"userId"              yaml:"userId"`
 }
 
 type EnvironmentConfigs []EnvironmentConfig
@@ -26,6 +29,11 @@ type Environment struct {
 	ServiceAppId string
 	ServiceURI string
 	TenantId string
+	SearchFeatures []string
+	// When set, entities created without an id are assigned a random UUID before being sent to the services.
+	GenerateIds bool
+	// Recorded as the user performing status transitions made through this environment's clients.
+	UserId string
 	accessTokenProvider accessTokenProvider
 	assetServiceClient *AssetServiceClient
 	assetInspectionServiceClient *AssetInspectionServiceClient
@@ -69,6 +77,17 @@ func (env Environment) IsUserAuthenticated() bool {
 	return env.accessTokenProvider != nil && env.accessTokenProvider.isUserAuthenticated()
 }
 
+// Whether the services support the given SearchFeature* capability, as listed under searchFeatures in the
+// environment's configuration.
+func (env Environment) SupportsSearchFeature(feature string) bool {
+	for _, f := range env.SearchFeatures {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
 func (env Environment) ObtainAccessToken() (string, error) {
 	if env.accessTokenProvider == nil {
 		// No provider
@@ -148,6 +167,9 @@ func LoadEnvironments(configFilePath string) (*Environments, error) {
 			ServiceAppId:        cfg.ServiceAppId,
 			ServiceURI:          strings.TrimRight(cfg.ServiceURI, "/"),
 			TenantId:            cfg.TenantId,
+			SearchFeatures:      cfg.SearchFeatures,
+			GenerateIds:         cfg.GenerateIds,
+			UserId:              cfg.UserId,
 			accessTokenProvider: NewProvider(&cfg),
 		}
 		env.assetInspectionServiceClient = &AssetInspectionServiceClient{
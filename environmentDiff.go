@@ -0,0 +1,288 @@
+package gowindams
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const DiffAdded = "added"
+const DiffRemoved = "removed"
+const DiffChanged = "changed"
+
+// Fields managed by the services, which differ between environments holding the same data.
+var DefaultDiffIgnore = map[string][]string{
+	EntityTypeResource: {"downloadURL", "sourceURL", "zoomifyId", "zoomifyURL"},
+	"":                 {"version"},
+}
+
+type DiffOptions struct {
+	// Maps ids in the first environment to those in the second, as recorded when copying between them.  Without it
+	// entities are matched by id.
+	Ids *IdMapping
+	// Further fields to ignore, by entity type, using the patterns of CompareFields.  Patterns under "" apply to every
+	// type.  These are added to DefaultDiffIgnore unless NoDefaultIgnore is set.
+	Ignore          map[string][]string
+	NoDefaultIgnore bool
+	OrderNumber     *string
+	Parallelism     int
+}
+
+// An entity which is only in one environment, or differs between them.  Id is the id in the first environment, if
+// present there, and OtherId the id in the second.
+type EntityDiff struct {
+	EntityType string        `json:"entityType"`
+	Kind       string        `json:"kind"`
+	Id         string        `json:"id,omitempty"`
+	OtherId    string        `json:"otherId,omitempty"`
+	Changes    []FieldChange `json:"changes,omitempty"`
+}
+
+type DiffReport struct {
+	Diffs []EntityDiff
+}
+
+func (report *DiffReport) Count(kind string) int {
+	n := 0
+	for _, d := range report.Diffs {
+		if d.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+func (report *DiffReport) String() string {
+	var sb strings.Builder
+	for _, d := range report.Diffs {
+		switch d.Kind {
+		case DiffAdded:
+			fmt.Fprintf(&sb, "+ %s %s\n", d.EntityType, d.OtherId)
+		case DiffRemoved:
+			fmt.Fprintf(&sb, "- %s %s\n", d.EntityType, d.Id)
+		default:
+			if d.Id == d.OtherId {
+				fmt.Fprintf(&sb, "~ %s %s\n", d.EntityType, d.Id)
+			} else {
+				fmt.Fprintf(&sb, "~ %s %s (%s)\n", d.EntityType, d.Id, d.OtherId)
+			}
+			for _, c := range d.Changes {
+				fmt.Fprintf(&sb, "    %s\n", c)
+			}
+		}
+	}
+	fmt.Fprintf(&sb, "%d added, %d removed, %d changed\n", report.Count(DiffAdded), report.Count(DiffRemoved), report.Count(DiffChanged))
+	return sb.String()
+}
+
+// Loads a site from two environments, with its work orders and the inspection event resources of its resources, and
+// compares them.
+func DiffSites(a *Environment, b *Environment, siteId string, options *DiffOptions) (*DiffReport, error) {
+	if options == nil {
+		options = &DiffOptions{}
+	}
+	otherSiteId := siteId
+	if options.Ids != nil {
+		if id, ok := options.Ids.Get(EntityTypeSite, siteId); ok {
+			otherSiteId = id
+		}
+	}
+	treeOptions := SiteTreeOptions{Depth: SiteTreeDepthAll, OrderNumber: options.OrderNumber, Parallelism: options.Parallelism}
+	var snapshotA, snapshotB *siteSnapshot
+	load := func(env *Environment, siteId string, snapshot **siteSnapshot) func() error {
+		return func() error {
+			tree, err := LoadSiteTree(env, siteId, &treeOptions)
+			if err != nil {
+				return err
+			}
+			*snapshot = snapshotOf(tree)
+			return (*snapshot).loadRelated(environmentSource{env: env}, siteId, options.OrderNumber)
+		}
+	}
+	g := newWorkGroup(2)
+	g.Go(load(a, siteId, &snapshotA))
+	g.Go(load(b, otherSiteId, &snapshotB))
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return diffSnapshots(snapshotA, snapshotB, options)
+}
+
+// Compares two trees entity by entity.  Diffs are ordered by entity type, from the site down to resources, then by
+// id.  Work orders and inspection event resources are not held in a tree, so only DiffSites compares them.
+func DiffSiteTrees(a *SiteTree, b *SiteTree, options *DiffOptions) (*DiffReport, error) {
+	if options == nil {
+		options = &DiffOptions{}
+	}
+	return diffSnapshots(snapshotOf(a), snapshotOf(b), options)
+}
+
+// The order in which entity types are compared and reported.
+var diffEntityTypes = []string{
+	EntityTypeSite,
+	EntityTypeWorkOrder,
+	EntityTypeAsset,
+	EntityTypeComponent,
+	EntityTypeAssetInspection,
+	EntityTypeComponentInspection,
+	EntityTypeResource,
+	EntityTypeInspectionEventResource,
+}
+
+func diffSnapshots(a *siteSnapshot, b *siteSnapshot, options *DiffOptions) (*DiffReport, error) {
+	entitiesA, entitiesB := snapshotEntities(a), snapshotEntities(b)
+	report := &DiffReport{Diffs: make([]EntityDiff, 0)}
+	for _, entityType := range diffEntityTypes {
+		ignore := options.ignored(entityType)
+		inA, inB := entitiesA[entityType], entitiesB[entityType]
+		matched := make(map[string]bool)
+		for _, id := range sortedKeys(inA) {
+			otherId := id
+			if options.Ids != nil {
+				if mapped, ok := options.Ids.Get(entityType, id); ok {
+					otherId = mapped
+				}
+			}
+			other, ok := inB[otherId]
+			if !ok {
+				report.Diffs = append(report.Diffs, EntityDiff{EntityType: entityType, Kind: DiffRemoved, Id: id})
+				continue
+			}
+			matched[otherId] = true
+			translated, err := options.translate(entityType, inA[id])
+			if err != nil {
+				return nil, err
+			}
+			changes, err := CompareFields(translated, other, ignore)
+			if err != nil {
+				return nil, err
+			}
+			if len(changes) > 0 {
+				report.Diffs = append(report.Diffs, EntityDiff{EntityType: entityType, Kind: DiffChanged, Id: id, OtherId: otherId, Changes: changes})
+			}
+		}
+		for _, id := range sortedKeys(inB) {
+			if !matched[id] {
+				report.Diffs = append(report.Diffs, EntityDiff{EntityType: entityType, Kind: DiffAdded, OtherId: id})
+			}
+		}
+	}
+	return report, nil
+}
+
+func (options *DiffOptions) ignored(entityType string) []string {
+	ignore := make([]string, 0)
+	if !options.NoDefaultIgnore {
+		ignore = append(ignore, DefaultDiffIgnore[""]...)
+		ignore = append(ignore, DefaultDiffIgnore[entityType]...)
+	}
+	ignore = append(ignore, options.Ignore[""]...)
+	return append(ignore, options.Ignore[entityType]...)
+}
+
+// The JSON fields referring to other entities.
+var diffReferenceFields = map[string]string{
+	"siteId":                EntityTypeSite,
+	"assetId":               EntityTypeAsset,
+	"componentId":           EntityTypeComponent,
+	"assetInspectionId":     EntityTypeAssetInspection,
+	"componentInspectionId": EntityTypeComponentInspection,
+	"resourceId":            EntityTypeResource,
+	"sourceResourceId":      EntityTypeResource,
+	"plateImageResourceId":  EntityTypeResource,
+}
+
+// The JSON field holding each entity type's id, where it is not "id".
+var diffIdFields = map[string]string{
+	EntityTypeResource:  "resourceId",
+	EntityTypeWorkOrder: "orderNumber",
+}
+
+// Rewrites the ids in an entity from the first environment to those of the second, so that only real differences
+// are reported.
+func (options *DiffOptions) translate(entityType string, entity interface{}) (interface{}, error) {
+	if options.Ids == nil {
+		return entity, nil
+	}
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	mapId := func(t string, v interface{}) interface{} {
+		if id, ok := v.(string); ok {
+			if mapped, ok := options.Ids.Get(t, id); ok {
+				return mapped
+			}
+		}
+		return v
+	}
+	idField, ok := diffIdFields[entityType]
+	if !ok {
+		idField = "id"
+	}
+	for key, value := range fields {
+		if key == idField {
+			fields[key] = mapId(entityType, value)
+		} else if t, ok := diffReferenceFields[key]; ok {
+			fields[key] = mapId(t, value)
+		} else if key == "resources" {
+			if list, ok := value.([]interface{}); ok {
+				for i := range list {
+					list[i] = mapId(EntityTypeResource, list[i])
+				}
+			}
+		}
+	}
+	return fields, nil
+}
+
+func snapshotEntities(s *siteSnapshot) map[string]map[string]interface{} {
+	entities := make(map[string]map[string]interface{})
+	for _, entityType := range diffEntityTypes {
+		entities[entityType] = make(map[string]interface{})
+	}
+	put := func(entityType string, id *string, entity interface{}) {
+		if id != nil {
+			entities[entityType][*id] = entity
+		}
+	}
+	if s.site != nil {
+		put(EntityTypeSite, s.site.Id, s.site)
+	}
+	for i := range s.workOrders {
+		put(EntityTypeWorkOrder, s.workOrders[i].OrderNumber, &s.workOrders[i])
+	}
+	for i := range s.assets {
+		put(EntityTypeAsset, s.assets[i].Id, &s.assets[i])
+	}
+	for i := range s.components {
+		put(EntityTypeComponent, s.components[i].Id, &s.components[i])
+	}
+	for i := range s.assetInspections {
+		put(EntityTypeAssetInspection, s.assetInspections[i].Id, &s.assetInspections[i])
+	}
+	for i := range s.componentInspections {
+		put(EntityTypeComponentInspection, s.componentInspections[i].Id, &s.componentInspections[i])
+	}
+	for i := range s.resources {
+		put(EntityTypeResource, s.resources[i].ResourceId, &s.resources[i])
+	}
+	for i := range s.inspectionEventResources {
+		put(EntityTypeInspectionEventResource, s.inspectionEventResources[i].Id, &s.inspectionEventResources[i])
+	}
+	return entities
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
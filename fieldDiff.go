@@ -0,0 +1,75 @@
+package gowindams
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+)
+
+// A difference in a single field between two versions of an entity.  Field is the JSON name, with nested fields
+// joined by dots as for FormatOptions.Columns.
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, cellText(c.Old), cellText(c.New))
+}
+
+// Compares the JSON form of two entities field by field.  Fields matching one of the ignore patterns, which use
+// path.Match syntax against the dotted field name (such as "downloadURL" or "attributes.*"), are skipped.  Changes are
+// sorted by field name.
+func CompareFields(old interface{}, new interface{}, ignore []string) ([]FieldChange, error) {
+	oldFields, err := flattenedFields(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := flattenedFields(new)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]FieldChange, 0)
+	for field, ov := range oldFields {
+		nv := newFields[field]
+		if !ignoredField(field, ignore) && !reflect.DeepEqual(plainValue(ov), plainValue(nv)) {
+			changes = append(changes, FieldChange{Field: field, Old: plainValue(ov), New: plainValue(nv)})
+		}
+	}
+	for field, nv := range newFields {
+		if _, ok := oldFields[field]; !ok && nv != nil && !ignoredField(field, ignore) {
+			changes = append(changes, FieldChange{Field: field, New: plainValue(nv)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes, nil
+}
+
+func flattenedFields(v interface{}) (map[string]interface{}, error) {
+	rows, _, err := formatRows(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) != 1 {
+		return nil, fmt.Errorf("Expected a single entity to compare, got %d", len(rows))
+	}
+	fields := rows[0].fields
+	// An absent entity flattens to a single null field.
+	if len(fields) == 1 && fields[""] == nil {
+		return map[string]interface{}{}, nil
+	}
+	return fields, nil
+}
+
+func ignoredField(field string, ignore []string) bool {
+	for _, pattern := range ignore {
+		if matched, _ := path.Match(pattern, field); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,430 @@
+package gowindams
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+const FormatCSV = "csv"
+const FormatJSON = "json"
+const FormatNDJSON = "ndjson"
+const FormatTable = "table"
+const FormatTemplate = "template"
+const FormatYAML = "yaml"
+
+var Formats = []string{FormatTable, FormatJSON, FormatNDJSON, FormatYAML, FormatCSV, FormatTemplate}
+
+type FormatOptions struct {
+	// One of the Format* constants, defaulting to FormatJSON.
+	Format string
+	// Columns to include, named by their JSON field names with nested fields joined by dots, such as
+	// "location.latitude" or "attributes.hubHeight".  By default the table and CSV formats include every field which
+	// has a value in any of the rows, and the other formats include everything.
+	Columns []string
+	// A text/template executed for each row.  Rows are passed as maps keyed by JSON field name, so fields are
+	// referred to as {{.name}} or {{.location.latitude}}.
+	Template string
+	NoHeader bool
+}
+
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Renders an entity, a pointer to one, or a slice of them.  Anything which can be marshaled to JSON may be given; a
+// slice is rendered as one row per element.
+func WriteFormatted(w io.Writer, v interface{}, options *FormatOptions) error {
+	if options == nil {
+		options = &FormatOptions{}
+	}
+	format := options.Format
+	if format == "" {
+		format = FormatJSON
+	}
+	if format == FormatJSON && len(options.Columns) == 0 {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	rows, list, err := formatRows(v)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case FormatJSON, FormatNDJSON, FormatYAML:
+		values := make([]interface{}, len(rows))
+		for i, row := range rows {
+			if len(options.Columns) > 0 {
+				values[i] = row.project(options.Columns)
+			} else {
+				values[i] = row.value
+			}
+		}
+		return writeStructured(w, format, values, list)
+	case FormatTable, FormatCSV:
+		columns := options.Columns
+		if len(columns) == 0 {
+			columns = defaultColumns(rows)
+		}
+		if format == FormatCSV {
+			return writeCSV(w, rows, columns, !options.NoHeader)
+		}
+		return writeTable(w, rows, columns, !options.NoHeader)
+	case FormatTemplate:
+		return writeTemplate(w, rows, options.Template)
+	default:
+		return fmt.Errorf("Unknown output format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// A row holds an element both as an ordered tree, for the structured formats, and flattened to dotted keys.
+type formatRow struct {
+	value  interface{}
+	keys   []string
+	fields map[string]interface{}
+}
+
+func (row formatRow) project(columns []string) yaml.MapSlice {
+	projected := make(yaml.MapSlice, len(columns))
+	for i, c := range columns {
+		projected[i] = yaml.MapItem{Key: c, Value: row.fields[c]}
+	}
+	return projected
+}
+
+func formatRows(v interface{}) ([]formatRow, bool, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() && (rv.Elem().Kind() == reflect.Slice || rv.Elem().Kind() == reflect.Array) {
+		rv = rv.Elem()
+	}
+	items := []interface{}{v}
+	list := false
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		list = true
+		items = make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = rv.Index(i).Interface()
+		}
+	}
+	rows := make([]formatRow, len(items))
+	for i, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, list, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		value, err := decodeOrdered(dec)
+		if err != nil {
+			return nil, list, err
+		}
+		row := formatRow{value: value, fields: make(map[string]interface{})}
+		row.flatten("", value)
+		rows[i] = row
+	}
+	return rows, list, nil
+}
+
+// Decodes the next JSON value, keeping the order of object keys by decoding objects as yaml.MapSlice.
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		obj := make(yaml.MapSlice, 0)
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, yaml.MapItem{Key: key, Value: value})
+		}
+		_, err = dec.Token()
+		return obj, err
+	case '[':
+		arr := make([]interface{}, 0)
+		for dec.More() {
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err = dec.Token()
+		return arr, err
+	}
+	return nil, fmt.Errorf("Unexpected JSON delimiter %s", delim)
+}
+
+func (row *formatRow) flatten(prefix string, value interface{}) {
+	if obj, ok := value.(yaml.MapSlice); ok && (prefix == "" || len(obj) > 0) {
+		for _, item := range obj {
+			key := fmt.Sprint(item.Key)
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			row.flatten(key, item.Value)
+		}
+		return
+	}
+	row.keys = append(row.keys, prefix)
+	row.fields[prefix] = value
+}
+
+// Every flattened field with a value in at least one row, in the order first seen.
+func defaultColumns(rows []formatRow) []string {
+	columns := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		for _, k := range row.keys {
+			if !seen[k] && row.fields[k] != nil {
+				seen[k] = true
+				columns = append(columns, k)
+			}
+		}
+	}
+	return columns
+}
+
+func cellText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(plainValue(v))
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+// Converts the ordered tree back to plain maps, which is what JSON encoding and templates expect.
+func plainValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		m := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			m[fmt.Sprint(item.Key)] = plainValue(item.Value)
+		}
+		return m
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i := range v {
+			arr[i] = plainValue(v[i])
+		}
+		return arr
+	default:
+		return v
+	}
+}
+
+func writeStructured(w io.Writer, format string, values []interface{}, list bool) error {
+	switch format {
+	case FormatNDJSON:
+		for _, v := range values {
+			data, err := json.Marshal(orderedJSON{v})
+			if err != nil {
+				return err
+			}
+			if _, err = fmt.Fprintf(w, "%s\n", data); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatYAML:
+		var v interface{} = values
+		if !list && len(values) == 1 {
+			v = values[0]
+		}
+		data, err := yaml.Marshal(yamlValue(v))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	default:
+		var v interface{} = values
+		if !list && len(values) == 1 {
+			v = values[0]
+		}
+		data, err := json.MarshalIndent(orderedJSON{v}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+}
+
+// Marshals an ordered tree to JSON, keeping the order of object keys.
+type orderedJSON struct {
+	value interface{}
+}
+
+func (o orderedJSON) MarshalJSON() ([]byte, error) {
+	switch v := o.value.(type) {
+	case yaml.MapSlice:
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(fmt.Sprint(item.Key))
+			if err != nil {
+				return nil, err
+			}
+			value, err := json.Marshal(orderedJSON{item.Value})
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		return buf.Bytes(), nil
+	case []interface{}:
+		arr := make([]orderedJSON, len(v))
+		for i := range v {
+			arr[i] = orderedJSON{v[i]}
+		}
+		return json.Marshal(arr)
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// Numbers are kept as json.Number while decoding, which YAML would otherwise write as quoted strings.
+func yamlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		converted := make(yaml.MapSlice, len(v))
+		for i, item := range v {
+			converted[i] = yaml.MapItem{Key: item.Key, Value: yamlValue(item.Value)}
+		}
+		return converted
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i := range v {
+			arr[i] = yamlValue(v[i])
+		}
+		return arr
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	default:
+		return v
+	}
+}
+
+func writeTable(w io.Writer, rows []formatRow, columns []string, header bool) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if header {
+		headers := make([]string, len(columns))
+		for i, c := range columns {
+			headers[i] = strings.ToUpper(c)
+		}
+		fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	}
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			// Tabs and newlines would break the alignment.
+			cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(cellText(row.fields[c]))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, rows []formatRow, columns []string, header bool) error {
+	cw := csv.NewWriter(w)
+	if header {
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, c := range columns {
+			record[i] = cellText(row.fields[c])
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeTemplate(w io.Writer, rows []formatRow, text string) error {
+	if text == "" {
+		return fmt.Errorf("A template is required for the %s format", FormatTemplate)
+	}
+	tmpl, err := template.New("row").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"join": func(sep string, v []interface{}) string {
+			parts := make([]string, len(v))
+			for i := range v {
+				parts[i] = cellText(v[i])
+			}
+			return strings.Join(parts, sep)
+		},
+	}).Parse(text)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		var buf bytes.Buffer
+		if err = tmpl.Execute(&buf, plainValue(row.value)); err != nil {
+			return err
+		}
+		if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+		if _, err = w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package gowindams
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const EarthRadiusMeters = 6371008.8
+
+const GeoJSONTypePoint = "Point"
+const GeoJSONTypePolygon = "Polygon"
+
+var ErrMissingCoordinates = errors.New("GeoPoint is missing its latitude or longitude")
+
+type GeoJSONPoint struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type GeoJSONPolygon struct {
+	Type        string        `json:"type"`
+	Coordinates [][][]float64 `json:"coordinates"`
+}
+
+type GeoBoundingBox struct {
+	MinLatitude  float64 `json:"minLatitude"`
+	MinLongitude float64 `json:"minLongitude"`
+	MaxLatitude  float64 `json:"maxLatitude"`
+	MaxLongitude float64 `json:"maxLongitude"`
+}
+
+func NewGeoPoint(latitude float64, longitude float64) GeoPoint {
+	return GeoPoint{
+		Latitude:  &latitude,
+		Longitude: &longitude,
+	}
+}
+
+func (p GeoPoint) HasCoordinates() bool {
+	return p.Latitude != nil && p.Longitude != nil
+}
+
+func (p GeoPoint) String() string {
+	if !p.HasCoordinates() {
+		return "(unknown)"
+	}
+	return fmt.Sprintf("(%f, %f)", *p.Latitude, *p.Longitude)
+}
+
+// Great circle distance, in meters, between two points using the haversine formula.
+func (p GeoPoint) DistanceTo(q GeoPoint) (float64, error) {
+	if !p.HasCoordinates() || !q.HasCoordinates() {
+		return 0, ErrMissingCoordinates
+	}
+	lat1 := toRadians(*p.Latitude)
+	lat2 := toRadians(*q.Latitude)
+	dLat := lat2 - lat1
+	dLng := toRadians(*q.Longitude - *p.Longitude)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a))), nil
+}
+
+// Initial bearing, in degrees clockwise from true north in the range [0, 360), to travel from p to q.
+func (p GeoPoint) BearingTo(q GeoPoint) (float64, error) {
+	if !p.HasCoordinates() || !q.HasCoordinates() {
+		return 0, ErrMissingCoordinates
+	}
+	lat1 := toRadians(*p.Latitude)
+	lat2 := toRadians(*q.Latitude)
+	dLng := toRadians(*q.Longitude - *p.Longitude)
+	y := math.Sin(dLng) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	return math.Mod(toDegrees(math.Atan2(y, x))+360, 360), nil
+}
+
+func (p GeoPoint) ToGeoJSON() (GeoJSONPoint, error) {
+	if !p.HasCoordinates() {
+		return GeoJSONPoint{}, ErrMissingCoordinates
+	}
+	return GeoJSONPoint{
+		Type:        GeoJSONTypePoint,
+		Coordinates: p.position(),
+	}, nil
+}
+
+// GeoJSON positions are longitude first, followed by latitude and optionally altitude.
+func (p GeoPoint) position() []float64 {
+	if p.Altitude != nil {
+		return []float64{*p.Longitude, *p.Latitude, *p.Altitude}
+	}
+	return []float64{*p.Longitude, *p.Latitude}
+}
+
+func geoPointFromPosition(position []float64) (GeoPoint, error) {
+	if len(position) < 2 {
+		return GeoPoint{}, fmt.Errorf("A GeoJSON position requires at least 2 values, got %d", len(position))
+	}
+	p := NewGeoPoint(position[1], position[0])
+	if len(position) > 2 {
+		altitude := position[2]
+		p.Altitude = &altitude
+	}
+	return p, nil
+}
+
+func GeoPointFromGeoJSON(point GeoJSONPoint) (GeoPoint, error) {
+	if point.Type != GeoJSONTypePoint {
+		return GeoPoint{}, fmt.Errorf("Expected a GeoJSON %s, got \"%s\"", GeoJSONTypePoint, point.Type)
+	}
+	return geoPointFromPosition(point.Coordinates)
+}
+
+// Converts a polygon outline into a GeoJSON Polygon with a single, closed, exterior ring.
+func PolygonToGeoJSON(outline []GeoPoint) (GeoJSONPolygon, error) {
+	if len(outline) < 3 {
+		return GeoJSONPolygon{}, fmt.Errorf("A polygon requires at least 3 points, got %d", len(outline))
+	}
+	ring := make([][]float64, 0, len(outline)+1)
+	for _, p := range outline {
+		if !p.HasCoordinates() {
+			return GeoJSONPolygon{}, ErrMissingCoordinates
+		}
+		ring = append(ring, p.position())
+	}
+	first := outline[0]
+	last := outline[len(outline)-1]
+	if *first.Latitude != *last.Latitude || *first.Longitude != *last.Longitude {
+		ring = append(ring, first.position())
+	}
+	return GeoJSONPolygon{
+		Type:        GeoJSONTypePolygon,
+		Coordinates: [][][]float64{ring},
+	}, nil
+}
+
+// Converts the exterior ring of a GeoJSON Polygon into a polygon outline.  The closing point is dropped and any
+// interior rings (holes) are ignored.
+func PolygonFromGeoJSON(polygon GeoJSONPolygon) ([]GeoPoint, error) {
+	if polygon.Type != GeoJSONTypePolygon {
+		return nil, fmt.Errorf("Expected a GeoJSON %s, got \"%s\"", GeoJSONTypePolygon, polygon.Type)
+	}
+	if len(polygon.Coordinates) == 0 {
+		return nil, errors.New("The GeoJSON Polygon has no rings")
+	}
+	ring := polygon.Coordinates[0]
+	if len(ring) > 1 && positionsEqual(ring[0], ring[len(ring)-1]) {
+		ring = ring[:len(ring)-1]
+	}
+	outline := make([]GeoPoint, 0, len(ring))
+	for _, position := range ring {
+		p, err := geoPointFromPosition(position)
+		if err != nil {
+			return nil, err
+		}
+		outline = append(outline, p)
+	}
+	return outline, nil
+}
+
+// Determines whether the point lies inside the polygon.  Like the polygon's geometry, the point is in the image's pixels,
+// with x as its Longitude and y as its Latitude.
+func (poly InspectionEventPolygon) Contains(p GeoPoint) bool {
+	return PointInPolygon(p, poly.Geometry)
+}
+
+// Determines whether the point lies inside the polygon outline using ray casting, with longitude as x and latitude as y.
+// The coordinates are treated as planar, so the outline may equally be in pixels.  Points without coordinates are
+// ignored.
+func PointInPolygon(p GeoPoint, outline []GeoPoint) bool {
+	if !p.HasCoordinates() {
+		return false
+	}
+	x := *p.Longitude
+	y := *p.Latitude
+	inside := false
+	for i, j := 0, len(outline)-1; i < len(outline); j, i = i, i+1 {
+		a := outline[i]
+		b := outline[j]
+		if !a.HasCoordinates() || !b.HasCoordinates() {
+			continue
+		}
+		ax, ay := *a.Longitude, *a.Latitude
+		bx, by := *b.Longitude, *b.Latitude
+		if (ay > y) != (by > y) && x < (bx-ax)*(y-ay)/(by-ay)+ax {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Computes the smallest bounding box containing all of the points which have coordinates.
+func BoundingBoxOf(points []GeoPoint) (GeoBoundingBox, error) {
+	var box GeoBoundingBox
+	found := false
+	for _, p := range points {
+		if !p.HasCoordinates() {
+			continue
+		}
+		if !found {
+			box = GeoBoundingBox{
+				MinLatitude:  *p.Latitude,
+				MinLongitude: *p.Longitude,
+				MaxLatitude:  *p.Latitude,
+				MaxLongitude: *p.Longitude,
+			}
+			found = true
+		} else {
+			box = box.Extend(p)
+		}
+	}
+	if !found {
+		return box, ErrMissingCoordinates
+	}
+	return box, nil
+}
+
+// Computes a bounding box containing every point within radius meters of the center.  The box does not wrap around the
+// antimeridian: its longitudes are clamped to [-180, 180], so near ±180 it covers only the side of the center's own
+// hemisphere.
+func BoundingBoxAround(center GeoPoint, radius float64) (GeoBoundingBox, error) {
+	if !center.HasCoordinates() {
+		return GeoBoundingBox{}, ErrMissingCoordinates
+	}
+	dLat := toDegrees(radius / EarthRadiusMeters)
+	dLng := 180.0
+	if cos := math.Cos(toRadians(*center.Latitude)); cos > 0 {
+		dLng = math.Min(180, dLat/cos)
+	}
+	return GeoBoundingBox{
+		MinLatitude:  math.Max(-90, *center.Latitude-dLat),
+		MinLongitude: math.Max(-180, *center.Longitude-dLng),
+		MaxLatitude:  math.Min(90, *center.Latitude+dLat),
+		MaxLongitude: math.Min(180, *center.Longitude+dLng),
+	}, nil
+}
+
+func (box GeoBoundingBox) Contains(p GeoPoint) bool {
+	return p.HasCoordinates() &&
+		*p.Latitude >= box.MinLatitude && *p.Latitude <= box.MaxLatitude &&
+		*p.Longitude >= box.MinLongitude && *p.Longitude <= box.MaxLongitude
+}
+
+func (box GeoBoundingBox) Extend(p GeoPoint) GeoBoundingBox {
+	if p.HasCoordinates() {
+		box.MinLatitude = math.Min(box.MinLatitude, *p.Latitude)
+		box.MinLongitude = math.Min(box.MinLongitude, *p.Longitude)
+		box.MaxLatitude = math.Max(box.MaxLatitude, *p.Latitude)
+		box.MaxLongitude = math.Max(box.MaxLongitude, *p.Longitude)
+	}
+	return box
+}
+
+func (box GeoBoundingBox) Center() GeoPoint {
+	return NewGeoPoint((box.MinLatitude+box.MaxLatitude)/2, (box.MinLongitude+box.MaxLongitude)/2)
+}
+
+// The bounding box as a GeoJSON bbox array: [west, south, east, north].
+func (box GeoBoundingBox) BBox() []float64 {
+	return []float64{box.MinLongitude, box.MinLatitude, box.MaxLongitude, box.MaxLatitude}
+}
+
+func positionsEqual(a []float64, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func toDegrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
@@ -0,0 +1,282 @@
+package gowindams
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+)
+
+const GeoJSONTypeFeature = "Feature"
+const GeoJSONTypeFeatureCollection = "FeatureCollection"
+
+const GeoJSONKindAsset = "asset"
+const GeoJSONKindInspectionEvent = "inspectionEvent"
+const GeoJSONKindResource = "resource"
+
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Id         string                 `json:"id,omitempty"`
+	Geometry   interface{}            `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type GeoJSONExportOptions struct {
+	IncludeResources        bool
+	IncludeInspectionEvents bool
+	OrderNumber             *string
+}
+
+// Writes a GeoJSON FeatureCollection to the underlying writer one feature at a time so that large sites need not be
+// held in memory.  Close must be called to terminate the collection.
+type GeoJSONWriter struct {
+	w       io.Writer
+	count   int
+	started bool
+	closed  bool
+}
+
+func NewGeoJSONWriter(w io.Writer) *GeoJSONWriter {
+	return &GeoJSONWriter{w: w}
+}
+
+func (gw *GeoJSONWriter) Count() int {
+	return gw.count
+}
+
+func (gw *GeoJSONWriter) WriteFeature(feature *GeoJSONFeature) error {
+	if gw.closed {
+		return errors.New("The GeoJSON feature collection has already been closed")
+	}
+	if err := gw.start(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(feature)
+	if err != nil {
+		return err
+	}
+	if gw.count > 0 {
+		if _, err = io.WriteString(gw.w, ","); err != nil {
+			return err
+		}
+	}
+	if _, err = gw.w.Write(data); err != nil {
+		return err
+	}
+	gw.count++
+	return nil
+}
+
+func (gw *GeoJSONWriter) Close() error {
+	if gw.closed {
+		return nil
+	}
+	if err := gw.start(); err != nil {
+		return err
+	}
+	gw.closed = true
+	_, err := io.WriteString(gw.w, "]}\n")
+	return err
+}
+
+func (gw *GeoJSONWriter) start() error {
+	if gw.started {
+		return nil
+	}
+	gw.started = true
+	_, err := fmt.Fprintf(gw.w, "{\"type\":\"%s\",\"features\":[", GeoJSONTypeFeatureCollection)
+	return err
+}
+
+func AssetFeature(asset *Asset) (*GeoJSONFeature, error) {
+	if asset.Location == nil {
+		return nil, ErrMissingCoordinates
+	}
+	point, err := asset.Location.ToGeoJSON()
+	if err != nil {
+		return nil, err
+	}
+	props := map[string]interface{}{
+		"kind": GeoJSONKindAsset,
+	}
+	putAssetProperties(props, asset)
+	putStringProperty(props, "serialNumber", asset.SerialNumber)
+	putStringProperty(props, "siteId", asset.SiteId)
+	putStringProperty(props, "type", asset.Type)
+	for k, v := range asset.Attributes {
+		props["attr."+k] = v
+	}
+	return &GeoJSONFeature{
+		Type:       GeoJSONTypeFeature,
+		Id:         stringValue(asset.Id),
+		Geometry:   point,
+		Properties: props,
+	}, nil
+}
+
+func ResourceFeature(rmeta *ResourceMetadata, asset *Asset) (*GeoJSONFeature, error) {
+	if rmeta.Location == nil {
+		return nil, ErrMissingCoordinates
+	}
+	point, err := rmeta.Location.ToGeoJSON()
+	if err != nil {
+		return nil, err
+	}
+	props := map[string]interface{}{
+		"kind": GeoJSONKindResource,
+	}
+	putAssetProperties(props, asset)
+	putStringProperty(props, "resourceId", rmeta.ResourceId)
+	putStringProperty(props, "assetId", rmeta.AssetId)
+	putStringProperty(props, "componentId", rmeta.ComponentId)
+	putStringProperty(props, "contentType", rmeta.ContentType)
+	putStringProperty(props, "name", rmeta.Name)
+	putStringProperty(props, "orderNumber", rmeta.OrderNumber)
+	putStringProperty(props, "status", rmeta.Status)
+	if rmeta.Timestamp != nil {
+		props["timestamp"] = rmeta.Timestamp.String()
+	}
+	if rmeta.Position != nil {
+		putStringProperty(props, "side", rmeta.Position.Side)
+	}
+	return &GeoJSONFeature{
+		Type:       GeoJSONTypeFeature,
+		Id:         stringValue(rmeta.ResourceId),
+		Geometry:   point,
+		Properties: props,
+	}, nil
+}
+
+// Builds a feature for a single polygon of an inspection event resource.  The polygon marks a region of the resource's
+// image rather than of the map, so the feature is a point at the image's location, or at the asset's when the image
+// has none.
+func InspectionEventPolygonFeature(ier *InspectionEventResource, poly *InspectionEventPolygon, rmeta *ResourceMetadata, asset *Asset) (*GeoJSONFeature, error) {
+	var location *GeoPoint
+	if rmeta != nil && rmeta.Location != nil {
+		location = rmeta.Location
+	} else if asset != nil {
+		location = asset.Location
+	}
+	if location == nil {
+		return nil, ErrMissingCoordinates
+	}
+	geometry, err := location.ToGeoJSON()
+	if err != nil {
+		return nil, err
+	}
+	props := map[string]interface{}{
+		"kind": GeoJSONKindInspectionEvent,
+	}
+	putAssetProperties(props, asset)
+	putStringProperty(props, "inspectionEventResourceId", ier.Id)
+	putStringProperty(props, "inspectionEventId", ier.InspectionEventId)
+	putStringProperty(props, "resourceId", ier.ResourceId)
+	putStringProperty(props, "assetId", ier.AssetId)
+	putStringProperty(props, "orderNumber", ier.OrderNumber)
+	putStringProperty(props, "name", poly.Name)
+	putStringProperty(props, "text", poly.Text)
+	if poly.Severity != nil {
+		props["severity"] = *poly.Severity
+	}
+	return &GeoJSONFeature{
+		Type:       GeoJSONTypeFeature,
+		Id:         stringValue(poly.Id),
+		Geometry:   geometry,
+		Properties: props,
+	}, nil
+}
+
+// Streams the assets of a site, and optionally their images and inspection event polygons, to the writer as a GeoJSON
+// FeatureCollection.  Entities without a location are skipped.
+func ExportSiteGeoJSON(env *Environment, siteId string, w io.Writer, options *GeoJSONExportOptions) error {
+	if options == nil {
+		options = &GeoJSONExportOptions{}
+	}
+	gw := NewGeoJSONWriter(w)
+	assets, err := env.AssetServiceClient().Search(&AssetSearchCriteria{SiteId: &siteId})
+	if err != nil {
+		return err
+	}
+	for i := range assets {
+		asset := &assets[i]
+		if feature, err := AssetFeature(asset); err == nil {
+			if err = gw.WriteFeature(feature); err != nil {
+				return err
+			}
+		} else {
+			log.Printf("GOWINDAMS: Skipping asset %s in the GeoJSON export: %s", stringValue(asset.Id), err)
+		}
+		if (options.IncludeResources || options.IncludeInspectionEvents) && asset.Id != nil {
+			err = exportAssetResourcesGeoJSON(env, gw, asset, options)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return gw.Close()
+}
+
+func exportAssetResourcesGeoJSON(env *Environment, gw *GeoJSONWriter, asset *Asset, options *GeoJSONExportOptions) error {
+	criteria := ResourceSearchCriteria{
+		AssetId:     asset.Id,
+		OrderNumber: options.OrderNumber,
+	}
+	resources := env.ResourceServiceClient().Iterate(context.Background(), &criteria, 0)
+	defer resources.Close()
+	for resources.Next() {
+		rmeta := resources.Value()
+		if options.IncludeResources {
+			if feature, err := ResourceFeature(rmeta, asset); err == nil {
+				if err = gw.WriteFeature(feature); err != nil {
+					return err
+				}
+			}
+		}
+		if options.IncludeInspectionEvents && rmeta.ResourceId != nil {
+			iers, err := env.InspectionEventResourceServiceClient().Search(&InspectionEventResourceSearchCriteria{ResourceId: rmeta.ResourceId})
+			if err != nil {
+				return err
+			}
+			for j := range iers {
+				ier := &iers[j]
+				if options.OrderNumber != nil && ier.OrderNumber != nil && *ier.OrderNumber != *options.OrderNumber {
+					continue
+				}
+				for k := range ier.Polygons {
+					feature, err := InspectionEventPolygonFeature(ier, &ier.Polygons[k], rmeta, asset)
+					if err != nil {
+						continue
+					}
+					if err = gw.WriteFeature(feature); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return resources.Err()
+}
+
+func putAssetProperties(props map[string]interface{}, asset *Asset) {
+	if asset == nil {
+		return
+	}
+	putStringProperty(props, "assetName", asset.Name)
+	putStringProperty(props, "make", asset.Make)
+	putStringProperty(props, "model", asset.Model)
+}
+
+func putStringProperty(props map[string]interface{}, key string, value *string) {
+	if value != nil {
+		props[key] = *value
+	}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,187 @@
+package gowindams_test
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+// The files of a backup of a site with one inspected component and one image, keyed by path in the archive.
+func siteBackupFiles() map[string]string {
+	return map[string]string{
+		"site.json":                     `{"id":"s1","name":"Prairie Wind"}`,
+		"workOrders.json":               `[{"orderNumber":"WO-1","siteId":"s1"}]`,
+		"assets.json":                   `[{"id":"a1","siteId":"s1","name":"WTG-01"}]`,
+		"components.json":               `[{"id":"c1","assetId":"a1","siteId":"s1"}]`,
+		"assetInspections.json":         `[{"id":"ai1","assetId":"a1","siteId":"s1","orderNumber":"WO-1"}]`,
+		"componentInspections.json":     `[{"id":"ci1","componentId":"c1","assetInspectionId":"ai1"}]`,
+		"resources.json":                `[{"resourceId":"r1","assetId":"a1","componentInspectionId":"ci1"}]`,
+		"inspectionEventResources.json": `[{"id":"ier1","resourceId":"r1"},{"id":"ier2","resourceId":"r2"}]`,
+		"binaries/r1":                   "image bytes",
+	}
+}
+
+// Writes an archive in the backup layout holding the files, with a manifest listing their checksums and counting the
+// binaries.  The file named by damage, if any, is altered after its checksum is taken.
+func writeBackup(testing *testing.T, dir string, files map[string]string, damage string) string {
+	manifest := gowindams.BackupManifest{FormatVersion: gowindams.BackupFormatVersion, SiteId: "s1", Environment: "Production", Counts: map[string]int{}}
+	path := filepath.Join(dir, "backup.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		if strings.HasPrefix(name, "binaries/") {
+			manifest.Counts["ResourceBinary"]++
+		}
+		sum := sha256.Sum256([]byte(content))
+		manifest.Files = append(manifest.Files, gowindams.BackupFile{Path: name, Size: int64(len(content)), SHA256: hex.EncodeToString(sum[:])})
+		if name == damage {
+			content = strings.ToUpper(content)
+		}
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	w, _ := zw.Create("manifest.json")
+	json.NewEncoder(w).Encode(&manifest)
+	zw.Close()
+	f.Close()
+	return path
+}
+
+func TestOpenBackup(testing *testing.T) {
+	dir, _ := ioutil.TempDir("", "backup")
+	defer os.RemoveAll(dir)
+	backup, err := gowindams.OpenBackup(writeBackup(testing, dir, siteBackupFiles(), ""))
+	if err != nil {
+		testing.Fatal(err)
+	}
+	defer backup.Close()
+	if err = backup.Verify(); err != nil {
+		testing.Fatal(err)
+	}
+	tree, err := backup.SiteTree()
+	if err != nil {
+		testing.Fatal(err)
+	}
+	compareStrings(testing, "Prairie Wind", *tree.Site.Name)
+	rn := tree.Resource("r1")
+	if rn == nil || rn.ComponentInspection == nil || *rn.ComponentInspection.ComponentInspection.Id != "ci1" {
+		testing.Fatal("Expected resource r1 to be linked to component inspection ci1")
+	}
+}
+
+func TestVerifyDamagedBackup(testing *testing.T) {
+	dir, _ := ioutil.TempDir("", "backup")
+	defer os.RemoveAll(dir)
+	backup, err := gowindams.OpenBackup(writeBackup(testing, dir, siteBackupFiles(), "binaries/r1"))
+	if err != nil {
+		testing.Fatal(err)
+	}
+	defer backup.Close()
+	err = backup.Verify()
+	if err == nil || !strings.Contains(err.Error(), "binaries/r1") {
+		testing.Fatalf("Expected the damaged binary to be reported, got %v", err)
+	}
+}
+
+// A site in a fake service with one of everything beneath it.
+func newBackupServices(testing *testing.T) (*fakeServices, *gowindams.Environment) {
+	fake, env := newFakeServices(testing, "")
+	fake.put("site", `{"id":"s1","name":"Prairie Wind"}`)
+	fake.put("workOrder", `{"orderNumber":"WO-1","siteId":"s1"}`)
+	fake.put("asset", `{"id":"a1","siteId":"s1","name":"WTG-01"}`)
+	fake.put("component", `{"id":"c1","assetId":"a1","siteId":"s1"}`)
+	fake.put("assetInspection", `{"id":"ai1","assetId":"a1","siteId":"s1","orderNumber":"WO-1"}`)
+	fake.put("componentInspection", `{"id":"ci1","componentId":"c1","assetId":"a1","siteId":"s1","assetInspectionId":"ai1","resources":["r1"]}`)
+	fake.put("resource", `{"resourceId":"r1","siteId":"s1","assetId":"a1","componentId":"c1","assetInspectionId":"ai1","componentInspectionId":"ci1","contentType":"image/png"}`)
+	fake.put("inspectionEventResource", `{"id":"ier1","resourceId":"r1","siteId":"s1"}`)
+	fake.binaries["r1"] = []byte("image bytes")
+	return fake, env
+}
+
+func TestBackupRestoreRoundTrip(testing *testing.T) {
+	_, sourceEnv := newBackupServices(testing)
+	dir, _ := ioutil.TempDir("", "backup")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "backup.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	manifest, err := gowindams.BackupSite(sourceEnv, "s1", f, nil)
+	f.Close()
+	if err != nil {
+		testing.Fatal(err)
+	}
+	for _, entityType := range []string{gowindams.EntityTypeSite, gowindams.EntityTypeWorkOrder, gowindams.EntityTypeAsset, gowindams.EntityTypeComponent,
+		gowindams.EntityTypeAssetInspection, gowindams.EntityTypeComponentInspection, gowindams.EntityTypeResource,
+		gowindams.EntityTypeInspectionEventResource, "ResourceBinary"} {
+		if manifest.Counts[entityType] != 1 {
+			testing.Errorf("Expected one %s to be backed up but got %d", entityType, manifest.Counts[entityType])
+		}
+	}
+	backup, err := gowindams.OpenBackup(path)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	defer backup.Close()
+
+	// Restored with new ids alongside what is already there.
+	target, targetEnv := newFakeServices(testing, "")
+	target.put("site", `{"id":"s1","name":"Existing"}`)
+	ids := gowindams.NewIdMapping()
+	if _, err = gowindams.RestoreSite(targetEnv, backup, ids, nil); err != nil {
+		testing.Fatal(err)
+	}
+	siteId, _ := ids.Get(gowindams.EntityTypeSite, "s1")
+	if siteId == "s1" || len(target.list("site")) != 2 {
+		testing.Errorf("Expected the site to be restored as a new one but got %s", siteId)
+	}
+	resourceId, _ := ids.Get(gowindams.EntityTypeResource, "r1")
+	compareStrings(testing, "image bytes", string(target.binary(resourceId)))
+	compareStrings(testing, siteId, fmt.Sprint(target.list("asset")[0]["siteId"]))
+
+	// Restored with the original ids over a site which already has the asset.
+	restore := func(onConflict string) (*fakeServices, error) {
+		target, targetEnv := newFakeServices(testing, "")
+		target.put("site", `{"id":"s1","name":"Existing"}`)
+		target.put("asset", `{"id":"a1","siteId":"s1","name":"Existing"}`)
+		_, err := gowindams.RestoreSite(targetEnv, backup, nil, &gowindams.CopyOptions{KeepIds: true, OnConflict: onConflict})
+		return target, err
+	}
+	target, err = restore(gowindams.ConflictSkip)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	compareStrings(testing, "Existing", fmt.Sprint(target.get("site", "s1")["name"]))
+	compareStrings(testing, "Existing", fmt.Sprint(target.get("asset", "a1")["name"]))
+	if target.get("component", "c1") == nil || target.get("resource", "r1") == nil || string(target.binary("r1")) != "image bytes" {
+		testing.Errorf("Expected the missing entities to be restored with their ids")
+	}
+
+	target, err = restore(gowindams.ConflictOverwrite)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	compareStrings(testing, "Prairie Wind", fmt.Sprint(target.get("site", "s1")["name"]))
+	compareStrings(testing, "WTG-01", fmt.Sprint(target.get("asset", "a1")["name"]))
+
+	target, err = restore(gowindams.ConflictFail)
+	if !gowindams.IsConflict(err) {
+		testing.Fatalf("Expected a conflict but got %v", err)
+	}
+	if len(target.list("asset")) != 1 || target.get("component", "c1") != nil {
+		testing.Errorf("Expected the restore to stop at the conflict")
+	}
+}
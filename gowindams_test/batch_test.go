@@ -0,0 +1,104 @@
+package gowindams_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func TestRunBatchPartialFailure(testing *testing.T) {
+	results := gowindams.RunBatch(10, &gowindams.BatchOptions{Parallelism: 3}, func(i int) (*string, error) {
+		if i%4 == 1 {
+			return nil, fmt.Errorf("item %d is invalid", i)
+		}
+		id := fmt.Sprintf("id-%d", i)
+		return &id, nil
+	})
+	if len(results) != 10 {
+		testing.Fatalf("Expected 10 results, got %d", len(results))
+	}
+	for i, r := range results {
+		if r.Index != i {
+			testing.Fatalf("Result %d has index %d", i, r.Index)
+		}
+		if i%4 == 1 {
+			if r.Err == nil {
+				testing.Fatalf("Expected item %d to fail", i)
+			}
+		} else {
+			compareStrings(testing, fmt.Sprintf("id-%d", i), *r.Id)
+		}
+	}
+	if len(results.Failed()) != 3 || results.Err() == nil {
+		testing.Fatalf("Expected 3 failures, got %d", len(results.Failed()))
+	}
+}
+
+func TestRunBatchParallelism(testing *testing.T) {
+	var mu sync.Mutex
+	running := 0
+	peak := 0
+	gowindams.RunBatch(20, &gowindams.BatchOptions{Parallelism: 4}, func(i int) (*string, error) {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil, nil
+	})
+	if peak > 4 {
+		testing.Fatalf("Expected at most 4 concurrent items, got %d", peak)
+	}
+}
+
+func TestRunBatchStopOnError(testing *testing.T) {
+	results := gowindams.RunBatch(5, &gowindams.BatchOptions{Parallelism: 1, StopOnError: true}, func(i int) (*string, error) {
+		if i == 1 {
+			return nil, errors.New("failed")
+		}
+		return nil, nil
+	})
+	if results[0].Err != nil || results[1].Err == nil {
+		testing.Fatal("Expected only the second item to fail")
+	}
+	for _, r := range results[2:] {
+		if r.Err != gowindams.ErrBatchAborted {
+			testing.Fatalf("Expected item %d to be skipped, got %v", r.Index, r.Err)
+		}
+	}
+}
+
+func TestRunBatchRateLimit(testing *testing.T) {
+	start := time.Now()
+	gowindams.RunBatch(5, &gowindams.BatchOptions{RequestsPerSecond: 100}, func(i int) (*string, error) {
+		return nil, nil
+	})
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		testing.Fatalf("Expected the rate limit to spread 5 items over at least 40ms, took %s", elapsed)
+	}
+}
+
+func TestRunBatchUnmeasurableRate(testing *testing.T) {
+	results := gowindams.RunBatch(3, &gowindams.BatchOptions{RequestsPerSecond: 2e9}, func(i int) (*string, error) {
+		return nil, nil
+	})
+	if results.Err() != nil {
+		testing.Fatal(results.Err())
+	}
+}
+
+func TestBatchResultsSuccess(testing *testing.T) {
+	results := gowindams.RunBatch(0, nil, func(i int) (*string, error) { return nil, nil })
+	if results.Err() != nil {
+		testing.Fatal("Expected no error for an empty batch")
+	}
+}
@@ -0,0 +1,120 @@
+package gowindams_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func TestValidateComponentInspectionTransition(testing *testing.T) {
+	if err := gowindams.ValidateComponentInspectionTransition("ci1", nil, gowindams.COMP_INSPECTION_STATUS_STARTED); err != nil {
+		testing.Fatal(err)
+	}
+	if err := gowindams.ValidateComponentInspectionTransition("ci1", strPtr(gowindams.COMP_INSPECTION_STATUS_SUBMITTED), gowindams.COMP_INSPECTION_STATUS_APPROVED); err != nil {
+		testing.Fatal(err)
+	}
+	var te *gowindams.TransitionError
+	err := gowindams.ValidateComponentInspectionTransition("ci1", nil, gowindams.COMP_INSPECTION_STATUS_COMPLETED)
+	if !errors.As(err, &te) {
+		testing.Fatalf("Expected a TransitionError, got %v", err)
+	}
+	err = gowindams.ValidateComponentInspectionTransition("ci1", strPtr(gowindams.COMP_INSPECTION_STATUS_STARTED), gowindams.COMP_INSPECTION_STATUS_LOCKED)
+	if !errors.As(err, &te) {
+		testing.Fatalf("Expected a TransitionError, got %v", err)
+	}
+	compareStrings(testing, gowindams.COMP_INSPECTION_STATUS_STARTED, te.From)
+}
+
+func TestTimeInStatus(testing *testing.T) {
+	start := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	event := func(status string, offset time.Duration) gowindams.StatusEvent {
+		ts := gowindams.WindAMSTime(start.Add(offset))
+		return gowindams.StatusEvent{Status: strPtr(status), Timestamp: &ts}
+	}
+	ci := gowindams.ComponentInspection{
+		StatusHistory: []gowindams.StatusEvent{
+			event(gowindams.COMP_INSPECTION_STATUS_COMPLETED, 2*time.Hour),
+			event(gowindams.COMP_INSPECTION_STATUS_STARTED, 0),
+			event(gowindams.COMP_INSPECTION_STATUS_SUBMITTED, 3*time.Hour),
+		},
+	}
+	compareStrings(testing, gowindams.COMP_INSPECTION_STATUS_SUBMITTED, *ci.CurrentStatus())
+	durations := ci.TimeInStatus(start.Add(4 * time.Hour))
+	expected := map[string]time.Duration{
+		gowindams.COMP_INSPECTION_STATUS_STARTED:   2 * time.Hour,
+		gowindams.COMP_INSPECTION_STATUS_COMPLETED: time.Hour,
+		gowindams.COMP_INSPECTION_STATUS_SUBMITTED: time.Hour,
+	}
+	for status, d := range expected {
+		if durations[status] != d {
+			testing.Fatalf("Expected %s in %s, got %s", d, status, durations[status])
+		}
+	}
+}
+
+func TestComponentInspectionTransitions(testing *testing.T) {
+	fake, env := newFakeServices(testing, "  userId: inspector\n")
+	fake.put("componentInspection", `{"id":"ci1","description":"Blade A","version":1}`)
+	client := env.ComponentInspectionServiceClient()
+
+	// Another user edits the inspection just before the completion is saved
+	edited := false
+	fake.intercept = func(r *http.Request) int {
+		if r.Method == "POST" && r.URL.Path == "/componentInspection" && !edited {
+			if stored := fake.get("componentInspection", "ci1"); stored["statusHistory"] != nil {
+				edited = true
+				stored["description"] = "Blade A, leading edge"
+				stored["version"] = stored["version"].(float64) + 1
+			}
+		}
+		return 0
+	}
+	steps := []struct {
+		status     string
+		transition func(string) (*gowindams.ComponentInspection, error)
+	}{
+		{gowindams.COMP_INSPECTION_STATUS_STARTED, client.Start},
+		{gowindams.COMP_INSPECTION_STATUS_COMPLETED, client.Complete},
+		{gowindams.COMP_INSPECTION_STATUS_SUBMITTED, client.Submit},
+		{gowindams.COMP_INSPECTION_STATUS_APPROVED, client.Approve},
+		{gowindams.COMP_INSPECTION_STATUS_LOCKED, client.Lock},
+		{gowindams.COMP_INSPECTION_STATUS_TRANSMITTED, client.Transmit},
+	}
+	for i, step := range steps {
+		ci, err := step.transition("ci1")
+		if err != nil {
+			testing.Fatal(err)
+		}
+		compareStrings(testing, step.status, *ci.CurrentStatus())
+		stored, err := client.Get("ci1")
+		if err != nil {
+			testing.Fatal(err)
+		}
+		if len(stored.StatusHistory) != i+1 {
+			testing.Fatalf("Expected %d status events after moving to %s, got %+v", i+1, step.status, stored.StatusHistory)
+		}
+		event := stored.StatusHistory[i]
+		compareStrings(testing, step.status, *event.Status)
+		if event.UserId == nil || event.Timestamp == nil {
+			testing.Fatalf("Expected the status event to record the user and time, got %+v", event)
+		}
+		compareStrings(testing, "inspector", *event.UserId)
+		if d := time.Since(event.Timestamp.Time()); d < 0 || d > time.Minute {
+			testing.Errorf("Expected the status event to be timestamped now, got %v", event.Timestamp.Time())
+		}
+	}
+
+	// The completion was retried on the edited inspection rather than overwriting the edit
+	if updates := fake.calls("POST /componentInspection"); len(updates) != len(steps)+1 {
+		testing.Errorf("Expected one retry after the conflict, got %v", updates)
+	}
+	compareStrings(testing, "Blade A, leading edge", fake.get("componentInspection", "ci1")["description"].(string))
+
+	var te *gowindams.TransitionError
+	if _, err := client.Start("ci1"); !errors.As(err, &te) {
+		testing.Fatalf("Expected a TransitionError restarting a transmitted inspection, got %v", err)
+	}
+}
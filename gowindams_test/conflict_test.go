@@ -0,0 +1,125 @@
+package gowindams_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func TestIsConflict(testing *testing.T) {
+	var err error = &gowindams.ConflictError{URL: "https://example.com/asset", StatusCode: 412, Message: "stale"}
+	if !gowindams.IsConflict(err) {
+		testing.Fatal("Expected a conflict")
+	}
+	if !gowindams.IsConflict(fmt.Errorf("updating asset: %w", err)) {
+		testing.Fatal("Expected a wrapped conflict")
+	}
+	if gowindams.IsConflict(errors.New("stale")) || gowindams.IsConflict(nil) {
+		testing.Fatal("Unexpected conflict")
+	}
+}
+
+func TestMarshalVersion(testing *testing.T) {
+	data, _ := json.Marshal(gowindams.ComponentInspection{})
+	if strings.Contains(string(data), "version") {
+		testing.Fatalf("Unexpected version in %s", string(data))
+	}
+	version := int64(7)
+	data, _ = json.Marshal(gowindams.ComponentInspection{Version: &version})
+	if !strings.Contains(string(data), `"version":7`) {
+		testing.Fatalf("Missing version in %s", string(data))
+	}
+}
+
+func TestUpdateConflict(testing *testing.T) {
+	fake, env := newFakeServices(testing, "")
+	fake.put("asset", `{"id":"a1","name":"WTG-01","version":3}`)
+	var ifMatch []string
+	fake.intercept = func(r *http.Request) int {
+		if r.Method == "POST" && r.URL.Path == "/asset" {
+			ifMatch = append(ifMatch, r.Header.Get("If-Match"))
+		}
+		return 0
+	}
+	version := int64(2)
+	err := env.AssetServiceClient().Update(&gowindams.Asset{Id: strPtr("a1"), Name: strPtr("Stale"), Version: &version})
+	var conflict *gowindams.ConflictError
+	if !errors.As(err, &conflict) || conflict.StatusCode != http.StatusPreconditionFailed {
+		testing.Fatalf("Expected a precondition failure to be a ConflictError but got %v", err)
+	}
+	compareStrings(testing, `"2"`, strings.Join(ifMatch, ","))
+	compareStrings(testing, "WTG-01", fake.get("asset", "a1")["name"].(string))
+
+	// A conflict reported as 409 is a ConflictError as well
+	fake.put("site", `{"id":"s1"}`)
+	fake.routes["POST /site"] = func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Edited elsewhere", http.StatusConflict)
+	}
+	if err = env.SiteServiceClient().Update(&gowindams.Site{Id: strPtr("s1")}); !gowindams.IsConflict(err) {
+		testing.Errorf("Expected a 409 to be a ConflictError but got %v", err)
+	}
+}
+
+func TestModifyRetries(testing *testing.T) {
+	fake, env := newFakeServices(testing, "")
+	fake.put("asset", `{"id":"a1","name":"WTG-01","version":1}`)
+	// Someone else changes the asset between the first load and update.
+	loads, updates := 0, 0
+	fake.intercept = func(r *http.Request) int {
+		if r.Method == "GET" && r.URL.Path == "/asset/a1" {
+			loads++
+		}
+		if r.Method == "POST" && r.URL.Path == "/asset" {
+			if updates++; updates == 1 {
+				fake.put("asset", `{"id":"a1","name":"WTG-01","serialNumber":"SN-9","version":2}`)
+			}
+		}
+		return 0
+	}
+	calls := 0
+	asset, err := env.AssetServiceClient().Modify("a1", func(a *gowindams.Asset) error {
+		calls++
+		a.Name = strPtr("WTG-01A")
+		return nil
+	}, 3)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if calls != 2 || loads != 2 {
+		testing.Errorf("Expected one retry but mutated %d times after %d loads", calls, loads)
+	}
+	stored := fake.get("asset", "a1")
+	if stored["name"] != "WTG-01A" || stored["serialNumber"] != "SN-9" || *asset.Version != 3 {
+		testing.Errorf("Expected the modification applied to the concurrent change but got %v", stored)
+	}
+
+	// Give up when every update conflicts.
+	fake.intercept = func(r *http.Request) int {
+		if r.Method == "POST" && r.URL.Path == "/asset" {
+			return http.StatusPreconditionFailed
+		}
+		return 0
+	}
+	calls = 0
+	_, err = env.AssetServiceClient().Modify("a1", func(a *gowindams.Asset) error {
+		calls++
+		return nil
+	}, 2)
+	if !gowindams.IsConflict(err) || calls != 2 {
+		testing.Errorf("Expected to give up with a conflict after 2 attempts but got %v after %d", err, calls)
+	}
+
+	// Errors from mutate end the attempt without an update.
+	fake.resetCalls()
+	_, err = env.AssetServiceClient().Modify("a1", func(a *gowindams.Asset) error {
+		return errors.New("Not this one")
+	}, 2)
+	if err == nil || gowindams.IsConflict(err) || len(fake.calls("POST /asset")) != 0 {
+		testing.Errorf("Expected the mutate error without an update but got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package gowindams_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+// A backed up site with a scaled copy of its image, linked from the component inspection along with the original.
+func copierBackupFiles() map[string]string {
+	files := siteBackupFiles()
+	files["componentInspections.json"] = `[{"id":"ci1","componentId":"c1","assetInspectionId":"ai1","plateImageResourceId":"r1","resources":["r1","r2"]}]`
+	files["resources.json"] = `[{"resourceId":"r1","assetId":"a1","componentInspectionId":"ci1","contentType":"image/jpeg"},` +
+		`{"resourceId":"r2","assetId":"a1","componentInspectionId":"ci1","sourceResourceId":"r1","contentType":"image/jpeg"}]`
+	files["binaries/r2"] = "scaled bytes"
+	return files
+}
+
+func compareIds(testing *testing.T, name string, ids *gowindams.IdMapping, entityType string, sourceId string, actual interface{}) {
+	expected, ok := ids.Get(entityType, sourceId)
+	if !ok || expected == sourceId {
+		testing.Errorf("Expected %s %s to be copied with a new id but got %q", entityType, sourceId, expected)
+	}
+	if fmt.Sprint(actual) != expected {
+		testing.Errorf("Expected %s to be rewritten to %s but got %v", name, expected, actual)
+	}
+}
+
+func TestCopySiteFromBackup(testing *testing.T) {
+	fake, env := newFakeServices(testing, "")
+	dir, _ := ioutil.TempDir("", "copier")
+	defer os.RemoveAll(dir)
+	backup, err := gowindams.OpenBackup(writeBackup(testing, dir, copierBackupFiles(), ""))
+	if err != nil {
+		testing.Fatal(err)
+	}
+	defer backup.Close()
+	mappingPath := filepath.Join(dir, "ids.ndjson")
+
+	// The first run stops when the first image fails to upload.
+	fake.intercept = func(r *http.Request) int {
+		if r.Method == "POST" && strings.HasPrefix(r.URL.Path, "/multimedia/") {
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	}
+	ids, err := gowindams.OpenIdMapping(mappingPath)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if _, err = gowindams.RestoreSite(env, backup, ids, nil); err == nil {
+		testing.Fatal("Expected the copy to fail uploading the image")
+	}
+	ids.Close()
+
+	// Resuming from the mapping file uploads the images without creating anything twice.
+	fake.intercept = nil
+	if ids, err = gowindams.OpenIdMapping(mappingPath); err != nil {
+		testing.Fatal(err)
+	}
+	defer ids.Close()
+	report, err := gowindams.RestoreSite(env, backup, ids, nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if report.Skipped[gowindams.EntityTypeAsset] != 1 || report.Copied["ResourceBinary"] != 2 {
+		testing.Errorf("Expected the entities to be skipped and the binaries copied but got %+v", report)
+	}
+	for collection, count := range map[string]int{"site": 1, "workOrder": 1, "asset": 1, "component": 1, "assetInspection": 1,
+		"componentInspection": 1, "resource": 2, "inspectionEventResource": 2} {
+		if n := len(fake.list(collection)); n != count {
+			testing.Errorf("Expected %d %s but got %d", count, collection, n)
+		}
+	}
+
+	asset := fake.list("asset")[0]
+	compareIds(testing, "the asset's site", ids, gowindams.EntityTypeSite, "s1", asset["siteId"])
+	ci := fake.list("componentInspection")[0]
+	compareIds(testing, "the component inspection's component", ids, gowindams.EntityTypeComponent, "c1", ci["componentId"])
+	compareIds(testing, "the component inspection's asset inspection", ids, gowindams.EntityTypeAssetInspection, "ai1", ci["assetInspectionId"])
+	compareIds(testing, "the plate image", ids, gowindams.EntityTypeResource, "r1", ci["plateImageResourceId"])
+	r1, _ := ids.Get(gowindams.EntityTypeResource, "r1")
+	r2, _ := ids.Get(gowindams.EntityTypeResource, "r2")
+	compareStrings(testing, fmt.Sprint([]interface{}{r1, r2}), fmt.Sprint(ci["resources"]))
+	scaled := fake.get("resource", r2)
+	compareIds(testing, "the scaled image's component inspection", ids, gowindams.EntityTypeComponentInspection, "ci1", scaled["componentInspectionId"])
+	compareIds(testing, "the scaled image's source", ids, gowindams.EntityTypeResource, "r1", scaled["sourceResourceId"])
+	compareIds(testing, "the inspection event's resource", ids, gowindams.EntityTypeResource, "r1", fake.list("inspectionEventResource")[0]["resourceId"])
+	compareStrings(testing, "image bytes", string(fake.binary(r1)))
+	compareStrings(testing, "scaled bytes", string(fake.binary(r2)))
+
+	// A run after a complete copy has nothing left to do.
+	fake.resetCalls()
+	if report, err = gowindams.RestoreSite(env, backup, ids, nil); err != nil {
+		testing.Fatal(err)
+	}
+	if len(report.Copied) != 0 || len(fake.calls("PUT")) != 0 || len(fake.calls("POST /multimedia")) != 0 {
+		testing.Errorf("Expected nothing to be copied again but got %v and requests %v", report.Copied, fake.calls(""))
+	}
+}
+
+func TestCopySiteBetweenEnvironments(testing *testing.T) {
+	source, sourceEnv := newFakeServices(testing, "")
+	source.put("site", `{"id":"s1","name":"Prairie Wind"}`)
+	source.put("asset", `{"id":"a1","siteId":"s1","name":"WTG-01"}`)
+	target, targetEnv := newFakeServices(testing, "")
+
+	// A Copier made without NewCopier copies from its Source.
+	copier := &gowindams.Copier{Source: sourceEnv, Target: targetEnv, Ids: gowindams.NewIdMapping()}
+	report, err := copier.CopySite("s1")
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if report.Copied[gowindams.EntityTypeSite] != 1 || report.Copied[gowindams.EntityTypeAsset] != 1 {
+		testing.Errorf("Expected the site and asset to be copied but got %v", report.Copied)
+	}
+	assets := target.list("asset")
+	if len(assets) != 1 {
+		testing.Fatalf("Expected one asset in the target but got %d", len(assets))
+	}
+	compareIds(testing, "the asset's site", copier.Ids, gowindams.EntityTypeSite, "s1", assets[0]["siteId"])
+}
@@ -0,0 +1,150 @@
+package gowindams_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+// The files of a backup of a site with one inspected blade, two images of it and damage marked on the first.
+func reportBackupFiles() map[string]string {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			img.Set(x, y, color.RGBA{R: 90, G: 110, B: 130, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return map[string]string{
+		"site.json":                 `{"id":"s1","name":"Prairie Wind"}`,
+		"workOrders.json":           `[{"orderNumber":"WO-1","siteId":"s1","description":"Annual blade inspection"}]`,
+		"assets.json":               `[{"id":"a1","siteId":"s1","name":"WTG-01"}]`,
+		"components.json":           `[{"id":"c1","assetId":"a1","siteId":"s1","type":"BladeA","serialNumber":"B-100"}]`,
+		"assetInspections.json":     `[{"id":"ai1","assetId":"a1","siteId":"s1","orderNumber":"WO-1","dateOfInspection":"2020-06-01"}]`,
+		"componentInspections.json": `[{"id":"ci1","componentId":"c1","assetInspectionId":"ai1","orderNumber":"WO-1","description":"Erosion <near> tip"}]`,
+		"resources.json": `[{"resourceId":"r1","assetId":"a1","assetInspectionId":"ai1","componentInspectionId":"ci1","contentType":"image/png","name":"IMG_0001","position":{"side":"LeadingEdge","x":0.5,"y":0.25}},` +
+			`{"resourceId":"r2","assetId":"a1","assetInspectionId":"ai1","componentInspectionId":"ci1","contentType":"image/png","name":"IMG_0002"}]`,
+		"inspectionEventResources.json": `[{"id":"ier1","resourceId":"r1","orderNumber":"WO-1","polygons":[` +
+			`{"name":"Erosion","severity":4,"text":"Leading edge (tip)","geometry":[{"latitude":10,"longitude":20},{"latitude":10,"longitude":60},{"latitude":40,"longitude":60}]},` +
+			`{"name":"Crack","severity":2,"geometry":[{"latitude":50,"longitude":100},{"latitude":60,"longitude":100},{"latitude":60,"longitude":140}]},` +
+			`{"name":"Chip","center":{"latitude":80,"longitude":150}}]}]`,
+		"binaries/r1": buf.String(),
+		"binaries/r2": buf.String(),
+	}
+}
+
+func loadTestDamageReport(testing *testing.T, options *gowindams.DamageReportOptions) *gowindams.DamageReport {
+	dir, _ := ioutil.TempDir("", "report")
+	defer os.RemoveAll(dir)
+	backup, err := gowindams.OpenBackup(writeBackup(testing, dir, reportBackupFiles(), ""))
+	if err != nil {
+		testing.Fatal(err)
+	}
+	defer backup.Close()
+	report, err := gowindams.BackupDamageReport(backup, "WO-1", "", options)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	return report
+}
+
+func TestDamageReportSummary(testing *testing.T) {
+	report := loadTestDamageReport(testing, nil)
+	compareStrings(testing, "Damage report for Prairie Wind", report.Title)
+	if len(report.Turbines) != 1 || len(report.Turbines[0].Components) != 1 {
+		testing.Fatalf("Expected one turbine with one inspected component")
+	}
+	images := report.Turbines[0].Components[0].Images
+	if len(images) != 1 || *images[0].Resource.ResourceId != "r1" {
+		testing.Fatalf("Expected only the image with damage marked on it")
+	}
+	if images[0].Width != 200 || images[0].Height != 100 || len(images[0].JPEG) == 0 {
+		testing.Errorf("Expected the original size and a scaled copy but got %dx%d", images[0].Width, images[0].Height)
+	}
+	if fmt.Sprint(report.Severities) != "[2 4]" {
+		testing.Errorf("Expected severities [2 4] but got %v", report.Severities)
+	}
+	row := report.Summary[0]
+	compareStrings(testing, "WTG-01", row.Asset)
+	compareStrings(testing, "BladeA (B-100)", row.Component)
+	if fmt.Sprint(row.Counts) != "[1 1]" || row.Unrated != 1 || row.Total != 3 || row.Highest != 4 {
+		testing.Errorf("Unexpected summary %+v", row)
+	}
+
+	all := loadTestDamageReport(testing, &gowindams.DamageReportOptions{IncludeAllImages: true})
+	if len(all.Turbines[0].Components[0].Images) != 2 {
+		testing.Errorf("Expected both images when including those without damage")
+	}
+}
+
+func TestDamageReportHTML(testing *testing.T) {
+	report := loadTestDamageReport(testing, nil)
+	var buf bytes.Buffer
+	if err := report.WriteHTML(&buf); err != nil {
+		testing.Fatal(err)
+	}
+	html := buf.String()
+	for _, expected := range []string{
+		"<title>Damage report for Prairie Wind</title>",
+		"Work order WO-1: Annual blade inspection.",
+		`xlink:href="data:image/jpeg;base64,`,
+		`viewBox="0 0 200 100"`,
+		`<polygon points="20.0,10.0 60.0,10.0 60.0,40.0" fill="#f07800" stroke="#f07800"/>`,
+		// Polygons are drawn in the image's pixels, with longitude as x and latitude as y
+		`<polygon points="100.0,50.0 100.0,60.0 140.0,60.0"`,
+		">Erosion</text>",
+		"Erosion &lt;near&gt; tip",
+		"IMG_0001 · LeadingEdge · x 0.50, y 0.25",
+		"<td>unrated</td>",
+	} {
+		if !strings.Contains(html, expected) {
+			testing.Errorf("Expected the report to contain %s", expected)
+		}
+	}
+}
+
+func TestDamageReportPDF(testing *testing.T) {
+	report := loadTestDamageReport(testing, nil)
+	var buf bytes.Buffer
+	if err := report.WritePDF(&buf); err != nil {
+		testing.Fatal(err)
+	}
+	pdf := buf.Bytes()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		testing.Fatalf("Expected a PDF header and trailer")
+	}
+	// Every object must be where the cross reference table says it is.
+	xref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(pdf)
+	if xref == nil {
+		testing.Fatal("Expected a startxref entry")
+	}
+	start, _ := strconv.Atoi(string(xref[1]))
+	lines := strings.Split(string(pdf[start:]), "\n")
+	if lines[0] != "xref" {
+		testing.Fatalf("Expected the cross reference table at %d", start)
+	}
+	var count int
+	fmt.Sscanf(lines[1], "0 %d", &count)
+	for i := 1; i < count; i++ {
+		offset, _ := strconv.Atoi(lines[2+i][:10])
+		if !bytes.HasPrefix(pdf[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i))) {
+			testing.Errorf("Object %d is not at offset %d", i, offset)
+		}
+	}
+	for _, expected := range []string{"/Filter /DCTDecode", "(Severity summary) Tj", "(Erosion) Tj", `(Leading edge \(tip\)) Tj`, "/Im0 Do"} {
+		if !bytes.Contains(pdf, []byte(expected)) {
+			testing.Errorf("Expected the PDF to contain %s", expected)
+		}
+	}
+}
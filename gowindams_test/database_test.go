@@ -0,0 +1,302 @@
+package gowindams_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// An in-memory stand-in for SQLite, for testing the mirror.  It understands only the statements the mirror makes:
+// CREATE TABLE and CREATE INDEX, INSERT OR REPLACE, and DELETE and SELECT with conditions of the form "column = ?"
+// joined by AND and an optional ORDER BY on one column.  Rows are kept as the values they were inserted with, and a
+// transaction which is rolled back restores the tables as they were when it began.
+type fakeDatabase struct {
+	mu     sync.Mutex
+	tables map[string]*fakeTable
+	saved  map[string]*fakeTable
+}
+
+type fakeTable struct {
+	key  []string
+	rows []map[string]driver.Value
+}
+
+// Opens a new, empty database.
+func newFakeDatabase() *sql.DB {
+	return sql.OpenDB(&fakeConnector{db: &fakeDatabase{tables: make(map[string]*fakeTable)}})
+}
+
+var (
+	fakeCreateTable = regexp.MustCompile(`^CREATE TABLE IF NOT EXISTS (\w+) \((.*)\)$`)
+	fakePrimaryKey  = regexp.MustCompile(`PRIMARY KEY \(([^)]*)\)|(\w+) \w+ PRIMARY KEY`)
+	fakeInsert      = regexp.MustCompile(`^INSERT OR REPLACE INTO (\w+) \(([^)]*)\) VALUES \([?, ]*\)$`)
+	fakeDelete      = regexp.MustCompile(`^DELETE FROM (\w+)(?: WHERE (.*))?$`)
+	fakeSelect      = regexp.MustCompile(`^SELECT (.*) FROM (\w+)(?: WHERE (.*?))?(?: ORDER BY (\w+))?$`)
+	fakeCondition   = regexp.MustCompile(`^(\w+) = \?$`)
+)
+
+// Runs a statement, returning the number of rows affected by an INSERT or DELETE or the rows found by a SELECT.
+func (db *fakeDatabase) run(query string, args []driver.Value) (int64, *fakeRows, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	query = strings.Join(strings.Fields(query), " ")
+	if m := fakeCreateTable.FindStringSubmatch(query); m != nil {
+		if db.tables[m[1]] == nil {
+			key := fakePrimaryKey.FindStringSubmatch(m[2])
+			if key == nil {
+				return 0, nil, fmt.Errorf("No primary key in %s", query)
+			}
+			columns := strings.Split(key[1]+key[2], ",")
+			for i := range columns {
+				columns[i] = strings.TrimSpace(columns[i])
+			}
+			db.tables[m[1]] = &fakeTable{key: columns}
+		}
+		return 0, nil, nil
+	}
+	if strings.HasPrefix(query, "CREATE INDEX ") {
+		return 0, nil, nil
+	}
+	if m := fakeInsert.FindStringSubmatch(query); m != nil {
+		table, err := db.table(m[1])
+		if err != nil {
+			return 0, nil, err
+		}
+		columns := strings.Split(m[2], ", ")
+		if len(columns) != len(args) {
+			return 0, nil, fmt.Errorf("Expected %d values but got %d in %s", len(columns), len(args), query)
+		}
+		row := make(map[string]driver.Value)
+		for i, column := range columns {
+			if b, ok := args[i].([]byte); ok {
+				args[i] = append([]byte(nil), b...)
+			}
+			row[column] = args[i]
+		}
+		table.replace(row)
+		return 1, nil, nil
+	}
+	if m := fakeDelete.FindStringSubmatch(query); m != nil {
+		table, err := db.table(m[1])
+		if err != nil {
+			return 0, nil, err
+		}
+		match, err := fakeWhere(m[2], args)
+		if err != nil {
+			return 0, nil, err
+		}
+		kept := make([]map[string]driver.Value, 0, len(table.rows))
+		for _, row := range table.rows {
+			if !match(row) {
+				kept = append(kept, row)
+			}
+		}
+		deleted := int64(len(table.rows) - len(kept))
+		table.rows = kept
+		return deleted, nil, nil
+	}
+	if m := fakeSelect.FindStringSubmatch(query); m != nil {
+		table, err := db.table(m[2])
+		if err != nil {
+			return 0, nil, err
+		}
+		match, err := fakeWhere(m[3], args)
+		if err != nil {
+			return 0, nil, err
+		}
+		found := make([]map[string]driver.Value, 0)
+		for _, row := range table.rows {
+			if match(row) {
+				found = append(found, row)
+			}
+		}
+		if order := m[4]; order != "" {
+			sort.SliceStable(found, func(i, j int) bool { return fakeString(found[i][order]) < fakeString(found[j][order]) })
+		}
+		rows := &fakeRows{columns: strings.Split(m[1], ", ")}
+		for _, row := range found {
+			values := make([]driver.Value, len(rows.columns))
+			for i, column := range rows.columns {
+				values[i] = row[column]
+			}
+			rows.values = append(rows.values, values)
+		}
+		return 0, rows, nil
+	}
+	return 0, nil, fmt.Errorf("Unsupported statement %s", query)
+}
+
+func (db *fakeDatabase) table(name string) (*fakeTable, error) {
+	table := db.tables[name]
+	if table == nil {
+		return nil, fmt.Errorf("No such table: %s", name)
+	}
+	return table, nil
+}
+
+// Replaces the row with the same primary key, if there is one, or adds the row.
+func (table *fakeTable) replace(row map[string]driver.Value) {
+	for i, existing := range table.rows {
+		same := true
+		for _, column := range table.key {
+			same = same && fakeString(existing[column]) == fakeString(row[column])
+		}
+		if same {
+			table.rows[i] = row
+			return
+		}
+	}
+	table.rows = append(table.rows, row)
+}
+
+// A predicate for the conditions of a WHERE clause, taking their values from args in order.  An empty clause matches
+// every row.
+func fakeWhere(where string, args []driver.Value) (func(map[string]driver.Value) bool, error) {
+	conditions := make(map[string]string)
+	if where != "" {
+		for i, condition := range strings.Split(where, " AND ") {
+			m := fakeCondition.FindStringSubmatch(condition)
+			if m == nil || i >= len(args) {
+				return nil, fmt.Errorf("Unsupported condition %s", condition)
+			}
+			conditions[m[1]] = fakeString(args[i])
+		}
+	}
+	return func(row map[string]driver.Value) bool {
+		for column, value := range conditions {
+			if row[column] == nil || fakeString(row[column]) != value {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func fakeString(v driver.Value) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+func (db *fakeDatabase) begin() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.saved = make(map[string]*fakeTable)
+	for name, table := range db.tables {
+		db.saved[name] = &fakeTable{key: table.key, rows: append([]map[string]driver.Value(nil), table.rows...)}
+	}
+}
+
+func (db *fakeDatabase) end(commit bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if !commit && db.saved != nil {
+		db.tables = db.saved
+	}
+	db.saved = nil
+}
+
+type fakeConnector struct {
+	db *fakeDatabase
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: c.db}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, fmt.Errorf("Open the fake database with newFakeDatabase")
+}
+
+type fakeConn struct {
+	db *fakeDatabase
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.begin()
+	return fakeTx{db: c.db}, nil
+}
+
+type fakeTx struct {
+	db *fakeDatabase
+}
+
+func (tx fakeTx) Commit() error {
+	tx.db.end(true)
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.end(false)
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDatabase
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	n, _, err := s.db.run(s.query, args)
+	return driver.RowsAffected(n), err
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	_, rows, err := s.db.run(s.query, args)
+	if err == nil && rows == nil {
+		err = fmt.Errorf("Not a query: %s", s.query)
+	}
+	return rows, err
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
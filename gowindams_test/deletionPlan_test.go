@@ -0,0 +1,128 @@
+package gowindams_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+// An inspection listing one of its two resources, each of which has damage marked on it.
+func newDeletionServices(testing *testing.T) (*fakeServices, *gowindams.Environment) {
+	fake, env := newFakeServices(testing, "")
+	fake.put("componentInspection", `{"id":"ci1","componentId":"c1","resources":["r1"]}`)
+	fake.put("resource",
+		`{"resourceId":"r1","componentInspectionId":"ci1"}`,
+		`{"resourceId":"r2","componentInspectionId":"ci1"}`,
+		`{"resourceId":"r3","componentInspectionId":"ci2"}`)
+	fake.put("inspectionEventResource",
+		`{"id":"ier1","resourceId":"r1"}`,
+		`{"id":"ier2","resourceId":"r2"}`,
+		`{"id":"ier3","resourceId":"r3"}`)
+	return fake, env
+}
+
+func TestDeleteCascadeDryRun(testing *testing.T) {
+	fake, env := newDeletionServices(testing)
+	plan, err := env.ComponentInspectionServiceClient().DeleteCascade("ci1", true)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	expected := "1\tInspectionEventResource\tier1\tpending\n" +
+		"2\tInspectionEventResource\tier2\tpending\n" +
+		"3\tResource\tr1\tpending\n" +
+		"4\tResource\tr2\tpending\n" +
+		"5\tComponentInspection\tci1\tpending\n"
+	compareStrings(testing, expected, plan.String())
+	if deletes := fake.calls("DELETE"); len(deletes) != 0 {
+		testing.Errorf("Expected a dry run to delete nothing but got %v", deletes)
+	}
+	// r1 is both listed by the inspection and found by the search, but is only looked up once.
+	if searches := fake.calls("POST /inspectionEventResource/search"); len(searches) != 2 {
+		testing.Errorf("Expected one search for each resource but got %d", len(searches))
+	}
+}
+
+func TestDeleteCascade(testing *testing.T) {
+	fake, env := newDeletionServices(testing)
+	plan, err := env.ComponentInspectionServiceClient().DeleteCascade("ci1", false)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	expected := []string{
+		"DELETE /inspectionEventResource/ier1",
+		"DELETE /inspectionEventResource/ier2",
+		"DELETE /resource/r1",
+		"DELETE /resource/r2",
+		"DELETE /componentInspection/ci1",
+	}
+	compareStrings(testing, strings.Join(expected, "\n"), strings.Join(fake.calls("DELETE"), "\n"))
+	for _, step := range plan.Steps {
+		if !step.Deleted {
+			testing.Errorf("Expected %s %s to be marked deleted", step.EntityType, step.Id)
+		}
+	}
+	if fake.get("resource", "r3") == nil || fake.get("inspectionEventResource", "ier3") == nil {
+		testing.Errorf("Expected the resources of other inspections to be left alone")
+	}
+}
+
+func TestDeletionPlanStopsAtFailure(testing *testing.T) {
+	fake, env := newDeletionServices(testing)
+	fake.intercept = func(r *http.Request) int {
+		if r.Method == "DELETE" && r.URL.Path == "/resource/r1" {
+			return http.StatusInternalServerError
+		}
+		return 0
+	}
+	plan, err := env.ComponentInspectionServiceClient().DeleteCascade("ci1", false)
+	if err == nil {
+		testing.Fatal("Expected the cascade to fail")
+	}
+	deleted := make([]string, 0)
+	for _, step := range plan.Steps {
+		deleted = append(deleted, fmt.Sprintf("%s=%v", step.Id, step.Deleted))
+	}
+	compareStrings(testing, "ier1=true ier2=true r1=false r2=false ci1=false", strings.Join(deleted, " "))
+	if deletes := fake.calls("DELETE"); len(deletes) != 3 {
+		testing.Errorf("Expected nothing to be deleted after the failure but got %v", deletes)
+	}
+
+	// Executing the plan again picks up where it failed.
+	fake.intercept = nil
+	fake.resetCalls()
+	if err = plan.Execute(env); err != nil {
+		testing.Fatal(err)
+	}
+	compareStrings(testing, "DELETE /resource/r1 DELETE /resource/r2 DELETE /componentInspection/ci1", strings.Join(fake.calls("DELETE"), " "))
+}
+
+func TestDeleteClients(testing *testing.T) {
+	fake, env := newFakeServices(testing, "")
+	fake.put("site", `{"id":"s1"}`)
+	fake.put("asset", `{"id":"a1"}`)
+	fake.put("component", `{"id":"c1"}`)
+	fake.put("assetInspection", `{"id":"ai1"}`)
+	fake.put("workOrder", `{"orderNumber":"WO-1"}`)
+	for _, err := range []error{
+		env.WorkOrderServiceClient().Delete("WO-1"),
+		env.AssetInspectionServiceClient().Delete("ai1"),
+		env.ComponentServiceClient().Delete("c1"),
+		env.AssetServiceClient().Delete("a1"),
+		env.SiteServiceClient().Delete("s1"),
+	} {
+		if err != nil {
+			testing.Fatal(err)
+		}
+	}
+	for _, collection := range []string{"site", "asset", "component", "assetInspection", "workOrder"} {
+		if len(fake.list(collection)) != 0 {
+			testing.Errorf("Expected the %s to be deleted", collection)
+		}
+	}
+	if err := env.SiteServiceClient().Delete("s1"); err == nil {
+		testing.Errorf("Expected an error deleting a site which does not exist")
+	}
+}
@@ -0,0 +1,146 @@
+package gowindams_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func diffTrees() (*gowindams.SiteTree, *gowindams.SiteTree) {
+	a := gowindams.BuildSiteTree(&gowindams.Site{Id: strPtr("s1"), Name: strPtr("Prairie Wind")},
+		[]gowindams.Asset{
+			{Id: strPtr("a1"), SiteId: strPtr("s1"), Name: strPtr("WTG-01"), Make: strPtr("Vestas")},
+			{Id: strPtr("a2"), SiteId: strPtr("s1"), Name: strPtr("WTG-02")},
+		}, nil, nil, nil, nil)
+	b := gowindams.BuildSiteTree(&gowindams.Site{Id: strPtr("t1"), Name: strPtr("Prairie Wind")},
+		[]gowindams.Asset{
+			{Id: strPtr("t2"), SiteId: strPtr("t1"), Name: strPtr("WTG-01"), Make: strPtr("Siemens")},
+			{Id: strPtr("t4"), SiteId: strPtr("t1"), Name: strPtr("WTG-04")},
+		}, nil, nil, nil, nil)
+	return a, b
+}
+
+func TestDiffSiteTreesWithMapping(testing *testing.T) {
+	a, b := diffTrees()
+	ids := gowindams.NewIdMapping()
+	ids.Set(gowindams.EntityTypeSite, "s1", "t1")
+	ids.Set(gowindams.EntityTypeAsset, "a1", "t2")
+	report, err := gowindams.DiffSiteTrees(a, b, &gowindams.DiffOptions{Ids: ids})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if len(report.Diffs) != 3 {
+		testing.Fatalf("Expected 3 differences, got:\n%s", report)
+	}
+	changed := report.Diffs[0]
+	compareStrings(testing, gowindams.DiffChanged, changed.Kind)
+	compareStrings(testing, "a1", changed.Id)
+	if len(changed.Changes) != 1 || changed.Changes[0].Field != "make" {
+		testing.Fatalf("Expected only make to differ, got %v", changed.Changes)
+	}
+	compareStrings(testing, gowindams.DiffRemoved, report.Diffs[1].Kind)
+	compareStrings(testing, "a2", report.Diffs[1].Id)
+	compareStrings(testing, gowindams.DiffAdded, report.Diffs[2].Kind)
+	compareStrings(testing, "t4", report.Diffs[2].OtherId)
+}
+
+func TestDiffSiteTreesIgnore(testing *testing.T) {
+	a, b := diffTrees()
+	ids := gowindams.NewIdMapping()
+	ids.Set(gowindams.EntityTypeSite, "s1", "t1")
+	ids.Set(gowindams.EntityTypeAsset, "a1", "t2")
+	ids.Set(gowindams.EntityTypeAsset, "a2", "t4")
+	report, err := gowindams.DiffSiteTrees(a, b, &gowindams.DiffOptions{
+		Ids:    ids,
+		Ignore: map[string][]string{gowindams.EntityTypeAsset: {"make", "name"}},
+	})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if len(report.Diffs) != 0 {
+		testing.Fatalf("Expected no differences, got:\n%s", report)
+	}
+}
+
+func TestDiffSiteTreesById(testing *testing.T) {
+	a, _ := diffTrees()
+	rmeta := gowindams.ResourceMetadata{ResourceId: strPtr("r1"), AssetId: strPtr("a1"), AssetInspectionId: strPtr("ai1"), DownloadURL: strPtr("https://a.example.com/r1")}
+	other := rmeta
+	other.DownloadURL = strPtr("https://b.example.com/r1")
+	ais := []gowindams.AssetInspection{{Id: strPtr("ai1"), AssetId: strPtr("a1")}}
+	treeA := gowindams.BuildSiteTree(a.Site, []gowindams.Asset{*a.Asset("a1").Asset}, nil, ais, nil, []gowindams.ResourceMetadata{rmeta})
+	treeB := gowindams.BuildSiteTree(a.Site, []gowindams.Asset{*a.Asset("a1").Asset}, nil, ais, nil, []gowindams.ResourceMetadata{other})
+	report, err := gowindams.DiffSiteTrees(treeA, treeB, nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if len(report.Diffs) != 0 {
+		testing.Fatalf("Expected download URLs to be ignored, got:\n%s", report)
+	}
+}
+
+func TestDiffSites(testing *testing.T) {
+	_, sourceEnv := newBackupServices(testing)
+	target, targetEnv := newFakeServices(testing, "")
+	target.put("site", `{"id":"t1","name":"Prairie Wind"}`)
+	target.put("workOrder", `{"orderNumber":"WO-1","siteId":"t1","description":"Rescheduled"}`)
+	target.put("asset", `{"id":"t2","siteId":"t1","name":"WTG-01"}`)
+	target.put("component", `{"id":"t3","assetId":"t2","siteId":"t1"}`)
+	target.put("assetInspection", `{"id":"t4","assetId":"t2","siteId":"t1","orderNumber":"WO-1"}`)
+	target.put("componentInspection", `{"id":"t5","componentId":"t3","assetId":"t2","siteId":"t1","assetInspectionId":"t4","resources":["t6"]}`)
+	target.put("resource", `{"resourceId":"t6","siteId":"t1","assetId":"t2","componentId":"t3","assetInspectionId":"t4","componentInspectionId":"t5","contentType":"image/png"}`)
+	target.put("inspectionEventResource",
+		`{"id":"t7","resourceId":"t6","siteId":"t1","polygons":[{"name":"Crack"}]}`,
+		`{"id":"t8","resourceId":"t6","siteId":"t1"}`)
+
+	dir, _ := ioutil.TempDir("", "diff")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ids.ndjson")
+	ids, err := gowindams.OpenIdMapping(path)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	// As recorded by a copy of everything but the newer inspection event resource.
+	for i, entry := range [][2]string{{gowindams.EntityTypeSite, "s1"}, {gowindams.EntityTypeAsset, "a1"}, {gowindams.EntityTypeComponent, "c1"},
+		{gowindams.EntityTypeAssetInspection, "ai1"}, {gowindams.EntityTypeComponentInspection, "ci1"}, {gowindams.EntityTypeResource, "r1"},
+		{gowindams.EntityTypeInspectionEventResource, "ier1"}} {
+		ids.Set(entry[0], entry[1], fmt.Sprintf("t%d", i+1))
+	}
+	ids.Close()
+	if ids, err = gowindams.LoadIdMapping(path); err != nil {
+		testing.Fatal(err)
+	}
+
+	report, err := gowindams.DiffSites(sourceEnv, targetEnv, "s1", &gowindams.DiffOptions{Ids: ids})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	diffs := make([]string, 0)
+	for _, d := range report.Diffs {
+		diffs = append(diffs, fmt.Sprintf("%s %s %s %s", d.Kind, d.EntityType, d.Id, d.OtherId))
+	}
+	expected := []string{
+		"changed WorkOrder WO-1 WO-1",
+		"changed InspectionEventResource ier1 t7",
+		"added InspectionEventResource  t8",
+	}
+	compareStrings(testing, strings.Join(expected, "\n"), strings.Join(diffs, "\n"))
+	compareStrings(testing, "description", report.Diffs[0].Changes[0].Field)
+}
+
+func TestLoadIdMappingMissing(testing *testing.T) {
+	dir, _ := ioutil.TempDir("", "diff")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ids.ndjson")
+	if _, err := gowindams.LoadIdMapping(path); err == nil {
+		testing.Fatal("Expected an error loading a mapping which does not exist")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		testing.Errorf("Expected the mapping not to be created")
+	}
+}
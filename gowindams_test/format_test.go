@@ -0,0 +1,72 @@
+package gowindams_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func formatAssets(testing *testing.T, options *gowindams.FormatOptions) string {
+	lat, lng := 41.5, -93.25
+	assets := []gowindams.Asset{
+		{Id: strPtr("a1"), Name: strPtr("WTG-01"), Location: &gowindams.GeoPoint{Latitude: &lat, Longitude: &lng}},
+		{Id: strPtr("a2"), Name: strPtr("WTG, 02"), Attributes: map[string]string{"hubHeight": "80"}},
+	}
+	var buf bytes.Buffer
+	if err := gowindams.WriteFormatted(&buf, assets, options); err != nil {
+		testing.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestFormatCSV(testing *testing.T) {
+	got := formatAssets(testing, &gowindams.FormatOptions{Format: gowindams.FormatCSV, Columns: []string{"id", "name", "location.latitude", "attributes.hubHeight"}})
+	compareStrings(testing, "id,name,location.latitude,attributes.hubHeight\na1,WTG-01,41.5,\na2,\"WTG, 02\",,80\n", got)
+}
+
+func TestFormatTableDefaultColumns(testing *testing.T) {
+	got := formatAssets(testing, &gowindams.FormatOptions{Format: gowindams.FormatTable})
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	if len(lines) != 3 {
+		testing.Fatalf("Expected a header and two rows, got %q", got)
+	}
+	header := strings.Fields(lines[0])
+	expected := []string{"ID", "LOCATION.LATITUDE", "LOCATION.LONGITUDE", "NAME", "ATTRIBUTES.HUBHEIGHT"}
+	for _, column := range expected {
+		if !strings.Contains(lines[0], column) {
+			testing.Fatalf("Expected column %s in %v", column, header)
+		}
+	}
+	if strings.Contains(lines[0], "MAKE") {
+		testing.Fatalf("Expected columns without values to be left out: %v", header)
+	}
+}
+
+func TestFormatNDJSON(testing *testing.T) {
+	got := formatAssets(testing, &gowindams.FormatOptions{Format: gowindams.FormatNDJSON, Columns: []string{"id", "name"}})
+	compareStrings(testing, "{\"id\":\"a1\",\"name\":\"WTG-01\"}\n{\"id\":\"a2\",\"name\":\"WTG, 02\"}\n", got)
+}
+
+func TestFormatYAML(testing *testing.T) {
+	var buf bytes.Buffer
+	site := gowindams.Site{Id: strPtr("s1"), Name: strPtr("Prairie Wind")}
+	err := gowindams.WriteFormatted(&buf, &site, &gowindams.FormatOptions{Format: gowindams.FormatYAML, Columns: []string{"id", "name"}})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	compareStrings(testing, "id: s1\nname: Prairie Wind\n", buf.String())
+}
+
+func TestFormatTemplate(testing *testing.T) {
+	got := formatAssets(testing, &gowindams.FormatOptions{Format: gowindams.FormatTemplate, Template: "{{.id}}={{.name}}"})
+	compareStrings(testing, "a1=WTG-01\na2=WTG, 02\n", got)
+}
+
+func TestFormatUnknown(testing *testing.T) {
+	var buf bytes.Buffer
+	if err := gowindams.WriteFormatted(&buf, []string{}, &gowindams.FormatOptions{Format: "xml"}); err == nil {
+		testing.Fatal("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,151 @@
+package gowindams_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func TestUnmarshalGeoPoint(testing *testing.T) {
+	p := new(gowindams.GeoPoint)
+	err := json.Unmarshal([]byte(`{"accuracy":3.5,"altitude":88.0,"latitude":41.5,"longitude":-93.6}`), p)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if p.Longitude == nil || *p.Longitude != -93.6 {
+		testing.Fatalf("Invalid longitude %v", p.Longitude)
+	}
+}
+
+func TestDistanceAndBearing(testing *testing.T) {
+	// Roughly one degree of latitude apart, due north.
+	a := gowindams.NewGeoPoint(41.0, -93.0)
+	b := gowindams.NewGeoPoint(42.0, -93.0)
+	d, err := a.DistanceTo(b)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if math.Abs(d-111195) > 10 {
+		testing.Fatalf("Invalid distance %f", d)
+	}
+	bearing, err := a.BearingTo(b)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if math.Abs(bearing) > 1e-9 {
+		testing.Fatalf("Invalid bearing %f", bearing)
+	}
+	bearing, _ = b.BearingTo(a)
+	if math.Abs(bearing-180) > 1e-9 {
+		testing.Fatalf("Invalid bearing %f", bearing)
+	}
+	east := gowindams.NewGeoPoint(0, 1)
+	bearing, _ = gowindams.NewGeoPoint(0, 0).BearingTo(east)
+	if math.Abs(bearing-90) > 1e-9 {
+		testing.Fatalf("Invalid bearing %f", bearing)
+	}
+
+	if _, err = a.DistanceTo(gowindams.GeoPoint{}); err != gowindams.ErrMissingCoordinates {
+		testing.Fatalf("Expected missing coordinates error, got %v", err)
+	}
+}
+
+func TestBoundingBox(testing *testing.T) {
+	points := []gowindams.GeoPoint{
+		gowindams.NewGeoPoint(41.2, -93.1),
+		gowindams.NewGeoPoint(41.5, -93.6),
+		{},
+		gowindams.NewGeoPoint(41.3, -93.4),
+	}
+	box, err := gowindams.BoundingBoxOf(points)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if box.MinLatitude != 41.2 || box.MaxLatitude != 41.5 || box.MinLongitude != -93.6 || box.MaxLongitude != -93.1 {
+		testing.Fatalf("Invalid bounding box %+v", box)
+	}
+	if !box.Contains(gowindams.NewGeoPoint(41.4, -93.3)) || box.Contains(gowindams.NewGeoPoint(41.6, -93.3)) {
+		testing.Fatalf("Invalid containment for bounding box %+v", box)
+	}
+
+	center := gowindams.NewGeoPoint(41.3, -93.4)
+	around, err := gowindams.BoundingBoxAround(center, 1000)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	corner := gowindams.NewGeoPoint(around.MaxLatitude, -93.4)
+	d, _ := center.DistanceTo(corner)
+	if math.Abs(d-1000) > 1 {
+		testing.Fatalf("Expected the box edge to be 1000m from the center, got %f", d)
+	}
+
+	for _, lng := range []float64{179.999, -179.999} {
+		around, err = gowindams.BoundingBoxAround(gowindams.NewGeoPoint(10, lng), 1000)
+		if err != nil {
+			testing.Fatal("error:", err)
+		}
+		if around.MinLongitude < -180 || around.MaxLongitude > 180 {
+			testing.Fatalf("Expected the box around %f to be clamped to the antimeridian, got %+v", lng, around)
+		}
+		if !around.Contains(gowindams.NewGeoPoint(10, lng)) {
+			testing.Fatalf("Expected the box around %f to contain its center, got %+v", lng, around)
+		}
+	}
+}
+
+func TestPointInPolygon(testing *testing.T) {
+	square := []gowindams.GeoPoint{
+		gowindams.NewGeoPoint(0, 0),
+		gowindams.NewGeoPoint(0, 10),
+		gowindams.NewGeoPoint(10, 10),
+		gowindams.NewGeoPoint(10, 0),
+	}
+	if !gowindams.PointInPolygon(gowindams.NewGeoPoint(5, 5), square) {
+		testing.Fatal("Expected the center to be inside the polygon")
+	}
+	if gowindams.PointInPolygon(gowindams.NewGeoPoint(5, 15), square) {
+		testing.Fatal("Expected the point to be outside the polygon")
+	}
+	poly := gowindams.InspectionEventPolygon{Geometry: square}
+	if !poly.Contains(gowindams.NewGeoPoint(1, 9)) {
+		testing.Fatal("Expected the point to be inside the inspection event polygon")
+	}
+}
+
+func TestGeoJSONConversion(testing *testing.T) {
+	p := gowindams.NewGeoPoint(41.5, -93.6)
+	point, err := p.ToGeoJSON()
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	data, _ := json.Marshal(point)
+	compareStrings(testing, `{"type":"Point","coordinates":[-93.6,41.5]}`, string(data))
+	back, err := gowindams.GeoPointFromGeoJSON(point)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if *back.Latitude != 41.5 || *back.Longitude != -93.6 {
+		testing.Fatalf("Invalid round trip %s", back)
+	}
+
+	outline := []gowindams.GeoPoint{
+		gowindams.NewGeoPoint(0, 0),
+		gowindams.NewGeoPoint(0, 1),
+		gowindams.NewGeoPoint(1, 1),
+	}
+	polygon, err := gowindams.PolygonToGeoJSON(outline)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	data, _ = json.Marshal(polygon)
+	compareStrings(testing, `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`, string(data))
+	points, err := gowindams.PolygonFromGeoJSON(polygon)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if len(points) != 3 || *points[2].Latitude != 1 || *points[2].Longitude != 1 {
+		testing.Fatalf("Invalid polygon round trip %v", points)
+	}
+}
@@ -0,0 +1,115 @@
+package gowindams_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func TestGeoJSONWriter(testing *testing.T) {
+	id := "asset-1"
+	name := "T-01"
+	assetMake := "Vestas"
+	location := gowindams.NewGeoPoint(41.5, -93.6)
+	asset := gowindams.Asset{Id: &id, Name: &name, Make: &assetMake, Location: &location}
+
+	severity := int8(3)
+	polyName := "Crack"
+	orderNumber := "1234"
+	ier := gowindams.InspectionEventResource{
+		OrderNumber: &orderNumber,
+		Polygons: []gowindams.InspectionEventPolygon{
+			{
+				Name:     &polyName,
+				Severity: &severity,
+				Geometry: []gowindams.GeoPoint{point(10, 10), point(60, 10), point(60, 40)},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	gw := gowindams.NewGeoJSONWriter(&buf)
+	feature, err := gowindams.AssetFeature(&asset)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if err = gw.WriteFeature(feature); err != nil {
+		testing.Fatal("error:", err)
+	}
+	imageLocation := gowindams.NewGeoPoint(41.6, -93.5)
+	rmeta := gowindams.ResourceMetadata{Location: &imageLocation}
+	feature, err = gowindams.InspectionEventPolygonFeature(&ier, &ier.Polygons[0], &rmeta, &asset)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if err = gw.WriteFeature(feature); err != nil {
+		testing.Fatal("error:", err)
+	}
+	if err = gw.Close(); err != nil {
+		testing.Fatal("error:", err)
+	}
+
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Type     string `json:"type"`
+			Id       string `json:"id"`
+			Geometry struct {
+				Type string `json:"type"`
+			} `json:"geometry"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	if err = json.Unmarshal(buf.Bytes(), &collection); err != nil {
+		testing.Fatalf("Invalid GeoJSON %s: %s", buf.String(), err)
+	}
+	compareStrings(testing, "FeatureCollection", collection.Type)
+	if len(collection.Features) != 2 {
+		testing.Fatalf("Expected 2 features, got %d", len(collection.Features))
+	}
+	compareStrings(testing, "asset-1", collection.Features[0].Id)
+	compareStrings(testing, "Point", collection.Features[0].Geometry.Type)
+	compareStrings(testing, "Vestas", collection.Features[0].Properties["make"].(string))
+	// The polygon is placed where its image was taken.
+	compareStrings(testing, "Point", collection.Features[1].Geometry.Type)
+	if !bytes.Contains(buf.Bytes(), []byte("[-93.5,41.6]")) {
+		testing.Errorf("Expected the polygon at the image's location but got %s", buf.String())
+	}
+	compareStrings(testing, "T-01", collection.Features[1].Properties["assetName"].(string))
+	compareStrings(testing, "1234", collection.Features[1].Properties["orderNumber"].(string))
+	if collection.Features[1].Properties["severity"].(float64) != 3 {
+		testing.Fatalf("Invalid severity %v", collection.Features[1].Properties["severity"])
+	}
+}
+
+func TestInspectionEventPolygonFeatureLocation(testing *testing.T) {
+	ier := gowindams.InspectionEventResource{Polygons: []gowindams.InspectionEventPolygon{{Center: &gowindams.GeoPoint{}}}}
+	location := gowindams.NewGeoPoint(41.5, -93.6)
+	feature, err := gowindams.InspectionEventPolygonFeature(&ier, &ier.Polygons[0], &gowindams.ResourceMetadata{}, &gowindams.Asset{Location: &location})
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if point, ok := feature.Geometry.(gowindams.GeoJSONPoint); !ok || point.Coordinates[1] != 41.5 {
+		testing.Errorf("Expected the asset's location for an image without one but got %v", feature.Geometry)
+	}
+	if _, err = gowindams.InspectionEventPolygonFeature(&ier, &ier.Polygons[0], nil, &gowindams.Asset{}); err != gowindams.ErrMissingCoordinates {
+		testing.Errorf("Expected missing coordinates error, got %v", err)
+	}
+}
+
+func TestGeoJSONWriterEmpty(testing *testing.T) {
+	var buf bytes.Buffer
+	gw := gowindams.NewGeoJSONWriter(&buf)
+	if err := gw.Close(); err != nil {
+		testing.Fatal("error:", err)
+	}
+	compareStrings(testing, "{\"type\":\"FeatureCollection\",\"features\":[]}\n", buf.String())
+}
+
+func TestAssetFeatureWithoutLocation(testing *testing.T) {
+	if _, err := gowindams.AssetFeature(&gowindams.Asset{}); err != gowindams.ErrMissingCoordinates {
+		testing.Fatalf("Expected missing coordinates error, got %v", err)
+	}
+}
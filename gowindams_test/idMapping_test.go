@@ -0,0 +1,54 @@
+package gowindams_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func TestIdMappingResume(testing *testing.T) {
+	dir, err := ioutil.TempDir("", "idmapping")
+	if err != nil {
+		testing.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ids.ndjson")
+
+	ids, err := gowindams.OpenIdMapping(path)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	ids.Set(gowindams.EntityTypeSite, "s1", "t1")
+	ids.Set(gowindams.EntityTypeAsset, "a1", "t2")
+	ids.Close()
+
+	// Simulate a write cut off part way through a line
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"type":"Asset","source":"a2","tar`)
+	f.Close()
+
+	ids, err = gowindams.OpenIdMapping(path)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	ids.Set(gowindams.EntityTypeAsset, "a3", "t3")
+	ids.Close()
+	ids, _ = gowindams.OpenIdMapping(path)
+	defer ids.Close()
+	if id, ok := ids.Get(gowindams.EntityTypeAsset, "a3"); !ok || id != "t3" {
+		testing.Fatalf("Expected a3 recorded after the truncated entry to map to t3, got %q", id)
+	}
+	if id, ok := ids.Get(gowindams.EntityTypeAsset, "a1"); !ok || id != "t2" {
+		testing.Fatalf("Expected a1 to map to t2, got %q", id)
+	}
+	if _, ok := ids.Get(gowindams.EntityTypeAsset, "a2"); ok {
+		testing.Fatal("Expected the truncated entry to be ignored")
+	}
+	compareStrings(testing, "t1", *ids.Map(gowindams.EntityTypeSite, strPtr("s1")))
+	if ids.Map(gowindams.EntityTypeSite, strPtr("s2")) != nil || ids.Map(gowindams.EntityTypeSite, nil) != nil {
+		testing.Fatal("Expected unmapped ids to map to nil")
+	}
+}
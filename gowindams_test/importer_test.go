@@ -0,0 +1,197 @@
+package gowindams_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+const importCSV = "\ufeffTurbine,Serial No,Lat,Long,Installed,Hub Height,Notes\n" +
+	"WTG-01,SN-1,41.5,-93.25,2019-04-12,80,first\n" +
+	",,,,,,\n" +
+	"WTG-02,,41.6,x,43567,90,\n"
+
+func assetMapping() *gowindams.ImportMapping {
+	return &gowindams.ImportMapping{
+		EntityType: gowindams.EntityTypeAsset,
+		SiteId:     "s1",
+		MatchOn:    "name",
+		Columns: map[string]string{
+			"name":               "Turbine",
+			"serialNumber":       "serial no",
+			"location.latitude":  "Lat",
+			"location.longitude": "Long",
+			"dateOfInstall":      "Installed",
+		},
+		Defaults: map[string]string{"type": "Wind_Turbine"},
+		Ignore:   []string{"Notes"},
+	}
+}
+
+func TestImportRecordsFromCSV(testing *testing.T) {
+	table, err := gowindams.ReadCSVTable(strings.NewReader(importCSV))
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if len(table.Rows) != 2 {
+		testing.Fatalf("Expected blank rows to be skipped, got %d rows", len(table.Rows))
+	}
+	records, err := assetMapping().Records(table)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	asset := records[0].Asset
+	if records[0].Err != nil || asset == nil {
+		testing.Fatalf("Unexpected error for row 2: %v", records[0].Err)
+	}
+	compareStrings(testing, "WTG-01", *asset.Name)
+	compareStrings(testing, "SN-1", *asset.SerialNumber)
+	compareStrings(testing, "s1", *asset.SiteId)
+	compareStrings(testing, "Wind_Turbine", *asset.Type)
+	compareStrings(testing, "20190412", asset.DateOfInstall.String())
+	compareStrings(testing, "80", asset.Attributes["Hub Height"])
+	if *asset.Location.Latitude != 41.5 || *asset.Location.Longitude != -93.25 {
+		testing.Fatalf("Unexpected location %s", asset.Location)
+	}
+	if _, ok := asset.Attributes["Notes"]; ok {
+		testing.Fatal("Expected the ignored column to be left out")
+	}
+	if records[1].Row != 4 || records[1].Err == nil {
+		testing.Fatalf("Expected the invalid longitude on row 4 to be reported, got row %d: %v", records[1].Row, records[1].Err)
+	}
+}
+
+func TestImportMappingMissingColumn(testing *testing.T) {
+	table, _ := gowindams.ReadCSVTable(strings.NewReader("Name\nWTG-01\n"))
+	if _, err := assetMapping().Records(table); err == nil {
+		testing.Fatal("Expected an error for a mapped column missing from the header")
+	}
+}
+
+func TestImportComponentMapping(testing *testing.T) {
+	mapping := &gowindams.ImportMapping{
+		EntityType: gowindams.EntityTypeComponent,
+		MatchOn:    "type",
+		Columns:    map[string]string{"assetName": "Turbine", "type": "Blade", "serialNumber": "Serial"},
+	}
+	table, _ := gowindams.ReadCSVTable(strings.NewReader("Turbine,Blade,Serial,Length\nWTG-01,Blade_A,B-1,61.2\n,Blade_B,B-2,\n"))
+	records, err := mapping.Records(table)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	compareStrings(testing, "WTG-01", records[0].ParentValue)
+	compareStrings(testing, "61.2", records[0].Component.Attributes["Length"])
+	if records[1].Err == nil {
+		testing.Fatal("Expected an error for a component without an asset")
+	}
+}
+
+func TestReadXLSXTable(testing *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Notes" sheetId="1" r:id="rId1"/><sheet name="Turbines" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>Turbine</t></si><si><t>Serial</t></si><si><r><t>WTG</t></r><r><t>-01</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData/></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>` +
+			`<row r="3"><c r="A3" t="s"><v>2</v></c><c r="C3" t="inlineStr"><is><t>SN-1</t></is></c></row>` +
+			`</sheetData></worksheet>`,
+	}
+	for name, content := range parts {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	table, err := gowindams.ReadXLSXTableFrom(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "turbines")
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if len(table.Rows) != 1 {
+		testing.Fatalf("Expected 1 row, got %d", len(table.Rows))
+	}
+	compareStrings(testing, "WTG-01", table.Value(0, table.Column("Turbine")))
+	compareStrings(testing, "SN-1", table.Value(0, table.Column("Serial")))
+	if _, err = gowindams.ReadXLSXTableFrom(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "Missing"); err == nil {
+		testing.Fatal("Expected an error for a missing sheet")
+	}
+}
+
+func TestCompareFields(testing *testing.T) {
+	old := gowindams.Asset{Id: strPtr("a1"), Name: strPtr("WTG-01"), Make: strPtr("Vestas"), Attributes: map[string]string{"color": "white"}}
+	modified := old
+	modified.Make = strPtr("Siemens")
+	modified.Attributes = map[string]string{"color": "white", "hubHeight": "80"}
+	changes, err := gowindams.CompareFields(&old, &modified, []string{"id"})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if len(changes) != 2 {
+		testing.Fatalf("Expected 2 changes, got %v", changes)
+	}
+	compareStrings(testing, "attributes.hubHeight:  -> 80", changes[0].String())
+	compareStrings(testing, "make: Vestas -> Siemens", changes[1].String())
+}
+
+func TestImport(testing *testing.T) {
+	fake, env := newFakeServices(testing, "")
+	fake.put("asset", `{"id":"a1","siteId":"s1","name":"WTG-01","serialNumber":"SN-0","make":"Vestas","version":1}`)
+	table, err := gowindams.ReadCSVTable(strings.NewReader("Turbine,Serial No,Lat,Long,Installed,Hub Height,Notes\n" +
+		"WTG-01,SN-1,41.5,-93.25,2019-04-12,80,first\n" +
+		"WTG-03,SN-3,41.7,-93.3,2020-01-02,90,\n"))
+	if err != nil {
+		testing.Fatal(err)
+	}
+	actions := func(report *gowindams.ImportReport) string {
+		found := make([]string, 0)
+		for _, change := range report.Changes {
+			if change.Err != nil {
+				testing.Fatalf("Unexpected error for row %d: %s", change.Row, change.Err)
+			}
+			found = append(found, change.Action+" "+change.Key+" "+change.Id)
+		}
+		return strings.Join(found, ", ")
+	}
+
+	report, err := gowindams.Import(env, table, assetMapping(), &gowindams.ImportOptions{DryRun: true})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	compareStrings(testing, "update WTG-01 a1, create WTG-03 ", actions(report))
+	if writes := len(fake.calls("")) - len(fake.calls("POST /asset/search")); writes != 0 {
+		testing.Errorf("Expected a dry run to only search, got %v", fake.calls(""))
+	}
+	if !report.DryRun || len(fake.list("asset")) != 1 {
+		testing.Fatalf("Expected nothing to be saved by a dry run")
+	}
+
+	report, err = gowindams.Import(env, table, assetMapping(), nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	compareStrings(testing, "update WTG-01 a1, create WTG-03 asset-1", actions(report))
+	updated := fake.get("asset", "a1")
+	compareStrings(testing, "SN-1", updated["serialNumber"].(string))
+	compareStrings(testing, "Vestas", updated["make"].(string))
+	compareStrings(testing, "80", updated["attributes"].(map[string]interface{})["Hub Height"].(string))
+	created := fake.get("asset", "asset-1")
+	compareStrings(testing, "WTG-03", created["name"].(string))
+	compareStrings(testing, "s1", created["siteId"].(string))
+
+	fake.resetCalls()
+	report, err = gowindams.Import(env, table, assetMapping(), nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	compareStrings(testing, "unchanged WTG-01 a1, unchanged WTG-03 asset-1", actions(report))
+	if writes := len(fake.calls("")) - len(fake.calls("POST /asset/search")); writes != 0 {
+		testing.Errorf("Expected nothing to be saved importing unchanged rows, got %v", fake.calls(""))
+	}
+}
@@ -2,6 +2,7 @@ package gowindams_test
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 	"github.com/Inspectools/gowindams"
@@ -72,4 +73,61 @@ func TestUnMarshalResourceMetadata(testing *testing.T) {
 	if rmeta.Timestamp == nil {
 		testing.Fatal("invalid timestamp: ", rmeta.Timestamp)
 	}
+
+	expected := time.Date(2015, 12, 16, 22, 48, 19, 0, time.UTC)
+	if !rmeta.Timestamp.Time().Equal(expected) {
+		testing.Fatalf("Invalid timestamp %s, expected %s", rmeta.Timestamp, expected)
+	}
+}
+
+func TestMarshalSearchPage(testing *testing.T) {
+	siteId := "d326dda1-4b60-429e-bdf5-7ac41081613e"
+	criteria := gowindams.ResourceSearchCriteria{SiteId: &siteId}
+	data, err := json.Marshal(criteria)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if strings.Contains(string(data), "page") {
+		testing.Fatalf("Unexpected paging parameters in %s", string(data))
+	}
+
+	page := 2
+	pageSize := 100
+	criteria.Page = &page
+	criteria.PageSize = &pageSize
+	data, err = json.Marshal(criteria)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if !strings.Contains(string(data), `"page":2,"pageSize":100`) {
+		testing.Fatalf("Missing paging parameters in %s", string(data))
+	}
+}
+
+func TestMarshalSearchCriteriaRanges(testing *testing.T) {
+	from := gowindams.NewWindAMSDate(2018, 1, 1)
+	to := gowindams.NewWindAMSDate(2018, 12, 31)
+	criteria := gowindams.AssetInspectionSearchCriteria{
+		Statuses:             []string{gowindams.ASSET_INSPECTION_STATUS_PENDING, gowindams.ASSET_INSPECTION_STATUS_UPLOADED},
+		DateOfInspectionFrom: &from,
+		DateOfInspectionTo:   &to,
+	}
+	criteria.SortOn("dateOfInspection", gowindams.SortDescending)
+	data, err := json.Marshal(criteria)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	expected := []string{
+		`"sortBy":"dateOfInspection","sortDirection":"DESC"`,
+		`"statuses":["Pending","Uploaded"]`,
+		`"dateOfInspectionFrom":"20180101","dateOfInspectionTo":"20181231"`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(data), e) {
+			testing.Fatalf("Expected %s in %s", e, string(data))
+		}
+	}
+	if strings.Contains(string(data), "types") {
+		testing.Fatalf("Unexpected empty types in %s", string(data))
+	}
 }
@@ -0,0 +1,159 @@
+package gowindams_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func compareJSON(testing *testing.T, expected string, got []byte) {
+	var e, g interface{}
+	if err := json.Unmarshal([]byte(expected), &e); err != nil {
+		testing.Fatal(err)
+	}
+	if err := json.Unmarshal(got, &g); err != nil {
+		testing.Fatal(err)
+	}
+	if !reflect.DeepEqual(e, g) {
+		testing.Fatalf("Expected %s, got %s", expected, string(got))
+	}
+}
+
+func TestCreateMergePatch(testing *testing.T) {
+	original := gowindams.Asset{
+		Id:         strPtr("a1"),
+		Name:       strPtr("WTG-01"),
+		Make:       strPtr("Vestas"),
+		Attributes: map[string]string{"hubHeight": "80", "color": "white"},
+	}
+	modified := original
+	modified.Name = strPtr("WTG-01A")
+	modified.Make = nil
+	modified.Attributes = map[string]string{"hubHeight": "90", "color": "white"}
+
+	patch, err := gowindams.CreateMergePatch(&original, &modified)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	compareJSON(testing, `{"name":"WTG-01A","make":null,"attributes":{"hubHeight":"90"}}`, patch)
+
+	patch, err = gowindams.CreateMergePatch(&original, &original)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if !gowindams.IsEmptyMergePatch(patch) {
+		testing.Fatalf("Expected an empty patch, got %s", string(patch))
+	}
+}
+
+func TestApplyMergePatch(testing *testing.T) {
+	// Examples from RFC 7386 appendix A
+	cases := [][3]string{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+	}
+	for _, c := range cases {
+		got, err := gowindams.ApplyMergePatch([]byte(c[0]), []byte(c[1]))
+		if err != nil {
+			testing.Fatal(err)
+		}
+		compareJSON(testing, c[2], got)
+	}
+}
+
+func TestMergePatchRoundTrip(testing *testing.T) {
+	original := gowindams.WorkOrder{OrderNumber: strPtr("WO-1"), Description: strPtr("Blade inspection"), SiteId: strPtr("s1")}
+	modified := original
+	modified.SiteId = strPtr("s2")
+	modified.Description = nil
+	patch, err := gowindams.CreateMergePatch(&original, &modified)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	before, _ := json.Marshal(&original)
+	got, err := gowindams.ApplyMergePatch(before, patch)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	var patched gowindams.WorkOrder
+	if err = json.Unmarshal(got, &patched); err != nil {
+		testing.Fatal(err)
+	}
+	if !reflect.DeepEqual(patched, modified) {
+		testing.Fatalf("Expected %+v after patching, got %+v", modified, patched)
+	}
+}
+
+func TestPatchChanges(testing *testing.T) {
+	fake, env := newFakeServices(testing, "")
+	fake.put("asset", `{"id":"a1","name":"WTG-01","make":"Vestas","version":1}`)
+	var contentType, ifMatch string
+	var body []byte
+	fake.intercept = func(r *http.Request) int {
+		if r.Method == "PATCH" {
+			contentType, ifMatch = r.Header.Get("Content-Type"), r.Header.Get("If-Match")
+			body, _ = ioutil.ReadAll(r.Body)
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		return 0
+	}
+	client := env.AssetServiceClient()
+	original, err := client.Get("a1")
+	if err != nil {
+		testing.Fatal(err)
+	}
+	// The patched asset is decoded into modified, so it must not share the version with original.
+	modified, version := *original, *original.Version
+	modified.Version = &version
+	modified.Name = strPtr("WTG-01A")
+	if err = client.PatchChanges(original, &modified); err != nil {
+		testing.Fatal(err)
+	}
+	if calls := fake.calls("PATCH"); !reflect.DeepEqual(calls, []string{"PATCH /asset/a1"}) {
+		testing.Fatalf("Expected a single PATCH of the asset, got %v", calls)
+	}
+	compareStrings(testing, gowindams.MergePatchContentType, contentType)
+	compareStrings(testing, `"1"`, ifMatch)
+	compareJSON(testing, `{"name":"WTG-01A"}`, body)
+	if stored := fake.get("asset", "a1"); stored["name"] != "WTG-01A" || stored["make"] != "Vestas" {
+		testing.Fatalf("Expected only the name to be patched, got %v", stored)
+	}
+	if modified.Version == nil || *modified.Version != 2 {
+		testing.Fatalf("Expected the patched asset to be decoded, got version %v", modified.Version)
+	}
+
+	fake.resetCalls()
+	if err = client.PatchChanges(&modified, &modified); err != nil {
+		testing.Fatal(err)
+	}
+	if calls := fake.calls("PATCH"); len(calls) != 0 {
+		testing.Fatalf("Expected no request for an empty diff, got %v", calls)
+	}
+
+	stale := *original
+	stale.Make = strPtr("Siemens")
+	if err = client.PatchChanges(original, &stale); !gowindams.IsConflict(err) {
+		testing.Fatalf("Expected a conflict patching a stale asset, got %v", err)
+	}
+
+	result := new(gowindams.Asset)
+	if err = client.Patch("a1", map[string]interface{}{"make": nil}, result); err != nil {
+		testing.Fatal(err)
+	}
+	compareStrings(testing, "", ifMatch)
+	compareJSON(testing, `{"make":null}`, body)
+	if result.Make != nil || result.Name == nil || *result.Name != "WTG-01A" {
+		testing.Fatalf("Expected the make to be cleared, got %+v", result)
+	}
+}
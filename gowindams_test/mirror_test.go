@@ -0,0 +1,196 @@
+package gowindams_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+// Both the service clients and the mirror clients serve as readers.
+var (
+	_ gowindams.SiteReader                    = gowindams.SiteServiceClient{}
+	_ gowindams.SiteReader                    = gowindams.MirrorSiteClient{}
+	_ gowindams.WorkOrderReader               = gowindams.WorkOrderServiceClient{}
+	_ gowindams.WorkOrderReader               = gowindams.MirrorWorkOrderClient{}
+	_ gowindams.AssetReader                   = gowindams.AssetServiceClient{}
+	_ gowindams.AssetReader                   = gowindams.MirrorAssetClient{}
+	_ gowindams.ComponentReader               = gowindams.ComponentServiceClient{}
+	_ gowindams.ComponentReader               = gowindams.MirrorComponentClient{}
+	_ gowindams.AssetInspectionReader         = gowindams.AssetInspectionServiceClient{}
+	_ gowindams.AssetInspectionReader         = gowindams.MirrorAssetInspectionClient{}
+	_ gowindams.ComponentInspectionReader     = gowindams.ComponentInspectionServiceClient{}
+	_ gowindams.ComponentInspectionReader     = gowindams.MirrorComponentInspectionClient{}
+	_ gowindams.ResourceReader                = gowindams.ResourceServiceClient{}
+	_ gowindams.ResourceReader                = gowindams.MirrorResourceClient{}
+	_ gowindams.InspectionEventResourceReader = gowindams.InspectionEventResourceServiceClient{}
+	_ gowindams.InspectionEventResourceReader = gowindams.MirrorInspectionEventResourceClient{}
+)
+
+func TestMakeThumbnail(testing *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 400; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 40, B: 40, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		testing.Fatal(err)
+	}
+	data, err := gowindams.MakeThumbnail(&buf, 100)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if thumb.Bounds().Dx() != 100 || thumb.Bounds().Dy() != 25 {
+		testing.Errorf("Expected a 100x25 thumbnail but got %v", thumb.Bounds())
+	}
+	r, _, _, _ := thumb.At(50, 12).RGBA()
+	if r>>8 < 180 {
+		testing.Errorf("Expected the thumbnail to keep the image's colour but got red %d", r>>8)
+	}
+
+	if _, err = gowindams.MakeThumbnail(bytes.NewReader([]byte("not an image")), 100); err == nil {
+		testing.Errorf("Expected an error decoding something other than an image")
+	}
+}
+
+// A PNG in a single colour.
+func pngImage(testing *testing.T, width int, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 40, B: 40, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		testing.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func syncMirror(testing *testing.T, mirror *gowindams.Mirror, env *gowindams.Environment, options *gowindams.MirrorSyncOptions) *gowindams.MirrorSyncReport {
+	report, err := mirror.SyncSite(env, "s1", options)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	return report
+}
+
+func compareSyncReport(testing *testing.T, expected string, report *gowindams.MirrorSyncReport) {
+	compareStrings(testing, expected, fmt.Sprintf("added %v updated %v removed %v thumbnails %d errors %d",
+		report.Added, report.Updated, report.Removed, report.Thumbnails, report.ThumbnailErrors))
+}
+
+func TestMirrorSyncSite(testing *testing.T) {
+	fake, env := newBackupServices(testing)
+	fake.put("workOrder", `{"orderNumber":"WO-2","siteId":"s1"}`)
+	fake.put("assetInspection", `{"id":"ai2","assetId":"a1","siteId":"s1","orderNumber":"WO-2"}`)
+	fake.binaries["r1"] = pngImage(testing, 400, 100)
+	mirror, err := gowindams.OpenMirror(newFakeDatabase())
+	if err != nil {
+		testing.Fatal(err)
+	}
+	options := &gowindams.MirrorSyncOptions{Thumbnails: true, ThumbnailSize: 100}
+
+	report := syncMirror(testing, mirror, env, options)
+	compareSyncReport(testing, "added map[Asset:1 AssetInspection:2 Component:1 ComponentInspection:1 InspectionEventResource:1 "+
+		"Resource:1 Site:1 WorkOrder:2] updated map[] removed map[] thumbnails 1 errors 0", report)
+	data, err := mirror.Thumbnail("r1")
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if thumb, err := jpeg.Decode(bytes.NewReader(data)); err != nil || thumb.Bounds().Dx() != 100 {
+		testing.Errorf("Expected a thumbnail 100 pixels wide but got %v", err)
+	}
+
+	// Only what changed is written.  The thumbnail of the changed resource cannot be downloaded, which leaves the
+	// resource without one rather than with the old.
+	fake.put("asset", `{"id":"a1","siteId":"s1","name":"WTG-01A"}`)
+	fake.put("component", `{"id":"c2","assetId":"a1","siteId":"s1"}`)
+	fake.put("resource", `{"resourceId":"r1","siteId":"s1","assetId":"a1","componentId":"c1","assetInspectionId":"ai1","componentInspectionId":"ci1","contentType":"image/png","name":"IMG_0001"}`)
+	if err = env.InspectionEventResourceServiceClient().Delete("ier1"); err != nil {
+		testing.Fatal(err)
+	}
+	fake.intercept = func(r *http.Request) int {
+		if r.URL.Path == "/multimedia/r1" {
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	}
+	report = syncMirror(testing, mirror, env, options)
+	compareSyncReport(testing, "added map[Component:1] updated map[Asset:1 Resource:1] removed map[InspectionEventResource:1] thumbnails 0 errors 1", report)
+	if _, err = mirror.Thumbnail("r1"); !errors.Is(err, gowindams.ErrNotMirrored) {
+		testing.Errorf("Expected the out of date thumbnail to be removed but got %v", err)
+	}
+	asset, err := mirror.AssetServiceClient().Get("a1")
+	if err != nil {
+		testing.Fatal(err)
+	}
+	compareStrings(testing, "WTG-01A", *asset.Name)
+
+	// The next sync tries the thumbnail again.
+	fake.intercept = nil
+	report = syncMirror(testing, mirror, env, options)
+	compareSyncReport(testing, "added map[] updated map[] removed map[] thumbnails 1 errors 0", report)
+
+	// A sync of one order removes what went from that order and leaves the other order alone.
+	if err = env.WorkOrderServiceClient().Delete("WO-1"); err != nil {
+		testing.Fatal(err)
+	}
+	report = syncMirror(testing, mirror, env, &gowindams.MirrorSyncOptions{OrderNumber: strPtr("WO-1")})
+	compareSyncReport(testing, "added map[] updated map[] removed map[WorkOrder:1] thumbnails 0 errors 0", report)
+	if _, err = mirror.WorkOrderServiceClient().Get("WO-2"); err != nil {
+		testing.Errorf("Expected the other work order to be kept but got %v", err)
+	}
+	if _, err = mirror.ResourceServiceClient().Get("r1"); err != nil {
+		testing.Errorf("Expected the resource without an order number to be kept but got %v", err)
+	}
+
+	criteria := gowindams.AssetInspectionSearchCriteria{SiteId: strPtr("s1")}
+	criteria.SortOn("id", gowindams.SortDescending)
+	inspections, err := mirror.AssetInspectionServiceClient().Search(&criteria)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	ids := make([]string, 0)
+	for _, inspection := range inspections {
+		ids = append(ids, *inspection.Id)
+	}
+	compareStrings(testing, "ai2,ai1", strings.Join(ids, ","))
+
+	sites, err := mirror.Sites()
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if len(sites) != 1 || sites[0].Environment != "Test" || sites[0].OrderNumber != nil {
+		testing.Errorf("Expected the site to remain mirrored in full after syncing WO-1 but got %+v", sites)
+	}
+	if err = mirror.RemoveSite("s1"); err != nil {
+		testing.Fatal(err)
+	}
+	if _, err = mirror.SiteServiceClient().Get("s1"); !errors.Is(err, gowindams.ErrNotMirrored) {
+		testing.Errorf("Expected the site to be removed but got %v", err)
+	}
+
+	syncMirror(testing, mirror, env, &gowindams.MirrorSyncOptions{OrderNumber: strPtr("WO-2")})
+	if sites, err = mirror.Sites(); err != nil {
+		testing.Fatal(err)
+	}
+	if len(sites) != 1 || sites[0].OrderNumber == nil || *sites[0].OrderNumber != "WO-2" {
+		testing.Errorf("Expected the site synced for WO-2 but got %+v", sites)
+	}
+}
@@ -0,0 +1,251 @@
+package gowindams_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func TestOutboxRecordAndReopen(testing *testing.T) {
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		testing.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outbox, err := gowindams.OpenOutbox(dir)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	ci := &gowindams.ComponentInspection{ComponentId: strPtr("c1")}
+	if err = outbox.ComponentInspectionServiceClient().Create(ci); err != nil {
+		testing.Fatal(err)
+	}
+	if ci.Id == nil {
+		testing.Fatal("Expected the recorded inspection to be given an id")
+	}
+	ci.Description = strPtr("Leading edge erosion")
+	if err = outbox.ComponentInspectionServiceClient().Update(ci); err != nil {
+		testing.Fatal(err)
+	}
+	var body io.Reader = strings.NewReader("image bytes")
+	if err = outbox.ResourceServiceClient().Upload("r1", "image/jpeg", &body); err != nil {
+		testing.Fatal(err)
+	}
+	if err = outbox.Update(gowindams.EntityTypeComponentInspection, &gowindams.ComponentInspection{}); err == nil {
+		testing.Errorf("Expected an error recording an update without an id")
+	}
+	if err = outbox.Create(gowindams.EntityTypeSite, &gowindams.Site{}); err == nil {
+		testing.Errorf("Expected an error recording an entity type the outbox does not accept")
+	}
+	outbox.Close()
+
+	// Simulate a write cut off part way through a line
+	f, _ := os.OpenFile(filepath.Join(dir, "outbox.ndjson"), os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"id":"x","sequence":4,"oper`)
+	f.Close()
+
+	outbox, err = gowindams.OpenOutbox(dir)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	defer outbox.Close()
+	ops := outbox.Operations()
+	if len(ops) != 3 || outbox.Pending() != 3 {
+		testing.Fatalf("Expected 3 pending operations but got %+v", ops)
+	}
+	for i, expected := range []string{gowindams.OutboxOperationCreate, gowindams.OutboxOperationUpdate, gowindams.OutboxOperationUpload} {
+		compareStrings(testing, expected, ops[i].Operation)
+		if ops[i].Sequence != i+1 {
+			testing.Errorf("Expected sequence %d but got %d", i+1, ops[i].Sequence)
+		}
+	}
+	compareStrings(testing, *ci.Id, ops[1].EntityId)
+	if !bytes.Contains(ops[1].Entity, []byte("Leading edge erosion")) {
+		testing.Errorf("Expected the update to hold the modified inspection but got %s", ops[1].Entity)
+	}
+}
+
+// An outbox in a new directory, closed and removed at the end of the test.
+func openTestOutbox(testing *testing.T) (*gowindams.Outbox, string) {
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		testing.Fatal(err)
+	}
+	outbox, err := gowindams.OpenOutbox(dir)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	testing.Cleanup(func() {
+		outbox.Close()
+		os.RemoveAll(dir)
+	})
+	return outbox, dir
+}
+
+func TestOutboxReplay(testing *testing.T) {
+	fake, env := newFakeServices(testing, "  generateIds: true\n")
+	outbox, dir := openTestOutbox(testing)
+	outbox.ComponentInspectionServiceClient().Create(&gowindams.ComponentInspection{ComponentId: strPtr("c1")})
+	var body io.Reader = strings.NewReader("image bytes")
+	outbox.ResourceServiceClient().Upload("r1", "image/jpeg", &body)
+	fake.intercept = func(r *http.Request) int {
+		if r.Method == "PUT" {
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	}
+
+	// The create fails and the replay stops there.
+	report, err := outbox.Replay(env, nil)
+	if err == nil {
+		testing.Fatal("Expected the replay to stop at the failed create")
+	}
+	if len(report.Operations) != 1 || report.Count(gowindams.OutboxStatusPending) != 1 || report.Operations[0].LastError == "" {
+		testing.Errorf("Expected the create to remain pending with its error but got %s", report)
+	}
+	if fake.binary("r1") != nil || outbox.Pending() != 2 {
+		testing.Errorf("Expected nothing after the failed operation to be replayed")
+	}
+
+	report, err = outbox.Replay(env, &gowindams.ReplayOptions{MaxAttempts: 2})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if report.Count(gowindams.OutboxStatusFailed) != 1 || report.Count(gowindams.OutboxStatusSynced) != 1 {
+		testing.Errorf("Expected the create to be given up on and the upload synced but got %s", report)
+	}
+	compareStrings(testing, "image bytes", string(fake.binary("r1")))
+	if outbox.Pending() != 0 {
+		testing.Errorf("Expected no pending operations")
+	}
+
+	if err = outbox.Purge(); err != nil {
+		testing.Fatal(err)
+	}
+	if len(outbox.Operations()) != 0 {
+		testing.Errorf("Expected purging to remove finished operations")
+	}
+	uploads, _ := ioutil.ReadDir(filepath.Join(dir, "uploads"))
+	if len(uploads) != 0 {
+		testing.Errorf("Expected the uploaded content to be removed but found %d files", len(uploads))
+	}
+}
+
+func TestOutboxReplayCreatedIds(testing *testing.T) {
+	// The services assign the ids of created entities.
+	fake, env := newFakeServices(testing, "")
+	outbox, dir := openTestOutbox(testing)
+	ai := &gowindams.AssetInspection{SiteId: strPtr("s1")}
+	outbox.AssetInspectionServiceClient().Create(ai)
+	ci := &gowindams.ComponentInspection{ComponentId: strPtr("c1"), AssetInspectionId: ai.Id}
+	outbox.ComponentInspectionServiceClient().Create(ci)
+	rmeta := &gowindams.ResourceMetadata{ComponentInspectionId: ci.Id, ContentType: strPtr("image/jpeg")}
+	outbox.ResourceServiceClient().Save(rmeta)
+	ci.Resources = []string{*rmeta.ResourceId}
+	outbox.ComponentInspectionServiceClient().Update(ci)
+	var body io.Reader = strings.NewReader("image bytes")
+	outbox.ResourceServiceClient().Upload(*rmeta.ResourceId, "image/jpeg", &body)
+	outbox.InspectionEventResourceServiceClient().Save(&gowindams.InspectionEventResource{ResourceId: rmeta.ResourceId})
+
+	report, err := outbox.Replay(env, nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if report.Count(gowindams.OutboxStatusSynced) != 6 {
+		testing.Fatalf("Expected every operation to be synced but got %s", report)
+	}
+	if fake.get("assetInspection", *ai.Id) != nil {
+		testing.Errorf("Expected the outbox's id not to be sent to services which assign their own")
+	}
+	compareStrings(testing, "assetInspection-1", report.Operations[0].CreatedId)
+	saved := fake.list("componentInspection")
+	if len(saved) != 1 {
+		testing.Fatalf("Expected the update to apply to the created inspection but got %v", saved)
+	}
+	compareStrings(testing, "componentInspection-2 assetInspection-1 [resource-3]",
+		fmt.Sprint(saved[0]["id"], " ", saved[0]["assetInspectionId"], " ", saved[0]["resources"]))
+	compareStrings(testing, "componentInspection-2", fmt.Sprint(fake.get("resource", "resource-3")["componentInspectionId"]))
+	compareStrings(testing, "image bytes", string(fake.binary("resource-3")))
+	compareStrings(testing, "resource-3", fmt.Sprint(fake.list("inspectionEventResource")[0]["resourceId"]))
+
+	// The rewritten operations are kept.
+	outbox.Close()
+	outbox, err = gowindams.OpenOutbox(dir)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	defer outbox.Close()
+	compareStrings(testing, "resource-3", outbox.Operations()[4].EntityId)
+}
+
+func TestOutboxReplayConflicts(testing *testing.T) {
+	fake, env := newFakeServices(testing, "  generateIds: true\n")
+	record := func(outbox *gowindams.Outbox) {
+		// The create finds the inspection already exists and the update is against an old version.
+		outbox.ComponentInspectionServiceClient().Create(&gowindams.ComponentInspection{Id: strPtr("ci1"), ComponentId: strPtr("c1"), Description: strPtr("Recorded")})
+		version := int64(1)
+		outbox.ComponentInspectionServiceClient().Update(&gowindams.ComponentInspection{Id: strPtr("ci1"), ComponentId: strPtr("c1"), Description: strPtr("Updated"), Version: &version})
+	}
+	fake.put("componentInspection", `{"id":"ci1","componentId":"c1","description":"Theirs","version":2}`)
+
+	outbox, _ := openTestOutbox(testing)
+	record(outbox)
+	report, err := outbox.Replay(env, nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if report.Count(gowindams.OutboxStatusConflict) != 2 {
+		testing.Fatalf("Expected both operations to be skipped as conflicts but got %s", report)
+	}
+	if !strings.Contains(report.Operations[0].LastError, "status 409") || !strings.Contains(report.Operations[1].LastError, "status 412") {
+		testing.Errorf("Expected a 409 for the create and a 412 for the update but got %s", report)
+	}
+	compareStrings(testing, "Theirs", fmt.Sprint(fake.get("componentInspection", "ci1")["description"]))
+
+	outbox, _ = openTestOutbox(testing)
+	record(outbox)
+	report, err = outbox.Replay(env, &gowindams.ReplayOptions{OnConflict: gowindams.ConflictFail})
+	if !gowindams.IsConflict(err) || len(report.Operations) != 1 || outbox.Pending() != 2 {
+		testing.Errorf("Expected the replay to stop at the first conflict but got %v and %s", err, report)
+	}
+
+	report, err = outbox.Replay(env, &gowindams.ReplayOptions{OnConflict: gowindams.ConflictOverwrite})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if report.Count(gowindams.OutboxStatusSynced) != 2 {
+		testing.Errorf("Expected both operations to overwrite the server's copy but got %s", report)
+	}
+	saved := fake.get("componentInspection", "ci1")
+	compareStrings(testing, "Updated 4", fmt.Sprint(saved["description"], " ", saved["version"]))
+
+	// Two updates made offline to the same copy are each sent against the version the one before saved.
+	fake.put("componentInspection", `{"id":"ci2","componentId":"c1","description":"Loaded","version":1}`)
+	outbox, _ = openTestOutbox(testing)
+	version := int64(1)
+	offline := &gowindams.ComponentInspection{Id: strPtr("ci2"), ComponentId: strPtr("c1"), Version: &version}
+	for _, description := range []string{"First", "Second"} {
+		offline.Description = strPtr(description)
+		if err = outbox.ComponentInspectionServiceClient().Update(offline); err != nil {
+			testing.Fatal(err)
+		}
+	}
+	report, err = outbox.Replay(env, nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if report.Count(gowindams.OutboxStatusSynced) != 2 {
+		testing.Errorf("Expected both updates to be synced but got %s", report)
+	}
+	saved = fake.get("componentInspection", "ci2")
+	compareStrings(testing, "Second 3", fmt.Sprint(saved["description"], " ", saved["version"]))
+}
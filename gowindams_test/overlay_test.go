@@ -0,0 +1,142 @@
+package gowindams_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func point(x float64, y float64) gowindams.GeoPoint {
+	return gowindams.GeoPoint{Longitude: &x, Latitude: &y}
+}
+
+func TestRenderOverlay(testing *testing.T) {
+	grey := color.RGBA{R: 100, G: 100, B: 100, A: 255}
+	img := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 200; x++ {
+			img.SetRGBA(x, y, grey)
+		}
+	}
+	severity := int8(5)
+	polygons := []gowindams.InspectionEventPolygon{{
+		Name:     strPtr("Crack"),
+		Severity: &severity,
+		Geometry: []gowindams.GeoPoint{point(60, 60), point(140, 60), point(140, 140), point(60, 140)},
+	}}
+	rendered := gowindams.RenderOverlay(img, polygons)
+	red := color.RGBA{R: 215, G: 25, B: 28, A: 255}
+	if c := rendered.RGBAAt(60, 100); c != red {
+		testing.Errorf("Expected the outline in red but got %v", c)
+	}
+	if c := rendered.RGBAAt(100, 100); c.R <= grey.R || c.G >= grey.G || c == red {
+		testing.Errorf("Expected the inside tinted red but got %v", c)
+	}
+	if c := rendered.RGBAAt(20, 180); c != grey {
+		testing.Errorf("Expected the image untouched outside the polygon but got %v", c)
+	}
+	// The label sits above the polygon, in red on white.
+	var labelRed, labelWhite int
+	for y := 30; y < 60; y++ {
+		for x := 60; x < 120; x++ {
+			switch rendered.RGBAAt(x, y) {
+			case red:
+				labelRed++
+			case color.RGBA{R: 255, G: 255, B: 255, A: 255}:
+				labelWhite++
+			}
+		}
+	}
+	if labelRed == 0 || labelWhite == 0 {
+		testing.Errorf("Expected a label above the polygon")
+	}
+	if c := img.RGBAAt(60, 100); c != grey {
+		testing.Errorf("Expected the original image to be left alone")
+	}
+}
+
+func TestBackupResourceOverlay(testing *testing.T) {
+	dir, _ := ioutil.TempDir("", "overlay")
+	defer os.RemoveAll(dir)
+	backup, err := gowindams.OpenBackup(writeBackup(testing, dir, reportBackupFiles(), ""))
+	if err != nil {
+		testing.Fatal(err)
+	}
+	defer backup.Close()
+
+	var buf bytes.Buffer
+	if err = gowindams.BackupResourceOverlay(backup, "r1", &buf, nil); err != nil {
+		testing.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if img.Bounds().Dx() != 200 || img.Bounds().Dy() != 100 {
+		testing.Errorf("Expected the image at its original size but got %v", img.Bounds())
+	}
+	// The outline of the erosion, severity 4, runs along x = 60 between y = 10 and 40.
+	if r, g, b, _ := img.At(60, 25).RGBA(); r>>8 != 240 || g>>8 != 120 || b>>8 != 0 {
+		testing.Errorf("Expected the erosion outlined in orange but got %d,%d,%d", r>>8, g>>8, b>>8)
+	}
+
+	buf.Reset()
+	if err = gowindams.BackupResourceOverlay(backup, "r1", &buf, &gowindams.OverlayOptions{Format: gowindams.OverlayFormatJPEG}); err != nil {
+		testing.Fatal(err)
+	}
+	if _, err = jpeg.Decode(&buf); err != nil {
+		testing.Errorf("Expected a JPEG but got %v", err)
+	}
+
+	buf.Reset()
+	if err = gowindams.BackupResourceOverlay(backup, "r1", &buf, &gowindams.OverlayOptions{Format: gowindams.OverlayFormatSVG}); err != nil {
+		testing.Fatal(err)
+	}
+	svg := buf.String()
+	for _, expected := range []string{`viewBox="0 0 200 100"`, `xlink:href="data:image/png;base64,`, ">Erosion</text>", ">Crack</text>"} {
+		if !strings.Contains(svg, expected) {
+			testing.Errorf("Expected the SVG to contain %s", expected)
+		}
+	}
+
+	buf.Reset()
+	options := gowindams.OverlayOptions{Format: gowindams.OverlayFormatSVG, Href: "IMG_0001.png", OrderNumber: strPtr("WO-2")}
+	if err = gowindams.BackupResourceOverlay(backup, "r1", &buf, &options); err != nil {
+		testing.Fatal(err)
+	}
+	if svg = buf.String(); !strings.Contains(svg, `xlink:href="IMG_0001.png"`) || strings.Contains(svg, "<polygon") {
+		testing.Errorf("Expected the linked image without the damage of other work orders but got %s", svg)
+	}
+
+	if err = gowindams.BackupResourceOverlay(backup, "r1", &buf, &gowindams.OverlayOptions{Format: "gif"}); err == nil {
+		testing.Errorf("Expected an unknown format to be refused")
+	}
+}
+
+func TestSVGOverlayPixels(testing *testing.T) {
+	polygons := []gowindams.InspectionEventPolygon{
+		{Name: strPtr("Inside"), Geometry: []gowindams.GeoPoint{point(10, 10), point(50, 10), point(50, 40)}},
+		{Name: strPtr("Outside"), Geometry: []gowindams.GeoPoint{point(150, 10), point(250, 10), point(250, 40)}},
+		{Name: strPtr("Small"), Geometry: []gowindams.GeoPoint{point(0.5, 0.5), point(0.6, 0.5), point(0.6, 0.7)}},
+	}
+	var buf bytes.Buffer
+	if err := gowindams.WriteSVGOverlay(&buf, "", 200, 100, polygons); err != nil {
+		testing.Fatal(err)
+	}
+	svg := buf.String()
+	if !strings.Contains(svg, ">Inside</text>") || strings.Contains(svg, ">Outside</text>") {
+		testing.Errorf("Expected only the polygon within the image to be drawn but got %s", svg)
+	}
+	// Coordinates are always pixels, however small.
+	if !strings.Contains(svg, `points="0.5,0.5 0.6,0.5 0.6,0.7"`) {
+		testing.Errorf("Expected the small polygon drawn at its pixel coordinates but got %s", svg)
+	}
+}
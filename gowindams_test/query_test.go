@@ -0,0 +1,198 @@
+package gowindams_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Inspectools/gowindams"
+)
+
+const QUERY_TEST_FILE = "query_test.yaml"
+
+func loadQueryEnvironments(testing *testing.T) (*gowindams.Environment, *gowindams.Environment) {
+	environments, err := gowindams.LoadEnvironments(QUERY_TEST_FILE)
+	if err != nil {
+		testing.Fatalf("Unable to open test file: %s\n", err)
+	}
+	legacy := environments.Find("Legacy")
+	current := environments.Find("Current")
+	if legacy == nil || current == nil {
+		testing.Fatal("Unable to find the test environments")
+	}
+	return legacy, current
+}
+
+func TestSearchFeatures(testing *testing.T) {
+	legacy, current := loadQueryEnvironments(testing)
+	if legacy.SupportsSearchFeature(gowindams.SearchFeatureSort) {
+		testing.Fatal("The legacy environment should not support sorting")
+	}
+	if !current.SupportsSearchFeature(gowindams.SearchFeatureSort) {
+		testing.Fatal("The current environment should support sorting")
+	}
+}
+
+func TestAssetQueryFallback(testing *testing.T) {
+	legacy, current := loadQueryEnvironments(testing)
+	build := func(env *gowindams.Environment) *gowindams.AssetQuery {
+		return env.AssetServiceClient().Query().
+			Site("site-1").
+			Types(gowindams.ASSET_TYPE_WIND_TURBINE, gowindams.ASSET_TYPE_SOLAR_PANEL).
+			NamePrefix("T-").
+			Make("Vestas").
+			Attribute("hubHeight", "80")
+	}
+
+	asset := gowindams.Asset{
+		Name:       strPtr("T-01"),
+		Make:       strPtr("Vestas"),
+		Type:       strPtr(gowindams.ASSET_TYPE_WIND_TURBINE),
+		Attributes: map[string]string{"hubHeight": "80"},
+	}
+	other := gowindams.Asset{
+		Name:       strPtr("Met Tower"),
+		Make:       strPtr("Vestas"),
+		Type:       strPtr(gowindams.ASSET_TYPE_WIND_TURBINE),
+		Attributes: map[string]string{"hubHeight": "80"},
+	}
+
+	q := build(legacy)
+	c := q.Criteria()
+	if c.NamePrefix != nil || c.AssetTypes != nil || c.AssetType != nil {
+		testing.Fatalf("Unsupported filters sent to a legacy environment: %+v", c)
+	}
+	compareStrings(testing, "site-1", *c.SiteId)
+	if !q.Matches(&asset) || q.Matches(&other) {
+		testing.Fatal("Name prefix not applied locally for the legacy environment")
+	}
+
+	q = build(current)
+	c = q.Criteria()
+	if c.NamePrefix == nil || len(c.AssetTypes) != 2 {
+		testing.Fatalf("Supported filters not sent to the current environment: %+v", c)
+	}
+	if !q.Matches(&other) {
+		testing.Fatal("Name prefix applied locally for the current environment")
+	}
+	noMake := asset
+	noMake.Make = strPtr("Siemens")
+	if q.Matches(&noMake) {
+		testing.Fatal("Make must always be applied locally")
+	}
+}
+
+func TestSingleValueQuery(testing *testing.T) {
+	legacy, _ := loadQueryEnvironments(testing)
+	c := legacy.AssetInspectionServiceClient().Query().Statuses(gowindams.ASSET_INSPECTION_STATUS_PENDING).Criteria()
+	if c.Status == nil || *c.Status != gowindams.ASSET_INSPECTION_STATUS_PENDING || c.Statuses != nil {
+		testing.Fatalf("Expected a single status to use the status field: %+v", c)
+	}
+}
+
+func TestDateRangeQueryFallback(testing *testing.T) {
+	legacy, current := loadQueryEnvironments(testing)
+	from := gowindams.NewWindAMSDate(2018, 1, 1)
+	to := gowindams.NewWindAMSDate(2018, 6, 30)
+	inside := gowindams.NewWindAMSDate(2018, 6, 30)
+	outside := gowindams.NewWindAMSDate(2018, 7, 1)
+
+	q := legacy.WorkOrderServiceClient().Query().RequestedBetween(&from, &to)
+	if q.Criteria().RequestDateFrom != nil {
+		testing.Fatal("Date range sent to a legacy environment")
+	}
+	if !q.Matches(&gowindams.WorkOrder{RequestDate: &inside}) || q.Matches(&gowindams.WorkOrder{RequestDate: &outside}) {
+		testing.Fatal("Date range not applied locally")
+	}
+
+	q = current.WorkOrderServiceClient().Query().RequestedBetween(&from, &to)
+	if q.Criteria().RequestDateFrom == nil || !q.Matches(&gowindams.WorkOrder{RequestDate: &outside}) {
+		testing.Fatal("Date range not sent to the current environment")
+	}
+}
+
+func findAssetNames(testing *testing.T, q *gowindams.AssetQuery) string {
+	assets, err := q.Find()
+	if err != nil {
+		testing.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, asset := range assets {
+		names = append(names, *asset.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestQueryFind(testing *testing.T) {
+	fake, env := newFakeServices(testing, "")
+	fake.put("asset",
+		`{"id":"a1","siteId":"s1","name":"T-03","make":"Vestas"}`,
+		`{"id":"a2","siteId":"s1","name":"Met Tower","make":"Vestas"}`,
+		`{"id":"a3","siteId":"s1","name":"T-01","make":"Siemens"}`,
+		`{"id":"a4","siteId":"s1","name":"T-02","make":"Vestas"}`,
+		`{"id":"a5","siteId":"s2","name":"T-04","make":"Vestas"}`)
+
+	// Without the namePrefix and sort features both are applied locally, and so is the paging.
+	q := env.AssetServiceClient().Query().Site("s1").NamePrefix("T-").OrderBy("name", gowindams.SortAscending)
+	compareStrings(testing, "T-01,T-02,T-03", findAssetNames(testing, q))
+	compareStrings(testing, "T-03", findAssetNames(testing, q.Page(1, 2)))
+	compareStrings(testing, "T-02", findAssetNames(testing, q.Make("Vestas").Page(0, 1)))
+	if c := q.Criteria(); c.Page != nil || c.SortBy != nil {
+		testing.Errorf("Expected paging and sorting to be left to the client but got %+v", c)
+	}
+
+	// With nothing to do locally the services page the results.
+	q = env.AssetServiceClient().Query().Site("s1").Page(1, 3)
+	compareStrings(testing, "T-02", findAssetNames(testing, q))
+	if c := q.Criteria(); c.Page == nil || *c.Page != 1 || *c.PageSize != 3 {
+		testing.Errorf("Expected the page to be sent to the services but got %+v", c)
+	}
+
+	if _, err := env.AssetServiceClient().Query().OrderBy("height", gowindams.SortAscending).Find(); err == nil {
+		testing.Errorf("Expected an error sorting on an unknown field")
+	}
+}
+
+func TestSearchCriteriaMatches(testing *testing.T) {
+	asset := &gowindams.Asset{Id: strPtr("a1"), SiteId: strPtr("s1"), Name: strPtr("T-101"), Type: strPtr(gowindams.ASSET_TYPE_WIND_TURBINE)}
+	for _, test := range []struct {
+		criteria gowindams.AssetSearchCriteria
+		expected bool
+	}{
+		{gowindams.AssetSearchCriteria{}, true},
+		{gowindams.AssetSearchCriteria{SiteId: strPtr("s1"), NamePrefix: strPtr("T-1")}, true},
+		{gowindams.AssetSearchCriteria{SiteId: strPtr("s2")}, false},
+		{gowindams.AssetSearchCriteria{AssetTypes: []string{gowindams.ASSET_TYPE_SOLAR_PANEL}}, false},
+		{gowindams.AssetSearchCriteria{AssetTypes: []string{gowindams.ASSET_TYPE_SOLAR_PANEL, gowindams.ASSET_TYPE_WIND_TURBINE}}, true},
+		{gowindams.AssetSearchCriteria{SerialNumber: strPtr("x")}, false},
+	} {
+		if got := test.criteria.Matches(asset); got != test.expected {
+			testing.Errorf("Expected %v matching %+v but got %v", test.expected, test.criteria, got)
+		}
+	}
+
+	ts := gowindams.WindAMSTime(time.Now())
+	ci := &gowindams.ComponentInspection{
+		OrderNumber:   strPtr("WO-1"),
+		StatusHistory: []gowindams.StatusEvent{{Status: strPtr(gowindams.COMP_INSPECTION_STATUS_APPROVED), Timestamp: &ts}},
+	}
+	criteria := gowindams.ComponentInspectionSearchCriteria{OrderNumber: strPtr("WO-1"), Status: strPtr(gowindams.COMP_INSPECTION_STATUS_APPROVED)}
+	if !criteria.Matches(ci) {
+		testing.Errorf("Expected the inspection to match on its current status")
+	}
+	criteria.Status = strPtr(gowindams.COMP_INSPECTION_STATUS_LOCKED)
+	if criteria.Matches(ci) {
+		testing.Errorf("Expected the inspection not to match another status")
+	}
+
+	from := gowindams.WindAMSDate(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	inJanuary := gowindams.WindAMSDate(time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC))
+	wo := &gowindams.WorkOrder{RequestDate: &inJanuary, Scope: strPtr(gowindams.WORK_ORDER_SCOPE_ANNUAL)}
+	woCriteria := gowindams.WorkOrderSearchCriteria{RequestDateFrom: &from, Scopes: []string{gowindams.WORK_ORDER_SCOPE_ANNUAL}}
+	if !woCriteria.Matches(wo) {
+		testing.Errorf("Expected the work order to match its scope and request date")
+	}
+	if woCriteria.Matches(&gowindams.WorkOrder{Scope: strPtr(gowindams.WORK_ORDER_SCOPE_ANNUAL)}) {
+		testing.Errorf("Expected a work order without a request date not to match a date range")
+	}
+}
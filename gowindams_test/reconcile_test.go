@@ -0,0 +1,113 @@
+package gowindams_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func inspectionWithStatus(status string) *gowindams.ComponentInspection {
+	ts := gowindams.WindAMSTime(time.Now())
+	return &gowindams.ComponentInspection{
+		StatusHistory: []gowindams.StatusEvent{{Status: strPtr(status), Timestamp: &ts}},
+	}
+}
+
+func TestExpectedAssetInspectionStatus(testing *testing.T) {
+	compareStrings(testing, gowindams.ASSET_INSPECTION_STATUS_PENDING, gowindams.ExpectedAssetInspectionStatus(nil, 0))
+	compareStrings(testing, gowindams.ASSET_INSPECTION_STATUS_UPLOADED, gowindams.ExpectedAssetInspectionStatus(
+		[]*gowindams.ComponentInspection{{}}, 3))
+	compareStrings(testing, gowindams.ASSET_INSPECTION_STATUS_IN_PROCESS, gowindams.ExpectedAssetInspectionStatus(
+		[]*gowindams.ComponentInspection{inspectionWithStatus(gowindams.COMP_INSPECTION_STATUS_APPROVED), {}}, 3))
+	compareStrings(testing, gowindams.ASSET_INSPECTION_STATUS_PROCESSED, gowindams.ExpectedAssetInspectionStatus(
+		[]*gowindams.ComponentInspection{
+			inspectionWithStatus(gowindams.COMP_INSPECTION_STATUS_APPROVED),
+			inspectionWithStatus(gowindams.COMP_INSPECTION_STATUS_TRANSMITTED),
+		}, 3))
+	compareStrings(testing, gowindams.ASSET_INSPECTION_STATUS_RELEASED, gowindams.ExpectedAssetInspectionStatus(
+		[]*gowindams.ComponentInspection{inspectionWithStatus(gowindams.COMP_INSPECTION_STATUS_TRANSMITTED)}, 0))
+}
+
+func TestReconcileSiteTree(testing *testing.T) {
+	assets := []gowindams.Asset{{Id: strPtr("a1"), SiteId: strPtr("s1")}}
+	components := []gowindams.Component{{Id: strPtr("c1"), AssetId: strPtr("a1")}}
+	ais := []gowindams.AssetInspection{
+		{Id: strPtr("ai1"), AssetId: strPtr("a1"), Status: strPtr(gowindams.ASSET_INSPECTION_STATUS_PENDING)},
+		{Id: strPtr("ai2"), AssetId: strPtr("a1"), Status: strPtr(gowindams.ASSET_INSPECTION_STATUS_PENDING)},
+	}
+	cis := []gowindams.ComponentInspection{*inspectionWithStatus(gowindams.COMP_INSPECTION_STATUS_STARTED)}
+	cis[0].Id = strPtr("ci1")
+	cis[0].ComponentId = strPtr("c1")
+	cis[0].AssetInspectionId = strPtr("ai1")
+	tree := gowindams.BuildSiteTree(&gowindams.Site{Id: strPtr("s1")}, assets, components, ais, cis, nil)
+
+	mismatches := gowindams.ReconcileSiteTree(tree)
+	if len(mismatches) != 1 {
+		testing.Fatalf("Expected 1 mismatch, got %d", len(mismatches))
+	}
+	compareStrings(testing, "ai1", *mismatches[0].AssetInspection.Id)
+	compareStrings(testing, gowindams.ASSET_INSPECTION_STATUS_IN_PROCESS, mismatches[0].Expected)
+}
+
+func TestReconcileOrder(testing *testing.T) {
+	fake, env := newBackupServices(testing)
+	fake.put("resource", `{"resourceId":"r1","siteId":"s1","assetId":"a1","componentId":"c1","assetInspectionId":"ai1","componentInspectionId":"ci1","orderNumber":"WO-1"}`)
+	fake.put("assetInspection",
+		`{"id":"ai2","assetId":"a1","siteId":"s1","orderNumber":"WO-2","status":"Released"}`,
+		`{"id":"ai3","assetId":"a1","siteId":"s1","orderNumber":"WO-1","status":"Processed"}`)
+	// ai3 is released by someone else once the tree has been loaded
+	fake.intercept = func(r *http.Request) int {
+		if r.Method == "GET" && r.URL.Path == "/assetInspection/ai3" {
+			fake.get("assetInspection", "ai3")["status"] = gowindams.ASSET_INSPECTION_STATUS_RELEASED
+		}
+		return 0
+	}
+	mismatches, err := gowindams.ReconcileOrder(env, "WO-1", &gowindams.ReconcileOptions{Update: true})
+	if err != nil {
+		testing.Fatal(err)
+	}
+	if len(mismatches) != 2 {
+		testing.Fatalf("Expected the two inspections under the order to be out of step, got %v", mismatches)
+	}
+	for _, m := range mismatches {
+		switch *m.AssetInspection.Id {
+		case "ai1":
+			compareStrings(testing, gowindams.ASSET_INSPECTION_STATUS_UPLOADED, m.Expected)
+			if !m.Updated || m.Err != nil {
+				testing.Errorf("Expected ai1 to be updated, got %+v", m)
+			}
+		case "ai3":
+			compareStrings(testing, gowindams.ASSET_INSPECTION_STATUS_PENDING, m.Expected)
+			if m.Updated || m.Err != gowindams.ErrStatusChanged {
+				testing.Errorf("Expected ai3 to be skipped as its status changed, got %+v", m)
+			}
+		default:
+			testing.Errorf("Expected only inspections under the order, got %v", m)
+		}
+	}
+	compareStrings(testing, gowindams.ASSET_INSPECTION_STATUS_UPLOADED, fake.get("assetInspection", "ai1")["status"].(string))
+	compareStrings(testing, gowindams.ASSET_INSPECTION_STATUS_RELEASED, fake.get("assetInspection", "ai3")["status"].(string))
+	if updates := fake.calls("POST /assetInspection"); len(updates) != 1+len(fake.calls("POST /assetInspection/search")) {
+		testing.Errorf("Expected only ai1 to be updated, got %v", updates)
+	}
+
+	// Across the site, only the inspections still out of step are found, and nothing is saved without Update
+	fake.intercept = nil
+	fake.resetCalls()
+	mismatches, err = gowindams.ReconcileSite(env, "s1", nil)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	found := make([]string, 0)
+	for _, m := range mismatches {
+		found = append(found, m.String())
+	}
+	if len(found) != 2 || found[0] != "ai2: Released, expected Pending" || found[1] != "ai3: Released, expected Pending" {
+		testing.Errorf("Expected ai2 and ai3 to be out of step, got %v", found)
+	}
+	if updates := len(fake.calls("POST /assetInspection")) - len(fake.calls("POST /assetInspection/search")); updates != 0 {
+		testing.Errorf("Expected no updates without Update, got %d", updates)
+	}
+}
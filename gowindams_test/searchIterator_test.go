@@ -0,0 +1,87 @@
+package gowindams_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func iterateAssetNames(testing *testing.T, it *gowindams.AssetIterator) string {
+	names := make([]string, 0)
+	for it.Next() {
+		names = append(names, *it.Value().Name)
+	}
+	if it.Err() != nil {
+		testing.Fatal(it.Err())
+	}
+	return strings.Join(names, ",")
+}
+
+func TestIteratePages(testing *testing.T) {
+	fake, env := newFakeServices(testing, "")
+	for i := 1; i <= 5; i++ {
+		fake.put("asset", fmt.Sprintf(`{"id":"a%d","siteId":"s1","name":"WTG-%d"}`, i, i))
+	}
+	fake.put("asset", `{"id":"a6","siteId":"s2","name":"Elsewhere"}`)
+	siteId := "s1"
+
+	// Two full pages then a short one, which ends the iteration.
+	it := env.AssetServiceClient().Iterate(context.Background(), &gowindams.AssetSearchCriteria{SiteId: &siteId}, 2)
+	compareStrings(testing, "WTG-1,WTG-2,WTG-3,WTG-4,WTG-5", iterateAssetNames(testing, it))
+	if searches := fake.calls("POST /asset/search"); len(searches) != 3 {
+		testing.Errorf("Expected 3 pages to be fetched but got %d", len(searches))
+	}
+
+	// When the results fill the last page exactly, an empty page ends the iteration.
+	fake.resetCalls()
+	it = env.AssetServiceClient().Iterate(context.Background(), &gowindams.AssetSearchCriteria{SiteId: &siteId}, 5)
+	compareStrings(testing, "WTG-1,WTG-2,WTG-3,WTG-4,WTG-5", iterateAssetNames(testing, it))
+	if searches := fake.calls("POST /asset/search"); len(searches) != 2 {
+		testing.Errorf("Expected 2 pages to be fetched but got %d", len(searches))
+	}
+}
+
+func TestIterateIgnoredPaging(testing *testing.T) {
+	fake, env := newFakeServices(testing, "")
+	pages := make([]int, 0)
+	// A service which ignores the paging parameters and returns everything for every page.
+	fake.routes["POST /asset/search"] = func(w http.ResponseWriter, r *http.Request) {
+		var criteria gowindams.AssetSearchCriteria
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &criteria)
+		pages = append(pages, *criteria.Page)
+		w.Write([]byte(`[{"id":"a1","name":"WTG-1"},{"id":"a2","name":"WTG-2"}]`))
+	}
+	it := env.AssetServiceClient().Iterate(context.Background(), nil, 2)
+	compareStrings(testing, "WTG-1,WTG-2", iterateAssetNames(testing, it))
+	if fmt.Sprint(pages) != "[0 1]" {
+		testing.Errorf("Expected the iteration to stop when the second page repeats the first but fetched pages %v", pages)
+	}
+}
+
+func TestIterateError(testing *testing.T) {
+	fake, env := newFakeServices(testing, "")
+	for i := 1; i <= 3; i++ {
+		fake.put("asset", fmt.Sprintf(`{"id":"a%d","name":"WTG-%d"}`, i, i))
+	}
+	fake.intercept = func(r *http.Request) int {
+		if len(fake.calls("POST /asset/search")) > 1 {
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	}
+	it := env.AssetServiceClient().Iterate(context.Background(), nil, 2)
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if count != 2 || it.Err() == nil {
+		testing.Errorf("Expected the first page and then an error but got %d results and %v", count, it.Err())
+	}
+}
@@ -0,0 +1,352 @@
+package gowindams_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Inspectools/gowindams"
+)
+
+// An in-memory stand-in for the services, for testing the clients over HTTP.  Entities are held as decoded JSON by
+// collection, the first element of the URL path.  Creates assign an id when there is none and fail with a conflict
+// when the id is taken, and updates and merge patches check If-Match against the stored version.
+type fakeServices struct {
+	testing  *testing.T
+	server   *httptest.Server
+	mu       sync.Mutex
+	entities map[string]map[string]map[string]interface{}
+	order    map[string][]string
+	binaries map[string][]byte
+	requests []string
+	nextId   int
+	// When set, called before each request is handled.  A non-zero status is returned in place of handling it.
+	intercept func(r *http.Request) int
+	// Handlers replacing the fake's own for requests, by "METHOD /path".
+	routes map[string]http.HandlerFunc
+}
+
+// Collections whose entities are not identified by an "id" field.
+var fakeIdFields = map[string]string{"resource": "resourceId", "workOrder": "orderNumber"}
+
+// Collections whose entities are saved with a POST whether or not they exist.
+var fakeSavedCollections = map[string]bool{"resource": true, "inspectionEventResource": true}
+
+// Starts the services and loads an environment for them.  The environment authenticates against the same server,
+// whose certificate is trusted for the duration of the test.  config holds further YAML lines for the environment.
+func newFakeServices(testing *testing.T, config string) (*fakeServices, *gowindams.Environment) {
+	fake := &fakeServices{
+		testing:  testing,
+		entities: make(map[string]map[string]map[string]interface{}),
+		order:    make(map[string][]string),
+		binaries: make(map[string][]byte),
+		routes:   make(map[string]http.HandlerFunc),
+	}
+	fake.server = httptest.NewTLSServer(http.HandlerFunc(fake.serve))
+	transport := http.DefaultTransport
+	http.DefaultTransport = fake.server.Client().Transport
+	testing.Cleanup(func() {
+		http.DefaultTransport = transport
+		fake.server.Close()
+	})
+
+	dir, err := ioutil.TempDir("", "services")
+	if err != nil {
+		testing.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "environments.yaml")
+	// Tokens are cached by service app id, so each server gets its own.
+	yaml := fmt.Sprintf("- name: Test\n  serviceURI: %s\n  accessTokenProvider: auth0\n  tenantId: %s\n  clientId: test\n  clientSecret: secret\n  serviceAppId: %s\n%s",
+		fake.server.URL, strings.TrimPrefix(fake.server.URL, "https://"), fake.server.URL, config)
+	if err = ioutil.WriteFile(path, []byte(yaml), 0644); err != nil {
+		testing.Fatal(err)
+	}
+	environments, err := gowindams.LoadEnvironments(path)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	return fake, environments.Find("Test")
+}
+
+func (fake *fakeServices) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/oauth/token" {
+		fmt.Fprintf(w, `{"access_token":"token","expires_on":%d}`, time.Now().Add(time.Hour).Unix())
+		return
+	}
+	fake.mu.Lock()
+	fake.requests = append(fake.requests, r.Method+" "+r.URL.Path)
+	intercept, route := fake.intercept, fake.routes[r.Method+" "+r.URL.Path]
+	fake.mu.Unlock()
+	if intercept != nil {
+		if status := intercept(r); status != 0 {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+	}
+	if route != nil {
+		route(w, r)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	collection := parts[0]
+	switch {
+	case collection == "multimedia" && len(parts) == 2 && r.Method == "POST":
+		fake.binaries[parts[1]] = body
+	case collection == "multimedia" && len(parts) == 2 && r.Method == "GET":
+		data, ok := fake.binaries[parts[1]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	case len(parts) == 1 && r.Method == "PUT":
+		fake.create(w, collection, body)
+	case len(parts) == 1 && r.Method == "POST":
+		fake.update(w, r, collection, body)
+	case len(parts) == 2 && parts[1] == "search" && r.Method == "POST":
+		fake.search(w, collection, body)
+	case len(parts) == 2 && r.Method == "GET":
+		obj, ok := fake.entities[collection][parts[1]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(obj)
+	case len(parts) == 2 && r.Method == "PATCH":
+		fake.patch(w, r, collection, parts[1], body)
+	case len(parts) == 2 && r.Method == "DELETE":
+		if _, ok := fake.entities[collection][parts[1]]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(fake.entities[collection], parts[1])
+		for i, id := range fake.order[collection] {
+			if id == parts[1] {
+				fake.order[collection] = append(fake.order[collection][:i], fake.order[collection][i+1:]...)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func idField(collection string) string {
+	if field, ok := fakeIdFields[collection]; ok {
+		return field
+	}
+	return "id"
+}
+
+func (fake *fakeServices) store(collection string, obj map[string]interface{}) {
+	id := fmt.Sprint(obj[idField(collection)])
+	if fake.entities[collection] == nil {
+		fake.entities[collection] = make(map[string]map[string]interface{})
+	}
+	if _, ok := fake.entities[collection][id]; !ok {
+		fake.order[collection] = append(fake.order[collection], id)
+	}
+	fake.entities[collection][id] = obj
+}
+
+func (fake *fakeServices) create(w http.ResponseWriter, collection string, body []byte) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	field := idField(collection)
+	if obj[field] == nil {
+		fake.nextId++
+		obj[field] = fmt.Sprintf("%s-%d", collection, fake.nextId)
+	} else if _, ok := fake.entities[collection][fmt.Sprint(obj[field])]; ok {
+		http.Error(w, "Already exists", http.StatusConflict)
+		return
+	}
+	obj["version"] = float64(1)
+	fake.store(collection, obj)
+	json.NewEncoder(w).Encode(obj)
+}
+
+func (fake *fakeServices) update(w http.ResponseWriter, r *http.Request, collection string, body []byte) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	field := idField(collection)
+	existing, ok := fake.entities[collection][fmt.Sprint(obj[field])]
+	if !ok && fakeSavedCollections[collection] {
+		if obj[field] == nil {
+			fake.nextId++
+			obj[field] = fmt.Sprintf("%s-%d", collection, fake.nextId)
+		}
+		existing = map[string]interface{}{}
+	} else if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	version, _ := existing["version"].(float64)
+	if match := r.Header.Get("If-Match"); match != "" && match != fmt.Sprintf("\"%d\"", int64(version)) {
+		http.Error(w, "Version mismatch", http.StatusPreconditionFailed)
+		return
+	}
+	obj["version"] = version + 1
+	fake.store(collection, obj)
+	json.NewEncoder(w).Encode(obj)
+}
+
+func (fake *fakeServices) patch(w http.ResponseWriter, r *http.Request, collection string, id string, body []byte) {
+	existing, ok := fake.entities[collection][id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Header.Get("Content-Type") != gowindams.MergePatchContentType {
+		http.Error(w, "Not a merge patch", http.StatusUnsupportedMediaType)
+		return
+	}
+	version, _ := existing["version"].(float64)
+	if match := r.Header.Get("If-Match"); match != "" && match != fmt.Sprintf("\"%d\"", int64(version)) {
+		http.Error(w, "Version mismatch", http.StatusPreconditionFailed)
+		return
+	}
+	document, _ := json.Marshal(existing)
+	patched, err := gowindams.ApplyMergePatch(document, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var obj map[string]interface{}
+	json.Unmarshal(patched, &obj)
+	obj[idField(collection)] = id
+	obj["version"] = version + 1
+	fake.store(collection, obj)
+	json.NewEncoder(w).Encode(obj)
+}
+
+// Filters on each criteria field with a value, by equality with the entity's field of the same name or, for a list,
+// membership of it.  A list field's name is taken as the plural of the entity's.
+func (fake *fakeServices) search(w http.ResponseWriter, collection string, body []byte) {
+	var criteria map[string]interface{}
+	if err := json.Unmarshal(body, &criteria); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	results := make([]map[string]interface{}, 0)
+	for _, id := range fake.order[collection] {
+		if obj := fake.entities[collection][id]; fakeMatches(collection, obj, criteria) {
+			results = append(results, obj)
+		}
+	}
+	if page, ok := criteria["page"].(float64); ok {
+		size := int(criteria["pageSize"].(float64))
+		from, to := int(page)*size, int(page+1)*size
+		if from > len(results) {
+			from = len(results)
+		}
+		if to > len(results) {
+			to = len(results)
+		}
+		results = results[from:to]
+	}
+	json.NewEncoder(w).Encode(results)
+}
+
+func fakeMatches(collection string, obj map[string]interface{}, criteria map[string]interface{}) bool {
+	for key, value := range criteria {
+		if value == nil || key == "page" || key == "pageSize" || key == "sortBy" || key == "sortDirection" {
+			continue
+		}
+		field := key
+		if key == collection+"Id" {
+			field = idField(collection)
+		}
+		if values, ok := value.([]interface{}); ok {
+			field = strings.TrimSuffix(strings.TrimSuffix(key, "es"), "s")
+			if key == "statuses" {
+				field = "status"
+			}
+			found := false
+			for _, v := range values {
+				found = found || fmt.Sprint(v) == fmt.Sprint(obj[field])
+			}
+			if !found {
+				return false
+			}
+		} else if obj[field] == nil || fmt.Sprint(obj[field]) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Adds entities to a collection, given as JSON objects.
+func (fake *fakeServices) put(collection string, objects ...string) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	for _, s := range objects {
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(s), &obj); err != nil {
+			fake.testing.Fatal(err)
+		}
+		fake.store(collection, obj)
+	}
+}
+
+func (fake *fakeServices) get(collection string, id string) map[string]interface{} {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.entities[collection][id]
+}
+
+// The entities of a collection in the order they were first stored.
+func (fake *fakeServices) list(collection string) []map[string]interface{} {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	objects := make([]map[string]interface{}, 0)
+	for _, id := range fake.order[collection] {
+		objects = append(objects, fake.entities[collection][id])
+	}
+	return objects
+}
+
+// The requests received, as "METHOD /path" in order, which start with prefix.
+func (fake *fakeServices) calls(prefix string) []string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	calls := make([]string, 0)
+	for _, r := range fake.requests {
+		if strings.HasPrefix(r, prefix) {
+			calls = append(calls, r)
+		}
+	}
+	return calls
+}
+
+func (fake *fakeServices) resetCalls() {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.requests = nil
+}
+
+// The binary uploaded for a resource, nil if there is none.
+func (fake *fakeServices) binary(id string) []byte {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	return fake.binaries[id]
+}
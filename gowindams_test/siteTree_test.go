@@ -0,0 +1,69 @@
+package gowindams_test
+
+import (
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestBuildSiteTree(testing *testing.T) {
+	site := gowindams.Site{Id: strPtr("site-1"), Name: strPtr("Prairie Wind")}
+	assets := []gowindams.Asset{
+		{Id: strPtr("asset-1"), SiteId: site.Id},
+		{Id: strPtr("asset-2"), SiteId: site.Id},
+	}
+	components := []gowindams.Component{
+		{Id: strPtr("comp-1"), AssetId: strPtr("asset-1")},
+		{Id: strPtr("comp-2"), AssetId: strPtr("asset-1")},
+		{Id: strPtr("comp-3"), AssetId: strPtr("asset-missing")},
+	}
+	assetInspections := []gowindams.AssetInspection{
+		{Id: strPtr("ai-1"), AssetId: strPtr("asset-1"), OrderNumber: strPtr("1234")},
+	}
+	componentInspections := []gowindams.ComponentInspection{
+		{Id: strPtr("ci-1"), ComponentId: strPtr("comp-1"), AssetInspectionId: strPtr("ai-1")},
+		{Id: strPtr("ci-2"), ComponentId: strPtr("comp-2"), AssetInspectionId: strPtr("ai-1")},
+	}
+	resources := []gowindams.ResourceMetadata{
+		{ResourceId: strPtr("r-1"), AssetId: strPtr("asset-1"), AssetInspectionId: strPtr("ai-1"), ComponentInspectionId: strPtr("ci-1")},
+		{ResourceId: strPtr("r-2"), AssetId: strPtr("asset-1"), AssetInspectionId: strPtr("ai-1")},
+		{ResourceId: strPtr("r-1"), AssetId: strPtr("asset-1"), AssetInspectionId: strPtr("ai-1"), ComponentInspectionId: strPtr("ci-1")},
+	}
+
+	tree := gowindams.BuildSiteTree(&site, assets, components, assetInspections, componentInspections, resources)
+	if len(tree.Assets) != 2 {
+		testing.Fatalf("Expected 2 assets, got %d", len(tree.Assets))
+	}
+	asset := tree.Asset("asset-1")
+	if asset == nil || asset.Tree != tree {
+		testing.Fatal("Unable to look up asset-1")
+	}
+	if len(asset.Components) != 2 || len(asset.Inspections) != 1 {
+		testing.Fatalf("Expected 2 components and 1 inspection, got %d and %d", len(asset.Components), len(asset.Inspections))
+	}
+	if tree.Component("comp-3") != nil {
+		testing.Fatal("Expected the orphaned component to be dropped")
+	}
+	ci := tree.ComponentInspection("ci-1")
+	if ci == nil || ci.Component.Asset != asset || ci.AssetInspection != tree.AssetInspection("ai-1") {
+		testing.Fatal("Component inspection ci-1 is not linked to its parents")
+	}
+	if len(tree.AssetInspection("ai-1").ComponentInspections) != 2 {
+		testing.Fatal("Expected the asset inspection to have 2 component inspections")
+	}
+	r1 := tree.Resource("r-1")
+	if r1 == nil || r1.ComponentInspection != ci || len(ci.Resources) != 1 {
+		testing.Fatal("Resource r-1 is not linked to its component inspection")
+	}
+	r2 := tree.Resource("r-2")
+	if r2 == nil || r2.ComponentInspection != nil || len(r2.AssetInspection.Resources) != 1 {
+		testing.Fatal("Resource r-2 is not linked to its asset inspection")
+	}
+	if len(tree.Resources()) != 2 {
+		testing.Fatalf("Expected 2 resources, got %d", len(tree.Resources()))
+	}
+}
@@ -0,0 +1,146 @@
+package gowindams_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func TestUnmarshalTimestampFormats(testing *testing.T) {
+	expected := time.Date(2015, 12, 16, 22, 48, 19, 0, time.UTC)
+	values := []string{
+		`"20151216T224819.000+0000"`,
+		`"20151216T224819+0000"`,
+		`"20151216T224819.000Z"`,
+		`"20151216T224819Z"`,
+		`"20151216T234819.000+0100"`,
+		`"2015-12-16T22:48:19Z"`,
+	}
+	for _, value := range values {
+		var t gowindams.WindAMSTime
+		err := json.Unmarshal([]byte(value), &t)
+		if err != nil {
+			testing.Fatalf("Unable to parse %s: %s", value, err)
+		}
+		if !t.Time().Equal(expected) {
+			testing.Fatalf("Parsed %s as %s, expected %s", value, t.Time(), expected)
+		}
+	}
+}
+
+func TestUnmarshalTimestampMillis(testing *testing.T) {
+	var t gowindams.WindAMSTime
+	err := json.Unmarshal([]byte(`"20151216T224819.123+0000"`), &t)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if t.Time().Nanosecond() != 123000000 {
+		testing.Fatalf("Invalid nanoseconds %d", t.Time().Nanosecond())
+	}
+}
+
+func TestUnmarshalTimestampInvalid(testing *testing.T) {
+	values := []string{`20151216T224819.000+0000`, `"yesterday"`, `12`}
+	for _, value := range values {
+		var t gowindams.WindAMSTime
+		if err := json.Unmarshal([]byte(value), &t); err == nil {
+			testing.Fatalf("Expected an error parsing %s", value)
+		}
+	}
+}
+
+func TestTimestampRoundTrip(testing *testing.T) {
+	start := gowindams.WindAMSTime(time.Date(2018, 7, 4, 13, 5, 9, 250000000, time.UTC))
+	end := gowindams.WindAMSTime(time.Date(2018, 7, 4, 13, 6, 0, 0, time.UTC))
+	value := 101.5
+	reading := gowindams.SensorReading{Value: &value, StartTime: &start, Endtime: &end}
+	data, err := json.Marshal(reading)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	got := new(gowindams.SensorReading)
+	err = json.Unmarshal(data, got)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if !got.StartTime.Time().Equal(start.Time()) || !got.Endtime.Time().Equal(end.Time()) {
+		testing.Fatalf("Round trip of %s produced %s - %s", string(data), got.StartTime, got.Endtime)
+	}
+}
+
+func TestTimestampNull(testing *testing.T) {
+	event := new(gowindams.StatusEvent)
+	err := json.Unmarshal([]byte(`{"status":"InspectionStarted","timestamp":null}`), event)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if event.Timestamp != nil {
+		testing.Fatalf("Expected a nil timestamp, got %s", event.Timestamp)
+	}
+
+	var t gowindams.WindAMSTime
+	err = json.Unmarshal([]byte("null"), &t)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if !t.Time().IsZero() {
+		testing.Fatalf("Expected the zero time, got %s", t)
+	}
+	data, _ := json.Marshal(t)
+	if string(data) != "null" {
+		testing.Fatalf("Expected the zero time to marshal as null, got %s", string(data))
+	}
+}
+
+func TestDateRoundTrip(testing *testing.T) {
+	install := gowindams.NewWindAMSDate(2012, 3, 31)
+	request := gowindams.NewWindAMSDate(2018, 11, 2)
+	asset := gowindams.Asset{DateOfInstall: &install}
+	order := gowindams.WorkOrder{RequestDate: &request}
+
+	data, err := json.Marshal(asset)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	gotAsset := new(gowindams.Asset)
+	if err = json.Unmarshal(data, gotAsset); err != nil {
+		testing.Fatal("error:", err)
+	}
+	compareStrings(testing, "20120331", gotAsset.DateOfInstall.String())
+
+	data, err = json.Marshal(order)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	gotOrder := new(gowindams.WorkOrder)
+	if err = json.Unmarshal(data, gotOrder); err != nil {
+		testing.Fatal("error:", err)
+	}
+	compareStrings(testing, "20181102", gotOrder.RequestDate.String())
+}
+
+func TestUnmarshalAssetInspectionDates(testing *testing.T) {
+	inspection := new(gowindams.AssetInspection)
+	err := json.Unmarshal([]byte(`{"dateOfInspection":"20170615","failureDate":null}`), inspection)
+	if err != nil {
+		testing.Fatal("error:", err)
+	}
+	if inspection.DateOfInspection == nil {
+		testing.Fatal("Date of inspection was not parsed")
+	}
+	d := inspection.DateOfInspection.Time()
+	if d.Year() != 2017 || d.Month() != 6 || d.Day() != 15 {
+		testing.Fatalf("Invalid date of inspection %s", inspection.DateOfInspection)
+	}
+	if inspection.FailureDate != nil {
+		testing.Fatalf("Expected a nil failure date, got %s", inspection.FailureDate)
+	}
+
+	var alt gowindams.WindAMSDate
+	if err = json.Unmarshal([]byte(`"2017-06-15"`), &alt); err != nil {
+		testing.Fatal("error:", err)
+	}
+	compareStrings(testing, "20170615", alt.String())
+}
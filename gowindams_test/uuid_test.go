@@ -0,0 +1,91 @@
+package gowindams_test
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/Inspectools/gowindams"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewUUID(testing *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := gowindams.NewUUID()
+		if !uuidPattern.MatchString(id) {
+			testing.Fatalf("Invalid UUID %s", id)
+		}
+		if seen[id] {
+			testing.Fatalf("Duplicate UUID %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestCreateDecodesSavedEntity(testing *testing.T) {
+	fake, env := newFakeServices(testing, "")
+	asset := &gowindams.Asset{SiteId: strPtr("s1"), Name: strPtr("WTG-01")}
+	if err := env.AssetServiceClient().Create(asset); err != nil {
+		testing.Fatal(err)
+	}
+	if asset.Id == nil || fake.get("asset", *asset.Id) == nil {
+		testing.Fatalf("Expected the id assigned by the services to be decoded into the asset")
+	}
+	if asset.Version == nil || *asset.Version != 1 {
+		testing.Errorf("Expected the version assigned by the services to be decoded into the asset")
+	}
+}
+
+func TestCreateGeneratesIds(testing *testing.T) {
+	fake, env := newFakeServices(testing, "  generateIds: true\n")
+	ci := &gowindams.ComponentInspection{ComponentId: strPtr("c1")}
+	if err := env.ComponentInspectionServiceClient().Create(ci); err != nil {
+		testing.Fatal(err)
+	}
+	// The services assign ids of their own to entities created without one.
+	if ci.Id == nil || !uuidPattern.MatchString(*ci.Id) || fake.get("componentInspection", *ci.Id) == nil {
+		testing.Errorf("Expected the inspection to be sent with a generated id")
+	}
+}
+
+func TestCreateWithoutSavedEntity(testing *testing.T) {
+	fake, env := newFakeServices(testing, "")
+	for _, response := range []struct {
+		status int
+		body   string
+	}{{http.StatusCreated, ""}, {http.StatusOK, `"s-9"`}, {http.StatusNoContent, ""}, {http.StatusOK, "  \n"}} {
+		response := response
+		fake.routes["PUT /site"] = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(response.status)
+			w.Write([]byte(response.body))
+		}
+		site := &gowindams.Site{Id: strPtr("s1"), Name: strPtr("Prairie Wind")}
+		if err := env.SiteServiceClient().Create(site); err != nil {
+			testing.Errorf("Expected a %d response of %q to be accepted but got %s", response.status, response.body, err)
+		}
+		if response.body == `"s-9"` {
+			compareStrings(testing, "s-9", *site.Id)
+		} else {
+			compareStrings(testing, "s1", *site.Id)
+		}
+	}
+	fake.routes["PUT /site"] = func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"s-10"`))
+	}
+	site := &gowindams.Site{Name: strPtr("Prairie Wind")}
+	if err := env.SiteServiceClient().Create(site); err != nil {
+		testing.Fatal("error:", err)
+	}
+	if site.Id == nil {
+		testing.Fatalf("Expected the id returned by the services to be set on the site")
+	}
+	compareStrings(testing, "s-10", *site.Id)
+	fake.routes["PUT /site"] = func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Invalid site", http.StatusBadRequest)
+	}
+	if err := env.SiteServiceClient().Create(&gowindams.Site{}); err == nil {
+		testing.Errorf("Expected an error status to fail the create")
+	}
+}
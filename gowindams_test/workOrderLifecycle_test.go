@@ -0,0 +1,132 @@
+package gowindams_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Inspectools/gowindams"
+)
+
+func TestValidateWorkOrderTransition(testing *testing.T) {
+	cases := []struct {
+		from    *string
+		to      string
+		allowed bool
+	}{
+		{nil, gowindams.WORK_ORDER_STATUS_REQUESTED, true},
+		{nil, gowindams.WORK_ORDER_STATUS_ONSITE, false},
+		{strPtr(gowindams.WORK_ORDER_STATUS_REQUESTED), gowindams.WORK_ORDER_STATUS_ONSITE, true},
+		{strPtr(gowindams.WORK_ORDER_STATUS_REQUESTED), gowindams.WORK_ORDER_STATUS_IMAGES_UPLOADED, false},
+		{strPtr(gowindams.WORK_ORDER_STATUS_ONSITE), gowindams.WORK_ORDER_STATUS_REQUESTED, true},
+		{strPtr(gowindams.WORK_ORDER_STATUS_ONSITE), gowindams.WORK_ORDER_STATUS_IMAGES_UPLOADED, true},
+		{strPtr(gowindams.WORK_ORDER_STATUS_IMAGES_PROCESSED), gowindams.WORK_ORDER_STATUS_COMPLETED, true},
+		{strPtr(gowindams.WORK_ORDER_STATUS_COMPLETED), gowindams.WORK_ORDER_STATUS_REQUESTED, false},
+		{strPtr(gowindams.WORK_ORDER_STATUS_ONSITE), "Cancelled", false},
+	}
+	for _, c := range cases {
+		err := gowindams.ValidateWorkOrderTransition("WO-1", c.from, c.to)
+		if c.allowed && err != nil {
+			testing.Fatalf("Expected the transition to %s to be allowed: %s", c.to, err)
+		}
+		var te *gowindams.TransitionError
+		if !c.allowed && !errors.As(err, &te) {
+			testing.Fatalf("Expected a TransitionError moving to %s, got %v", c.to, err)
+		}
+	}
+}
+
+func TestCheckWorkOrderPreconditions(testing *testing.T) {
+	inspections := []gowindams.AssetInspection{
+		{Id: strPtr("ai1"), Status: strPtr(gowindams.ASSET_INSPECTION_STATUS_PROCESSED)},
+		{Id: strPtr("ai2"), Status: strPtr(gowindams.ASSET_INSPECTION_STATUS_PENDING)},
+	}
+	err := gowindams.CheckWorkOrderPreconditions("WO-1", gowindams.WORK_ORDER_STATUS_IMAGES_UPLOADED, inspections)
+	var pe *gowindams.PreconditionError
+	if !errors.As(err, &pe) || len(pe.Reasons) != 1 {
+		testing.Fatalf("Expected one unmet precondition, got %v", err)
+	}
+
+	inspections[1].Status = strPtr(gowindams.ASSET_INSPECTION_STATUS_UPLOADED)
+	if err = gowindams.CheckWorkOrderPreconditions("WO-1", gowindams.WORK_ORDER_STATUS_IMAGES_UPLOADED, inspections); err != nil {
+		testing.Fatal(err)
+	}
+	if err = gowindams.CheckWorkOrderPreconditions("WO-1", gowindams.WORK_ORDER_STATUS_IMAGES_PROCESSED, inspections); err == nil {
+		testing.Fatal("Expected processing to require every inspection to be processed")
+	}
+	if err = gowindams.CheckWorkOrderPreconditions("WO-1", gowindams.WORK_ORDER_STATUS_ONSITE, nil); err != nil {
+		testing.Fatal(err)
+	}
+	if err = gowindams.CheckWorkOrderPreconditions("WO-1", gowindams.WORK_ORDER_STATUS_COMPLETED, nil); err == nil {
+		testing.Fatal("Expected completion to require asset inspections")
+	}
+}
+
+func TestWorkOrderTransition(testing *testing.T) {
+	fake, env := newFakeServices(testing, "  userId: inspector\n")
+	fake.put("workOrder", `{"orderNumber":"WO-1","status":"Onsite","version":4}`)
+	fake.put("assetInspection",
+		`{"id":"ai1","orderNumber":"WO-1","status":"Uploaded"}`,
+		`{"id":"ai2","orderNumber":"WO-1","status":"Pending"}`,
+		`{"id":"ai3","orderNumber":"WO-2","status":"Pending"}`)
+
+	// One inspection under the order is still pending
+	_, err := env.WorkOrderServiceClient().Transition("WO-1", gowindams.WORK_ORDER_STATUS_IMAGES_UPLOADED)
+	var pe *gowindams.PreconditionError
+	if !errors.As(err, &pe) || len(pe.Reasons) != 1 {
+		testing.Fatalf("Expected one unmet precondition, got %v", err)
+	}
+	if updates := fake.calls("POST /workOrder"); len(updates) != 0 {
+		testing.Errorf("Expected no update when a precondition is unmet but got %v", updates)
+	}
+
+	fake.put("assetInspection", `{"id":"ai2","orderNumber":"WO-1","status":"Processed"}`)
+	var ifMatch string
+	fake.intercept = func(r *http.Request) int {
+		if r.Method == "POST" && r.URL.Path == "/workOrder" {
+			ifMatch = r.Header.Get("If-Match")
+		}
+		return 0
+	}
+	wo, err := env.WorkOrderServiceClient().Transition("WO-1", gowindams.WORK_ORDER_STATUS_IMAGES_UPLOADED)
+	if err != nil {
+		testing.Fatal(err)
+	}
+	compareStrings(testing, gowindams.WORK_ORDER_STATUS_IMAGES_UPLOADED, *wo.Status)
+	compareStrings(testing, gowindams.WORK_ORDER_STATUS_IMAGES_UPLOADED, fake.get("workOrder", "WO-1")["status"].(string))
+	compareStrings(testing, `"4"`, ifMatch)
+	if len(wo.StatusHistory) != 1 {
+		testing.Fatalf("Expected a status event for the transition, got %+v", wo.StatusHistory)
+	}
+	event := wo.StatusHistory[0]
+	compareStrings(testing, gowindams.WORK_ORDER_STATUS_IMAGES_UPLOADED, *event.Status)
+	if event.UserId == nil || event.Timestamp == nil {
+		testing.Fatalf("Expected the status event to record the user and time, got %+v", event)
+	}
+	compareStrings(testing, "inspector", *event.UserId)
+	if d := time.Since(event.Timestamp.Time()); d < 0 || d > time.Minute {
+		testing.Errorf("Expected the status event to be timestamped now, got %v", event.Timestamp.Time())
+	}
+	if history, _ := fake.get("workOrder", "WO-1")["statusHistory"].([]interface{}); len(history) != 1 {
+		testing.Errorf("Expected the status event to be saved, got %v", history)
+	}
+
+	// Skipping a status is refused before the inspections are searched or anything is sent
+	fake.resetCalls()
+	var te *gowindams.TransitionError
+	_, err = env.WorkOrderServiceClient().Transition("WO-1", gowindams.WORK_ORDER_STATUS_COMPLETED)
+	if !errors.As(err, &te) {
+		testing.Fatalf("Expected a TransitionError, got %v", err)
+	}
+	if searches := fake.calls("POST /assetInspection/search"); len(searches) != 0 {
+		testing.Errorf("Expected no search for a refused transition but got %v", searches)
+	}
+	_, err = env.WorkOrderServiceClient().Transition("WO-1", gowindams.WORK_ORDER_STATUS_REQUESTED)
+	if !errors.As(err, &te) {
+		testing.Fatalf("Expected a TransitionError, got %v", err)
+	}
+	if updates := fake.calls("POST /workOrder"); len(updates) != 0 {
+		testing.Errorf("Expected no update for a refused transition but got %v", updates)
+	}
+}
@@ -0,0 +1,127 @@
+package gowindams
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// Records the ids assigned in a target environment to entities copied from a source environment.  When backed by a
+// file each entry is appended as a line of JSON as soon as it is recorded, so an interrupted copy can be resumed
+// without creating duplicates.
+type IdMapping struct {
+	mu      sync.Mutex
+	ids     map[string]map[string]string
+	file    *os.File
+	encoder *json.Encoder
+}
+
+type idMappingEntry struct {
+	EntityType string `json:"type"`
+	SourceId   string `json:"source"`
+	TargetId   string `json:"target"`
+}
+
+func NewIdMapping() *IdMapping {
+	return &IdMapping{ids: make(map[string]map[string]string)}
+}
+
+// Opens a mapping file, creating it if it does not exist.  A truncated final line, as left by an interrupted write, is
+// ignored.  Close must be called when done.
+func OpenIdMapping(path string) (*IdMapping, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	m := parseIdMapping(data)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// Start a fresh line after a truncated one.
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		if _, err = f.Write([]byte("\n")); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	m.file = f
+	m.encoder = json.NewEncoder(f)
+	return m, nil
+}
+
+// Reads a mapping file which must exist, for looking ids up.  Ids set afterwards are not written to the file.
+func LoadIdMapping(path string) (*IdMapping, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseIdMapping(data), nil
+}
+
+func parseIdMapping(data []byte) *IdMapping {
+	m := NewIdMapping()
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var entry idMappingEntry
+		if json.Unmarshal(line, &entry) == nil {
+			m.put(entry)
+		}
+	}
+	return m
+}
+
+func (m *IdMapping) put(entry idMappingEntry) {
+	byType, ok := m.ids[entry.EntityType]
+	if !ok {
+		byType = make(map[string]string)
+		m.ids[entry.EntityType] = byType
+	}
+	byType[entry.SourceId] = entry.TargetId
+}
+
+func (m *IdMapping) Get(entityType string, sourceId string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, ok := m.ids[entityType][sourceId]
+	return id, ok
+}
+
+// Maps a source id, returning nil if it is nil or has not been copied.
+func (m *IdMapping) Map(entityType string, sourceId *string) *string {
+	if sourceId == nil {
+		return nil
+	}
+	if id, ok := m.Get(entityType, *sourceId); ok {
+		return &id
+	}
+	return nil
+}
+
+func (m *IdMapping) Set(entityType string, sourceId string, targetId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := idMappingEntry{EntityType: entityType, SourceId: sourceId, TargetId: targetId}
+	m.put(entry)
+	if m.encoder != nil {
+		return m.encoder.Encode(entry)
+	}
+	return nil
+}
+
+func (m *IdMapping) Len(entityType string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.ids[entityType])
+}
+
+func (m *IdMapping) Close() error {
+	if m.file == nil {
+		return nil
+	}
+	err := m.file.Close()
+	m.file = nil
+	m.encoder = nil
+	return err
+}
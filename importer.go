@@ -0,0 +1,506 @@
+package gowindams
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Pseudo fields which identify the asset a component belongs to.
+const ImportFieldAssetName = "assetName"
+const ImportFieldAssetSerialNumber = "assetSerialNumber"
+
+const ImportActionCreate = "create"
+const ImportActionUpdate = "update"
+const ImportActionUnchanged = "unchanged"
+const ImportActionError = "error"
+
+// Describes how the columns of a spreadsheet map to the fields of assets or components, for example:
+//
+//	entityType: Asset
+//	siteId: 2b1f...
+//	matchOn: serialNumber
+//	columns:
+//	  name: Turbine
+//	  serialNumber: Serial No
+//	  location.latitude: Lat
+//	  location.longitude: Long
+//	defaults:
+//	  type: Wind_Turbine
+//	ignore: [Notes]
+//
+// Fields are named by their JSON names, with nested fields joined by dots.  Columns which are neither mapped nor
+// ignored are imported as attributes named by their header.
+type ImportMapping struct {
+	// EntityTypeAsset or EntityTypeComponent
+	EntityType string `yaml:"entityType"`
+	SiteId     string `yaml:"siteId"`
+	// The sheet to read from a workbook, by default the first.
+	Sheet   string            `yaml:"sheet"`
+	Columns map[string]string `yaml:"columns"`
+	// Values for fields which are not mapped or are empty in a row.
+	Defaults map[string]string `yaml:"defaults"`
+	// The field identifying existing records: serialNumber or name for assets, serialNumber or type for components.
+	// Defaults to serialNumber.
+	MatchOn string   `yaml:"matchOn"`
+	Ignore  []string `yaml:"ignore"`
+}
+
+type ImportOptions struct {
+	// Report the changes without saving them.
+	DryRun bool
+}
+
+// A row of the spreadsheet converted to an entity.  Patch holds only the fields given in the row, which are merged
+// into any existing record.
+type ImportRecord struct {
+	Row       int
+	Asset     *Asset
+	Component *Component
+	Patch     []byte
+	// The asset a component belongs to, by ImportFieldAssetName or ImportFieldAssetSerialNumber.
+	ParentField string
+	ParentValue string
+	Err         error
+}
+
+type ImportChange struct {
+	Row        int
+	EntityType string
+	Action     string
+	Id         string
+	Key        string
+	Changes    []FieldChange
+	Err        error
+}
+
+type ImportReport struct {
+	DryRun  bool
+	Changes []ImportChange
+}
+
+var importNumericFields = map[string]bool{
+	"location.accuracy":  true,
+	"location.altitude":  true,
+	"location.latitude":  true,
+	"location.longitude": true,
+}
+
+func LoadImportMapping(path string) (*ImportMapping, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mapping := new(ImportMapping)
+	if err = yaml.UnmarshalStrict(data, mapping); err != nil {
+		return nil, fmt.Errorf("Invalid import mapping %s: %s", path, err)
+	}
+	return mapping, mapping.validate()
+}
+
+func (m *ImportMapping) validate() error {
+	switch m.EntityType {
+	case EntityTypeAsset:
+		if m.MatchOn != "" && m.MatchOn != "serialNumber" && m.MatchOn != "name" {
+			return fmt.Errorf("Assets can only be matched on serialNumber or name, not %q", m.MatchOn)
+		}
+	case EntityTypeComponent:
+		if m.MatchOn != "" && m.MatchOn != "serialNumber" && m.MatchOn != "type" {
+			return fmt.Errorf("Components can only be matched on serialNumber or type, not %q", m.MatchOn)
+		}
+		_, byName := m.Columns[ImportFieldAssetName]
+		_, bySerial := m.Columns[ImportFieldAssetSerialNumber]
+		if !byName && !bySerial {
+			return fmt.Errorf("Components need a column mapped to %s or %s", ImportFieldAssetName, ImportFieldAssetSerialNumber)
+		}
+	default:
+		return fmt.Errorf("Only %s and %s records can be imported, not %q", EntityTypeAsset, EntityTypeComponent, m.EntityType)
+	}
+	return nil
+}
+
+func (m *ImportMapping) matchOn() string {
+	if m.MatchOn == "" {
+		return "serialNumber"
+	}
+	return m.MatchOn
+}
+
+// Converts each row of the table.  An error is returned if a mapped column is missing from the header; problems with
+// individual rows are recorded against their records.
+func (m *ImportMapping) Records(t *Table) ([]ImportRecord, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	mapped := make(map[int]bool)
+	for field, header := range m.Columns {
+		i := t.Column(header)
+		if i < 0 {
+			return nil, fmt.Errorf("The column %q mapped to %s is not in the header", header, field)
+		}
+		columns[field] = i
+		mapped[i] = true
+	}
+	for _, header := range m.Ignore {
+		if i := t.Column(header); i >= 0 {
+			mapped[i] = true
+		}
+	}
+	fields := make([]string, 0, len(columns))
+	for field := range columns {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	records := make([]ImportRecord, len(t.Rows))
+	for r := range t.Rows {
+		record := ImportRecord{Row: t.RowNumber(r)}
+		values := make(map[string]interface{})
+		var err error
+		for _, field := range fields {
+			value := t.Value(r, columns[field])
+			if value == "" {
+				continue
+			}
+			if field == ImportFieldAssetName || field == ImportFieldAssetSerialNumber {
+				record.ParentField = field
+				record.ParentValue = value
+				continue
+			}
+			if err = setImportField(values, field, value); err != nil {
+				break
+			}
+		}
+		for field, value := range m.Defaults {
+			if _, ok := lookupImportField(values, field); !ok && err == nil {
+				err = setImportField(values, field, value)
+			}
+		}
+		for i, header := range t.Header {
+			if value := t.Value(r, i); !mapped[i] && value != "" && strings.TrimSpace(header) != "" && err == nil {
+				err = setImportField(values, "attributes."+strings.TrimSpace(header), value)
+			}
+		}
+		if m.SiteId != "" && err == nil {
+			values["siteId"] = m.SiteId
+		}
+		if err == nil {
+			err = record.decode(m, values)
+		}
+		record.Err = err
+		records[r] = record
+	}
+	return records, nil
+}
+
+func (record *ImportRecord) decode(m *ImportMapping, values map[string]interface{}) error {
+	patch, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	record.Patch = patch
+	dec := json.NewDecoder(bytes.NewReader(patch))
+	dec.DisallowUnknownFields()
+	key, _ := values[m.matchOn()].(string)
+	if m.EntityType == EntityTypeAsset {
+		record.Asset = new(Asset)
+		if err = dec.Decode(record.Asset); err != nil {
+			return err
+		}
+		if key == "" {
+			return fmt.Errorf("The row has no %s to match assets on", m.matchOn())
+		}
+		return nil
+	}
+	record.Component = new(Component)
+	if err = dec.Decode(record.Component); err != nil {
+		return err
+	}
+	if record.ParentValue == "" {
+		return fmt.Errorf("The row does not say which asset the component belongs to")
+	}
+	if key == "" {
+		return fmt.Errorf("The row has no %s to match components on", m.matchOn())
+	}
+	return nil
+}
+
+func setImportField(values map[string]interface{}, field string, value string) error {
+	var v interface{} = value
+	if importNumericFields[field] {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid number %q for %s", value, field)
+		}
+		v = f
+	} else if field == "dateOfInstall" {
+		if serial, err := strconv.ParseFloat(value, 64); err == nil && !strings.ContainsAny(value, "-/") && len(value) != len(DATE_FORMAT) {
+			v = ExcelSerialDate(serial).Format(DATE_FORMAT)
+		} else if _, err := ParseWindAMSDate(value); err != nil {
+			return fmt.Errorf("Invalid date %q for %s", value, field)
+		}
+	}
+	parts := strings.Split(field, ".")
+	// Attribute names may themselves contain dots.
+	if parts[0] == "attributes" && len(parts) > 2 {
+		parts = []string{"attributes", strings.Join(parts[1:], ".")}
+	}
+	m := values
+	for _, p := range parts[:len(parts)-1] {
+		child, ok := m[p].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			m[p] = child
+		}
+		m = child
+	}
+	m[parts[len(parts)-1]] = v
+	return nil
+}
+
+func lookupImportField(values map[string]interface{}, field string) (interface{}, bool) {
+	parts := strings.SplitN(field, ".", 2)
+	v, ok := values[parts[0]]
+	if !ok || len(parts) == 1 {
+		return v, ok
+	}
+	if child, isMap := v.(map[string]interface{}); isMap {
+		return lookupImportField(child, parts[1])
+	}
+	return nil, false
+}
+
+// Reads a .csv or .xlsx file and imports it.
+func ImportFile(env *Environment, path string, mapping *ImportMapping, options *ImportOptions) (*ImportReport, error) {
+	t, err := ReadTableFile(path, mapping.Sheet)
+	if err != nil {
+		return nil, err
+	}
+	return Import(env, t, mapping, options)
+}
+
+// Creates or updates a record for each row of the table.  Existing records are found by the mapping's MatchOn field,
+// and only the fields given in a row are changed on them.
+func Import(env *Environment, t *Table, mapping *ImportMapping, options *ImportOptions) (*ImportReport, error) {
+	if options == nil {
+		options = &ImportOptions{}
+	}
+	records, err := mapping.Records(t)
+	if err != nil {
+		return nil, err
+	}
+	im := importer{env: env, mapping: mapping, options: options, parents: make(map[string]*string)}
+	report := &ImportReport{DryRun: options.DryRun}
+	for i := range records {
+		record := &records[i]
+		change := ImportChange{Row: record.Row, EntityType: mapping.EntityType}
+		if record.Err == nil {
+			if record.Asset != nil {
+				record.Err = im.importAsset(record, &change)
+			} else {
+				record.Err = im.importComponent(record, &change)
+			}
+		}
+		if record.Err != nil {
+			change.Action = ImportActionError
+			change.Err = record.Err
+			log.Printf("GOWINDAMS: Unable to import row %d: %s", record.Row, record.Err)
+		}
+		report.Changes = append(report.Changes, change)
+	}
+	return report, nil
+}
+
+type importer struct {
+	env     *Environment
+	mapping *ImportMapping
+	options *ImportOptions
+	// Asset ids by parent field and value
+	parents map[string]*string
+}
+
+func (im *importer) siteId() *string {
+	if im.mapping.SiteId == "" {
+		return nil
+	}
+	return &im.mapping.SiteId
+}
+
+func (im *importer) importAsset(record *ImportRecord, change *ImportChange) error {
+	criteria := AssetSearchCriteria{SiteId: im.siteId()}
+	if im.mapping.matchOn() == "name" {
+		criteria.Name = record.Asset.Name
+		change.Key = stringValue(record.Asset.Name)
+	} else {
+		criteria.SerialNumber = record.Asset.SerialNumber
+		change.Key = stringValue(record.Asset.SerialNumber)
+	}
+	found, err := im.env.AssetServiceClient().Search(&criteria)
+	if err != nil {
+		return err
+	}
+	if len(found) > 1 {
+		return fmt.Errorf("%d existing assets match %s %s", len(found), im.mapping.matchOn(), change.Key)
+	}
+	if len(found) == 0 {
+		if change.Changes, err = CompareFields(&Asset{}, record.Asset, nil); err != nil {
+			return err
+		}
+		change.Action = ImportActionCreate
+		if !im.options.DryRun {
+			err = im.env.AssetServiceClient().Create(record.Asset)
+		}
+		change.Id = stringValue(record.Asset.Id)
+		return err
+	}
+	existing := &found[0]
+	change.Id = stringValue(existing.Id)
+	merged := new(Asset)
+	if err = mergeImportPatch(existing, record.Patch, merged); err != nil {
+		return err
+	}
+	if change.Changes, err = CompareFields(existing, merged, nil); err != nil {
+		return err
+	}
+	if len(change.Changes) == 0 {
+		change.Action = ImportActionUnchanged
+		return nil
+	}
+	change.Action = ImportActionUpdate
+	if !im.options.DryRun {
+		err = im.env.AssetServiceClient().Update(merged)
+	}
+	return err
+}
+
+func (im *importer) importComponent(record *ImportRecord, change *ImportChange) error {
+	assetId, err := im.parentAssetId(record)
+	if err != nil {
+		return err
+	}
+	record.Component.AssetId = assetId
+	criteria := ComponentSearchCriteria{AssetId: assetId}
+	if im.mapping.matchOn() == "type" {
+		criteria.ComponentType = record.Component.Type
+		change.Key = record.ParentValue + "/" + stringValue(record.Component.Type)
+	} else {
+		criteria.SerialNumber = record.Component.SerialNumber
+		change.Key = record.ParentValue + "/" + stringValue(record.Component.SerialNumber)
+	}
+	found, err := im.env.ComponentServiceClient().Search(&criteria)
+	if err != nil {
+		return err
+	}
+	if len(found) > 1 {
+		return fmt.Errorf("%d existing components match %s", len(found), change.Key)
+	}
+	if len(found) == 0 {
+		if change.Changes, err = CompareFields(&Component{}, record.Component, nil); err != nil {
+			return err
+		}
+		change.Action = ImportActionCreate
+		if !im.options.DryRun {
+			err = im.env.ComponentServiceClient().Create(record.Component)
+		}
+		change.Id = stringValue(record.Component.Id)
+		return err
+	}
+	existing := &found[0]
+	change.Id = stringValue(existing.Id)
+	merged := new(Component)
+	if err = mergeImportPatch(existing, record.Patch, merged); err != nil {
+		return err
+	}
+	if change.Changes, err = CompareFields(existing, merged, nil); err != nil {
+		return err
+	}
+	if len(change.Changes) == 0 {
+		change.Action = ImportActionUnchanged
+		return nil
+	}
+	change.Action = ImportActionUpdate
+	if !im.options.DryRun {
+		err = im.env.ComponentServiceClient().Update(merged)
+	}
+	return err
+}
+
+func (im *importer) parentAssetId(record *ImportRecord) (*string, error) {
+	key := record.ParentField + "=" + record.ParentValue
+	if id, ok := im.parents[key]; ok {
+		return id, nil
+	}
+	criteria := AssetSearchCriteria{SiteId: im.siteId()}
+	if record.ParentField == ImportFieldAssetName {
+		criteria.Name = &record.ParentValue
+	} else {
+		criteria.SerialNumber = &record.ParentValue
+	}
+	found, err := im.env.AssetServiceClient().Search(&criteria)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) != 1 || found[0].Id == nil {
+		return nil, fmt.Errorf("Expected one asset with %s %s, found %d", record.ParentField, record.ParentValue, len(found))
+	}
+	im.parents[key] = found[0].Id
+	return found[0].Id, nil
+}
+
+func mergeImportPatch(existing interface{}, patch []byte, merged interface{}) error {
+	data, err := json.Marshal(existing)
+	if err != nil {
+		return err
+	}
+	if data, err = ApplyMergePatch(data, patch); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, merged)
+}
+
+func (report *ImportReport) Count(action string) int {
+	n := 0
+	for _, c := range report.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// A readable report of the changes made, or to be made by a dry run, followed by a summary.
+func (report *ImportReport) String() string {
+	var sb strings.Builder
+	for _, c := range report.Changes {
+		switch c.Action {
+		case ImportActionError:
+			fmt.Fprintf(&sb, "row %d: error: %s\n", c.Row, c.Err)
+		case ImportActionUnchanged:
+			fmt.Fprintf(&sb, "row %d: unchanged %s %s (%s)\n", c.Row, c.EntityType, c.Key, c.Id)
+		default:
+			if c.Id != "" {
+				fmt.Fprintf(&sb, "row %d: %s %s %s (%s)\n", c.Row, c.Action, c.EntityType, c.Key, c.Id)
+			} else {
+				fmt.Fprintf(&sb, "row %d: %s %s %s\n", c.Row, c.Action, c.EntityType, c.Key)
+			}
+			for _, fc := range c.Changes {
+				fmt.Fprintf(&sb, "    %s\n", fc)
+			}
+		}
+	}
+	verb := ""
+	if report.DryRun {
+		verb = " (dry run)"
+	}
+	fmt.Fprintf(&sb, "%d rows%s: %d created, %d updated, %d unchanged, %d failed\n", len(report.Changes), verb,
+		report.Count(ImportActionCreate), report.Count(ImportActionUpdate), report.Count(ImportActionUnchanged), report.Count(ImportActionError))
+	return sb.String()
+}
@@ -1,13 +1,17 @@
 package gowindams
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 )
 
+// A region marked on the image of an inspection event resource.  Geometry outlines it and Center marks its middle, both
+// in pixels of the image measured from its top left corner, x as Longitude and y as Latitude.  Despite the GeoPoints
+// they are not geographic coordinates.
 type InspectionEventPolygon struct {
-	Center *GeoPoint					`json:"center"'`
+	Center *GeoPoint					`json:"center"`
 	Geometry []GeoPoint					`json:"geometry"`
 	Id *string							`json:"id"`
 	Name *string						`json:"name"`
@@ -26,6 +30,8 @@ type InspectionEventResource struct {
 }
 
 type InspectionEventResourceSearchCriteria struct {
+	SearchPage
+	SearchSort
 	InspectionEventId *string			`json:"inspectionEventId"`
 	ResourceId *string					`json:"resourceId"`
 }
@@ -35,10 +41,18 @@ type InspectionEventResourceServiceClient struct {
 }
 
 const ieRootURI = "%s/inspectionEventResource"
+const ieDeleteURI = ieRootURI + "/%s"
 const ieGetURI = ieRootURI + "/%s"
 const ieSaveURI = ieRootURI
 const ieSearchURI = ieRootURI + "/search"
 
+func (client InspectionEventResourceServiceClient) Delete(id string) error {
+	log.Printf("Deleting inspection event resource %s", id)
+	url := fmt.Sprintf(ieDeleteURI, client.env.ServiceURI, id)
+	err := executeRestCall(client.env, "DELETE", url, nil, nil)
+	return err
+}
+
 func (client InspectionEventResourceServiceClient) Get(id string) (*ResourceMetadata, error) {
 	log.Printf("Loading inspection event resource for %s", id)
 	url := fmt.Sprintf(ieGetURI, client.env.ServiceURI, id)
@@ -47,6 +61,9 @@ func (client InspectionEventResourceServiceClient) Get(id string) (*ResourceMeta
 	return result, err
 }
 
+// Creates the inspection event resource if it has no id, otherwise replaces it.  Unlike the other entities, inspection
+// event resources carry no version, so a replace sends no If-Match precondition and never fails with a ConflictError:
+// the last save wins.
 func (client InspectionEventResourceServiceClient) Save(obj *InspectionEventResource) error {
 	data, err := json.Marshal(obj)
 	if err != nil {
@@ -56,7 +73,7 @@ func (client InspectionEventResourceServiceClient) Save(obj *InspectionEventReso
 	if obj.Id == nil {
 		err = executeRestCall(client.env, "PUT", url, data, obj)
 	} else {
-		err = executeRestCall(client.env, "POST", url, data, nil)
+		err = executeSaveCall(client.env, "POST", url, data, obj)
 	}
 	return err
 }
@@ -71,3 +88,28 @@ func (client InspectionEventResourceServiceClient) Search(criteria *InspectionEv
 	err = executeRestCall(client.env, "POST", url, data, &results)
 	return results, err
 }
+
+type InspectionEventResourceIterator struct {
+	*searchIterator
+	value *InspectionEventResource
+}
+
+func (it *InspectionEventResourceIterator) Next() bool {
+	it.value = new(InspectionEventResource)
+	return it.next(it.value)
+}
+
+func (it *InspectionEventResourceIterator) Value() *InspectionEventResource {
+	return it.value
+}
+
+func (client InspectionEventResourceServiceClient) Iterate(ctx context.Context, criteria *InspectionEventResourceSearchCriteria, pageSize int) *InspectionEventResourceIterator {
+	c := InspectionEventResourceSearchCriteria{}
+	if criteria != nil {
+		c = *criteria
+	}
+	url := fmt.Sprintf(ieSearchURI, client.env.ServiceURI)
+	return &InspectionEventResourceIterator{
+		searchIterator: newSearchIterator(ctx, client.env, url, &c, &c.SearchPage, pageSize),
+	}
+}
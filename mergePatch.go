@@ -0,0 +1,215 @@
+package gowindams
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+const MergePatchContentType = "application/merge-patch+json"
+
+// Computes an RFC 7386 JSON merge patch which transforms the JSON form of original into that of modified.  Fields
+// whose values are equal are left out, fields which become null or are removed are sent as null, and arrays are
+// replaced as a whole.
+func CreateMergePatch(original interface{}, modified interface{}) ([]byte, error) {
+	a, err := toJSONValue(original)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toJSONValue(modified)
+	if err != nil {
+		return nil, err
+	}
+	am, aok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+	if !aok || !bok {
+		return nil, errors.New("A merge patch can only be created between two JSON objects")
+	}
+	return json.Marshal(diffObjects(am, bm))
+}
+
+// Applies an RFC 7386 JSON merge patch to a JSON document.
+func ApplyMergePatch(document []byte, patch []byte) ([]byte, error) {
+	var doc interface{}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, err
+	}
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValue(doc, p))
+}
+
+func IsEmptyMergePatch(patch []byte) bool {
+	return bytes.Equal(bytes.TrimSpace(patch), []byte("{}"))
+}
+
+func toJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	err = json.Unmarshal(data, &result)
+	return result, err
+}
+
+func diffObjects(original map[string]interface{}, modified map[string]interface{}) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for k, ov := range original {
+		if _, ok := modified[k]; !ok && ov != nil {
+			patch[k] = nil
+		}
+	}
+	for k, mv := range modified {
+		ov, ok := original[k]
+		if ok && reflect.DeepEqual(ov, mv) {
+			continue
+		}
+		om, oIsObj := ov.(map[string]interface{})
+		mm, mIsObj := mv.(map[string]interface{})
+		if ok && oIsObj && mIsObj {
+			patch[k] = diffObjects(om, mm)
+		} else if !ok && mv == nil {
+			continue
+		} else {
+			patch[k] = mv
+		}
+	}
+	return patch
+}
+
+func mergeValue(target interface{}, patch interface{}) interface{} {
+	pm, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	tm, ok := target.(map[string]interface{})
+	if !ok {
+		tm = make(map[string]interface{})
+	}
+	for k, v := range pm {
+		if v == nil {
+			delete(tm, k)
+		} else {
+			tm[k] = mergeValue(tm[k], v)
+		}
+	}
+	return tm
+}
+
+// Sends a merge patch to the entity at url, decoding the patched entity into result if the services return it.
+func executePatchCall(env *Environment, url string, patch []byte, result interface{}, version *int64) error {
+	header := versionHeader(version)
+	header.Set("content-type", MergePatchContentType)
+	return doRestCall(env, "PATCH", url, patch, result, true, header)
+}
+
+func patchChanges(env *Environment, url string, original interface{}, modified interface{}, version *int64) error {
+	patch, err := CreateMergePatch(original, modified)
+	if err != nil {
+		return err
+	}
+	if IsEmptyMergePatch(patch) {
+		return nil
+	}
+	return executePatchCall(env, url, patch, modified, version)
+}
+
+// Patches the fields without an If-Match precondition.  The fields are not a diff against a loaded copy of the entity, so
+// there is no version for them to be checked against: they are set on whatever version the services hold.
+func patchFields(env *Environment, url string, fields map[string]interface{}, result interface{}) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("No fields to patch at %s", url)
+	}
+	patch, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return executePatchCall(env, url, patch, result, nil)
+}
+
+// Sends only the fields which differ between original and modified.  Nothing is sent if they are the same.
+func (client AssetServiceClient) PatchChanges(original *Asset, modified *Asset) error {
+	url := fmt.Sprintf(assetGetURI, client.env.ServiceURI, stringValue(original.Id))
+	return patchChanges(client.env, url, original, modified, original.Version)
+}
+
+// Sets the fields, keyed by their JSON names, of the asset.  A nil value clears the field.  If result is not nil, the
+// patched asset returned by the services is decoded into it.  Unlike PatchChanges, no version is sent, so the fields
+// are set even if the asset has changed since it was loaded.
+func (client AssetServiceClient) Patch(id string, fields map[string]interface{}, result *Asset) error {
+	url := fmt.Sprintf(assetGetURI, client.env.ServiceURI, id)
+	if result == nil {
+		return patchFields(client.env, url, fields, nil)
+	}
+	return patchFields(client.env, url, fields, result)
+}
+
+func (client AssetInspectionServiceClient) PatchChanges(original *AssetInspection, modified *AssetInspection) error {
+	url := fmt.Sprintf(assetInspectionGetURI, client.env.ServiceURI, stringValue(original.Id))
+	return patchChanges(client.env, url, original, modified, original.Version)
+}
+
+func (client AssetInspectionServiceClient) Patch(id string, fields map[string]interface{}, result *AssetInspection) error {
+	url := fmt.Sprintf(assetInspectionGetURI, client.env.ServiceURI, id)
+	if result == nil {
+		return patchFields(client.env, url, fields, nil)
+	}
+	return patchFields(client.env, url, fields, result)
+}
+
+func (client ComponentServiceClient) PatchChanges(original *Component, modified *Component) error {
+	url := fmt.Sprintf(componentGetURI, client.env.ServiceURI, stringValue(original.Id))
+	return patchChanges(client.env, url, original, modified, original.Version)
+}
+
+func (client ComponentServiceClient) Patch(id string, fields map[string]interface{}, result *Component) error {
+	url := fmt.Sprintf(componentGetURI, client.env.ServiceURI, id)
+	if result == nil {
+		return patchFields(client.env, url, fields, nil)
+	}
+	return patchFields(client.env, url, fields, result)
+}
+
+func (client ComponentInspectionServiceClient) PatchChanges(original *ComponentInspection, modified *ComponentInspection) error {
+	url := fmt.Sprintf(componentInspectionGetURI, client.env.ServiceURI, stringValue(original.Id))
+	return patchChanges(client.env, url, original, modified, original.Version)
+}
+
+func (client ComponentInspectionServiceClient) Patch(id string, fields map[string]interface{}, result *ComponentInspection) error {
+	url := fmt.Sprintf(componentInspectionGetURI, client.env.ServiceURI, id)
+	if result == nil {
+		return patchFields(client.env, url, fields, nil)
+	}
+	return patchFields(client.env, url, fields, result)
+}
+
+func (client SiteServiceClient) PatchChanges(original *Site, modified *Site) error {
+	url := fmt.Sprintf(siteGetURI, client.env.ServiceURI, stringValue(original.Id))
+	return patchChanges(client.env, url, original, modified, original.Version)
+}
+
+func (client SiteServiceClient) Patch(id string, fields map[string]interface{}, result *Site) error {
+	url := fmt.Sprintf(siteGetURI, client.env.ServiceURI, id)
+	if result == nil {
+		return patchFields(client.env, url, fields, nil)
+	}
+	return patchFields(client.env, url, fields, result)
+}
+
+func (client WorkOrderServiceClient) PatchChanges(original *WorkOrder, modified *WorkOrder) error {
+	url := fmt.Sprintf(woGetURI, client.env.ServiceURI, stringValue(original.OrderNumber))
+	return patchChanges(client.env, url, original, modified, original.Version)
+}
+
+func (client WorkOrderServiceClient) Patch(orderNumber string, fields map[string]interface{}, result *WorkOrder) error {
+	url := fmt.Sprintf(woGetURI, client.env.ServiceURI, orderNumber)
+	if result == nil {
+		return patchFields(client.env, url, fields, nil)
+	}
+	return patchFields(client.env, url, fields, result)
+}
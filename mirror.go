@@ -0,0 +1,484 @@
+package gowindams
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entities are stored as the JSON the services return, keyed by type and id, along with the site they were mirrored
+// under and their order number so that a refresh can tell which rows it covers.
+var mirrorSchema = []string{
+	`CREATE TABLE IF NOT EXISTS mirror_sites (
+		site_id      TEXT PRIMARY KEY,
+		environment  TEXT NOT NULL,
+		service_uri  TEXT NOT NULL,
+		order_number TEXT,
+		synced_at    TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS mirror_entities (
+		entity_type  TEXT NOT NULL,
+		id           TEXT NOT NULL,
+		site_id      TEXT NOT NULL,
+		order_number TEXT,
+		data         TEXT NOT NULL,
+		synced_at    TEXT NOT NULL,
+		PRIMARY KEY (entity_type, id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS mirror_entities_site ON mirror_entities (site_id, entity_type)`,
+	`CREATE TABLE IF NOT EXISTS mirror_thumbnails (
+		resource_id TEXT PRIMARY KEY,
+		site_id     TEXT NOT NULL,
+		data        BLOB NOT NULL
+	)`,
+}
+
+// Returned, wrapped, by the mirror clients' Get methods when the entity has not been mirrored.
+var ErrNotMirrored = errors.New("Not found in the mirror")
+
+// A copy of selected sites in a local SQLite database, for working without a connection to the services.  The
+// caller opens the database with the SQLite driver of their choice, e.g. github.com/mattn/go-sqlite3.  The SQL,
+// including SQLite's INSERT OR REPLACE, is tested against an in-memory stand-in for SQLite rather than a real driver.
+type Mirror struct {
+	db *sql.DB
+}
+
+type MirrorSyncOptions struct {
+	// Only mirror inspections, resources and work orders for this order number.
+	OrderNumber *string
+	// Also store a JPEG thumbnail of every image resource.
+	Thumbnails    bool
+	ThumbnailSize int
+	Parallelism   int
+}
+
+type MirrorSyncReport struct {
+	SiteId string
+	// Rows written and removed, by entity type.  Entities unchanged since the last sync are not rewritten.
+	Added   map[string]int
+	Updated map[string]int
+	Removed map[string]int
+	// Thumbnails stored, and those which could not be downloaded or decoded.
+	Thumbnails      int
+	ThumbnailErrors int
+}
+
+type MirroredSite struct {
+	SiteId      string
+	Environment string
+	ServiceURI  string
+	// The order number the site was last synced for, nil once the site has been synced in full.
+	OrderNumber *string
+	SyncedAt    time.Time
+}
+
+type mirrorRow struct {
+	entityType  string
+	id          string
+	orderNumber *string
+	data        string
+}
+
+func (r mirrorRow) key() string {
+	return r.entityType + "/" + r.id
+}
+
+// Creates the mirror's tables if the database does not already have them.
+func OpenMirror(db *sql.DB) (*Mirror, error) {
+	for _, stmt := range mirrorSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("Unable to create the mirror schema: %w", err)
+		}
+	}
+	return &Mirror{db: db}, nil
+}
+
+// Brings the mirror of a site up to date.  Only entities which changed since the last sync are written, and those
+// which no longer exist are removed.  When syncing a single order number, entities of other orders are left alone.
+func (m *Mirror) SyncSite(env *Environment, siteId string, options *MirrorSyncOptions) (*MirrorSyncReport, error) {
+	if options == nil {
+		options = &MirrorSyncOptions{}
+	}
+	snapshot, err := loadSnapshot(environmentSource{env: env}, siteId, options.OrderNumber, options.Parallelism)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := mirrorRows(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := m.siteRows(siteId)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MirrorSyncReport{
+		SiteId:  siteId,
+		Added:   make(map[string]int),
+		Updated: make(map[string]int),
+		Removed: make(map[string]int),
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	for _, row := range rows {
+		old, found := existing[row.key()]
+		delete(existing, row.key())
+		if found && old.data == row.data {
+			continue
+		}
+		_, err = tx.Exec(`INSERT OR REPLACE INTO mirror_entities (entity_type, id, site_id, order_number, data, synced_at)
+			VALUES (?, ?, ?, ?, ?, ?)`, row.entityType, row.id, siteId, row.orderNumber, row.data, now)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			report.Updated[row.entityType]++
+		} else {
+			report.Added[row.entityType]++
+		}
+		// A changed resource may have a new image, so its thumbnail is removed for syncThumbnails to make again.
+		if found && row.entityType == EntityTypeResource {
+			if _, err = tx.Exec(`DELETE FROM mirror_thumbnails WHERE resource_id = ?`, row.id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, old := range existing {
+		if !mirrorSyncCovers(old, options.OrderNumber) {
+			continue
+		}
+		if _, err = tx.Exec(`DELETE FROM mirror_entities WHERE entity_type = ? AND id = ?`, old.entityType, old.id); err != nil {
+			return nil, err
+		}
+		if old.entityType == EntityTypeResource {
+			if _, err = tx.Exec(`DELETE FROM mirror_thumbnails WHERE resource_id = ?`, old.id); err != nil {
+				return nil, err
+			}
+		}
+		report.Removed[old.entityType]++
+	}
+	// A sync of one order keeps the rest of a site synced in full, so the site stays recorded as mirrored in full.
+	orderNumber := options.OrderNumber
+	if orderNumber != nil {
+		var synced sql.NullString
+		err = tx.QueryRow(`SELECT order_number FROM mirror_sites WHERE site_id = ?`, siteId).Scan(&synced)
+		if err == nil && !synced.Valid {
+			orderNumber = nil
+		} else if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+	_, err = tx.Exec(`INSERT OR REPLACE INTO mirror_sites (site_id, environment, service_uri, order_number, synced_at)
+		VALUES (?, ?, ?, ?, ?)`, siteId, env.Name, env.ServiceURI, orderNumber, now)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if options.Thumbnails {
+		if err = m.syncThumbnails(env, siteId, snapshot.resources, options, report); err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("GOWINDAMS: Mirrored site %s from %s: added %v, updated %v, removed %v, %d thumbnails",
+		siteId, env.Name, report.Added, report.Updated, report.Removed, report.Thumbnails)
+	return report, nil
+}
+
+// Whether a row left over from an earlier sync falls within the scope of this one, so that its absence means the
+// entity was deleted.  Sites, assets and components are always synced in full.
+func mirrorSyncCovers(row mirrorRow, orderNumber *string) bool {
+	switch {
+	case orderNumber == nil:
+		return true
+	case row.entityType == EntityTypeSite || row.entityType == EntityTypeAsset || row.entityType == EntityTypeComponent:
+		return true
+	default:
+		return row.orderNumber != nil && *row.orderNumber == *orderNumber
+	}
+}
+
+func mirrorRows(s *siteSnapshot) ([]mirrorRow, error) {
+	rows := make([]mirrorRow, 0)
+	add := func(entityType string, id *string, orderNumber *string, v interface{}) error {
+		if id == nil {
+			return nil
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, mirrorRow{entityType: entityType, id: *id, orderNumber: orderNumber, data: string(data)})
+		return nil
+	}
+	if err := add(EntityTypeSite, s.site.Id, nil, s.site); err != nil {
+		return nil, err
+	}
+	for i := range s.workOrders {
+		obj := &s.workOrders[i]
+		if err := add(EntityTypeWorkOrder, obj.OrderNumber, obj.OrderNumber, obj); err != nil {
+			return nil, err
+		}
+	}
+	for i := range s.assets {
+		if err := add(EntityTypeAsset, s.assets[i].Id, nil, &s.assets[i]); err != nil {
+			return nil, err
+		}
+	}
+	for i := range s.components {
+		if err := add(EntityTypeComponent, s.components[i].Id, nil, &s.components[i]); err != nil {
+			return nil, err
+		}
+	}
+	for i := range s.assetInspections {
+		obj := &s.assetInspections[i]
+		if err := add(EntityTypeAssetInspection, obj.Id, obj.OrderNumber, obj); err != nil {
+			return nil, err
+		}
+	}
+	for i := range s.componentInspections {
+		obj := &s.componentInspections[i]
+		if err := add(EntityTypeComponentInspection, obj.Id, obj.OrderNumber, obj); err != nil {
+			return nil, err
+		}
+	}
+	for i := range s.resources {
+		obj := &s.resources[i]
+		if err := add(EntityTypeResource, obj.ResourceId, obj.OrderNumber, obj); err != nil {
+			return nil, err
+		}
+	}
+	for i := range s.inspectionEventResources {
+		obj := &s.inspectionEventResources[i]
+		if err := add(EntityTypeInspectionEventResource, obj.Id, obj.OrderNumber, obj); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+func (m *Mirror) siteRows(siteId string) (map[string]mirrorRow, error) {
+	rs, err := m.db.Query(`SELECT entity_type, id, order_number, data FROM mirror_entities WHERE site_id = ?`, siteId)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+	rows := make(map[string]mirrorRow)
+	for rs.Next() {
+		var row mirrorRow
+		var orderNumber sql.NullString
+		if err = rs.Scan(&row.entityType, &row.id, &orderNumber, &row.data); err != nil {
+			return nil, err
+		}
+		if orderNumber.Valid {
+			row.orderNumber = &orderNumber.String
+		}
+		rows[row.key()] = row
+	}
+	return rows, rs.Err()
+}
+
+// Stores thumbnails for image resources which do not have one, which includes those new or changed by this sync.
+// Images which cannot be downloaded are logged and counted rather than failing the sync; the next sync tries them
+// again.
+func (m *Mirror) syncThumbnails(env *Environment, siteId string, resources []ResourceMetadata, options *MirrorSyncOptions, report *MirrorSyncReport) error {
+	have := make(map[string]bool)
+	rs, err := m.db.Query(`SELECT resource_id FROM mirror_thumbnails WHERE site_id = ?`, siteId)
+	if err != nil {
+		return err
+	}
+	for rs.Next() {
+		var id string
+		if err = rs.Scan(&id); err != nil {
+			rs.Close()
+			return err
+		}
+		have[id] = true
+	}
+	rs.Close()
+	if err = rs.Err(); err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	thumbnails := make(map[string][]byte)
+	source := environmentSource{env: env}
+	g := newWorkGroup(options.Parallelism)
+	for i := range resources {
+		rmeta := &resources[i]
+		if rmeta.ResourceId == nil || !strings.HasPrefix(stringValue(rmeta.ContentType), "image/") {
+			continue
+		}
+		id := *rmeta.ResourceId
+		if have[id] {
+			continue
+		}
+		g.Go(func() error {
+			data, err := downloadThumbnail(source, id, options.ThumbnailSize)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("GOWINDAMS: Unable to mirror a thumbnail of resource %s: %s", id, err)
+				report.ThumbnailErrors++
+				return nil
+			}
+			thumbnails[id] = data
+			return nil
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return err
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for id, data := range thumbnails {
+		_, err = tx.Exec(`INSERT OR REPLACE INTO mirror_thumbnails (resource_id, site_id, data) VALUES (?, ?, ?)`, id, siteId, data)
+		if err != nil {
+			return err
+		}
+		report.Thumbnails++
+	}
+	return tx.Commit()
+}
+
+func downloadThumbnail(source copySource, resourceId string, size int) ([]byte, error) {
+	body, err := source.download(resourceId)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return MakeThumbnail(body, size)
+}
+
+// The sites held in the mirror and when each was last synced.
+func (m *Mirror) Sites() ([]MirroredSite, error) {
+	rs, err := m.db.Query(`SELECT site_id, environment, service_uri, order_number, synced_at FROM mirror_sites ORDER BY site_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+	sites := make([]MirroredSite, 0)
+	for rs.Next() {
+		var site MirroredSite
+		var orderNumber sql.NullString
+		var syncedAt string
+		if err = rs.Scan(&site.SiteId, &site.Environment, &site.ServiceURI, &orderNumber, &syncedAt); err != nil {
+			return nil, err
+		}
+		if orderNumber.Valid {
+			site.OrderNumber = &orderNumber.String
+		}
+		if site.SyncedAt, err = time.Parse(time.RFC3339, syncedAt); err != nil {
+			return nil, err
+		}
+		sites = append(sites, site)
+	}
+	return sites, rs.Err()
+}
+
+// Deletes a site and everything mirrored under it.
+func (m *Mirror) RemoveSite(siteId string) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range []string{
+		`DELETE FROM mirror_thumbnails WHERE site_id = ?`,
+		`DELETE FROM mirror_entities WHERE site_id = ?`,
+		`DELETE FROM mirror_sites WHERE site_id = ?`,
+	} {
+		if _, err = tx.Exec(stmt, siteId); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// The JPEG thumbnail stored for a resource.
+func (m *Mirror) Thumbnail(resourceId string) ([]byte, error) {
+	var data []byte
+	err := m.db.QueryRow(`SELECT data FROM mirror_thumbnails WHERE resource_id = ?`, resourceId).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("Thumbnail of resource %s: %w", resourceId, ErrNotMirrored)
+	}
+	return data, err
+}
+
+func (m *Mirror) get(entityType string, id string, result interface{}) error {
+	var data string
+	err := m.db.QueryRow(`SELECT data FROM mirror_entities WHERE entity_type = ? AND id = ?`, entityType, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%s %s: %w", entityType, id, ErrNotMirrored)
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), result)
+}
+
+// Calls decode with the JSON of each mirrored entity of the type, only those of one site when siteId is given.
+func (m *Mirror) scan(entityType string, siteId *string, decode func([]byte) error) error {
+	var rs *sql.Rows
+	var err error
+	if siteId != nil {
+		rs, err = m.db.Query(`SELECT data FROM mirror_entities WHERE entity_type = ? AND site_id = ? ORDER BY id`, entityType, *siteId)
+	} else {
+		rs, err = m.db.Query(`SELECT data FROM mirror_entities WHERE entity_type = ? ORDER BY id`, entityType)
+	}
+	if err != nil {
+		return err
+	}
+	defer rs.Close()
+	for rs.Next() {
+		var data []byte
+		if err = rs.Scan(&data); err != nil {
+			return err
+		}
+		if err = decode(data); err != nil {
+			return err
+		}
+	}
+	return rs.Err()
+}
+
+// Decodes the mirrored entities of the type, only those of one site when siteId is given, into the slice results
+// points to, keeping those for which match is true.  They are then sorted and paged as the services would.
+func (m *Mirror) search(entityType string, siteId *string, results interface{}, match func(interface{}) bool, order SearchSort, page SearchPage) error {
+	slice := reflect.ValueOf(results).Elem()
+	err := m.scan(entityType, siteId, func(data []byte) error {
+		obj := reflect.New(slice.Type().Elem())
+		if err := json.Unmarshal(data, obj.Interface()); err != nil {
+			return err
+		}
+		if match(obj.Interface()) {
+			slice.Set(reflect.Append(slice, obj.Elem()))
+		}
+		return nil
+	})
+	if err == nil {
+		err = sortSlice(slice.Interface(), order)
+	}
+	if err != nil {
+		return err
+	}
+	lo, hi := page.bounds(slice.Len())
+	slice.Set(slice.Slice(lo, hi))
+	return nil
+}
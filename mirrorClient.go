@@ -0,0 +1,229 @@
+package gowindams
+
+// The read side of the service clients, implemented both by the clients of an Environment and by those of a Mirror,
+// so that tools can work against either.
+type SiteReader interface {
+	Get(id string) (*Site, error)
+	Search(criteria *SiteSearchCriteria) ([]Site, error)
+}
+
+type WorkOrderReader interface {
+	Get(id string) (*WorkOrder, error)
+	Search(criteria *WorkOrderSearchCriteria) ([]WorkOrder, error)
+}
+
+type AssetReader interface {
+	Get(id string) (*Asset, error)
+	Search(criteria *AssetSearchCriteria) ([]Asset, error)
+}
+
+type ComponentReader interface {
+	Get(id string) (*Component, error)
+	Search(criteria *ComponentSearchCriteria) ([]Component, error)
+}
+
+type AssetInspectionReader interface {
+	Get(id string) (*AssetInspection, error)
+	Search(criteria *AssetInspectionSearchCriteria) ([]AssetInspection, error)
+}
+
+type ComponentInspectionReader interface {
+	Get(id string) (*ComponentInspection, error)
+	Search(criteria *ComponentInspectionSearchCriteria) ([]ComponentInspection, error)
+}
+
+type ResourceReader interface {
+	Get(resourceId string) (*ResourceMetadata, error)
+	Search(criteria *ResourceSearchCriteria) ([]ResourceMetadata, error)
+}
+
+type InspectionEventResourceReader interface {
+	Search(criteria *InspectionEventResourceSearchCriteria) ([]InspectionEventResource, error)
+}
+
+// Read-only clients answering from the mirror.  Searches apply the criteria as the services do, including sorting
+// and paging.
+type MirrorSiteClient struct{ mirror *Mirror }
+type MirrorWorkOrderClient struct{ mirror *Mirror }
+type MirrorAssetClient struct{ mirror *Mirror }
+type MirrorComponentClient struct{ mirror *Mirror }
+type MirrorAssetInspectionClient struct{ mirror *Mirror }
+type MirrorComponentInspectionClient struct{ mirror *Mirror }
+type MirrorResourceClient struct{ mirror *Mirror }
+type MirrorInspectionEventResourceClient struct{ mirror *Mirror }
+
+func (m *Mirror) SiteServiceClient() MirrorSiteClient {
+	return MirrorSiteClient{mirror: m}
+}
+
+func (m *Mirror) WorkOrderServiceClient() MirrorWorkOrderClient {
+	return MirrorWorkOrderClient{mirror: m}
+}
+
+func (m *Mirror) AssetServiceClient() MirrorAssetClient {
+	return MirrorAssetClient{mirror: m}
+}
+
+func (m *Mirror) ComponentServiceClient() MirrorComponentClient {
+	return MirrorComponentClient{mirror: m}
+}
+
+func (m *Mirror) AssetInspectionServiceClient() MirrorAssetInspectionClient {
+	return MirrorAssetInspectionClient{mirror: m}
+}
+
+func (m *Mirror) ComponentInspectionServiceClient() MirrorComponentInspectionClient {
+	return MirrorComponentInspectionClient{mirror: m}
+}
+
+func (m *Mirror) ResourceServiceClient() MirrorResourceClient {
+	return MirrorResourceClient{mirror: m}
+}
+
+func (m *Mirror) InspectionEventResourceServiceClient() MirrorInspectionEventResourceClient {
+	return MirrorInspectionEventResourceClient{mirror: m}
+}
+
+func (client MirrorSiteClient) Get(id string) (*Site, error) {
+	result := new(Site)
+	err := client.mirror.get(EntityTypeSite, id, result)
+	return result, err
+}
+
+func (client MirrorSiteClient) Search(criteria *SiteSearchCriteria) ([]Site, error) {
+	if criteria == nil {
+		criteria = &SiteSearchCriteria{}
+	}
+	results := make([]Site, 0)
+	match := func(obj interface{}) bool { return criteria.Matches(obj.(*Site)) }
+	if err := client.mirror.search(EntityTypeSite, nil, &results, match, criteria.SearchSort, criteria.SearchPage); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (client MirrorWorkOrderClient) Get(id string) (*WorkOrder, error) {
+	result := new(WorkOrder)
+	err := client.mirror.get(EntityTypeWorkOrder, id, result)
+	return result, err
+}
+
+func (client MirrorWorkOrderClient) Search(criteria *WorkOrderSearchCriteria) ([]WorkOrder, error) {
+	if criteria == nil {
+		criteria = &WorkOrderSearchCriteria{}
+	}
+	results := make([]WorkOrder, 0)
+	match := func(obj interface{}) bool { return criteria.Matches(obj.(*WorkOrder)) }
+	if err := client.mirror.search(EntityTypeWorkOrder, criteria.SiteId, &results, match, criteria.SearchSort, criteria.SearchPage); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (client MirrorAssetClient) Get(id string) (*Asset, error) {
+	result := new(Asset)
+	err := client.mirror.get(EntityTypeAsset, id, result)
+	return result, err
+}
+
+func (client MirrorAssetClient) Search(criteria *AssetSearchCriteria) ([]Asset, error) {
+	if criteria == nil {
+		criteria = &AssetSearchCriteria{}
+	}
+	results := make([]Asset, 0)
+	match := func(obj interface{}) bool { return criteria.Matches(obj.(*Asset)) }
+	if err := client.mirror.search(EntityTypeAsset, criteria.SiteId, &results, match, criteria.SearchSort, criteria.SearchPage); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (client MirrorComponentClient) Get(id string) (*Component, error) {
+	result := new(Component)
+	err := client.mirror.get(EntityTypeComponent, id, result)
+	return result, err
+}
+
+func (client MirrorComponentClient) Search(criteria *ComponentSearchCriteria) ([]Component, error) {
+	if criteria == nil {
+		criteria = &ComponentSearchCriteria{}
+	}
+	results := make([]Component, 0)
+	match := func(obj interface{}) bool { return criteria.Matches(obj.(*Component)) }
+	if err := client.mirror.search(EntityTypeComponent, criteria.SiteId, &results, match, criteria.SearchSort, criteria.SearchPage); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (client MirrorAssetInspectionClient) Get(id string) (*AssetInspection, error) {
+	result := new(AssetInspection)
+	err := client.mirror.get(EntityTypeAssetInspection, id, result)
+	return result, err
+}
+
+func (client MirrorAssetInspectionClient) Search(criteria *AssetInspectionSearchCriteria) ([]AssetInspection, error) {
+	if criteria == nil {
+		criteria = &AssetInspectionSearchCriteria{}
+	}
+	results := make([]AssetInspection, 0)
+	match := func(obj interface{}) bool { return criteria.Matches(obj.(*AssetInspection)) }
+	if err := client.mirror.search(EntityTypeAssetInspection, criteria.SiteId, &results, match, criteria.SearchSort, criteria.SearchPage); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (client MirrorComponentInspectionClient) Get(id string) (*ComponentInspection, error) {
+	result := new(ComponentInspection)
+	err := client.mirror.get(EntityTypeComponentInspection, id, result)
+	return result, err
+}
+
+func (client MirrorComponentInspectionClient) Search(criteria *ComponentInspectionSearchCriteria) ([]ComponentInspection, error) {
+	if criteria == nil {
+		criteria = &ComponentInspectionSearchCriteria{}
+	}
+	results := make([]ComponentInspection, 0)
+	match := func(obj interface{}) bool { return criteria.Matches(obj.(*ComponentInspection)) }
+	if err := client.mirror.search(EntityTypeComponentInspection, criteria.SiteId, &results, match, criteria.SearchSort, criteria.SearchPage); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (client MirrorResourceClient) Get(resourceId string) (*ResourceMetadata, error) {
+	result := new(ResourceMetadata)
+	err := client.mirror.get(EntityTypeResource, resourceId, result)
+	return result, err
+}
+
+func (client MirrorResourceClient) Search(criteria *ResourceSearchCriteria) ([]ResourceMetadata, error) {
+	if criteria == nil {
+		criteria = &ResourceSearchCriteria{}
+	}
+	results := make([]ResourceMetadata, 0)
+	match := func(obj interface{}) bool { return criteria.Matches(obj.(*ResourceMetadata)) }
+	if err := client.mirror.search(EntityTypeResource, criteria.SiteId, &results, match, criteria.SearchSort, criteria.SearchPage); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (client MirrorInspectionEventResourceClient) Get(id string) (*InspectionEventResource, error) {
+	result := new(InspectionEventResource)
+	err := client.mirror.get(EntityTypeInspectionEventResource, id, result)
+	return result, err
+}
+
+func (client MirrorInspectionEventResourceClient) Search(criteria *InspectionEventResourceSearchCriteria) ([]InspectionEventResource, error) {
+	if criteria == nil {
+		criteria = &InspectionEventResourceSearchCriteria{}
+	}
+	results := make([]InspectionEventResource, 0)
+	match := func(obj interface{}) bool { return criteria.Matches(obj.(*InspectionEventResource)) }
+	if err := client.mirror.search(EntityTypeInspectionEventResource, nil, &results, match, criteria.SearchSort, criteria.SearchPage); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,677 @@
+package gowindams
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const OutboxOperationCreate = "create"
+const OutboxOperationUpdate = "update"
+const OutboxOperationUpload = "upload"
+
+const OutboxStatusPending = "pending"
+const OutboxStatusSynced = "synced"
+
+// Skipped because the services held a conflicting change.
+const OutboxStatusConflict = "conflict"
+
+// Given up on after reaching the maximum number of attempts.
+const OutboxStatusFailed = "failed"
+
+const outboxLogFile = "outbox.ndjson"
+const outboxUploadsDir = "uploads"
+
+// An operation recorded while offline, along with how replaying it went.
+type OutboxOperation struct {
+	Id          string          `json:"id"`
+	Sequence    int             `json:"sequence"`
+	Operation   string          `json:"operation"`
+	EntityType  string          `json:"entityType"`
+	EntityId    string          `json:"entityId"`
+	Entity      json.RawMessage `json:"entity,omitempty"`
+	ContentType string          `json:"contentType,omitempty"`
+	RecordedAt  WindAMSTime     `json:"recordedAt"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts,omitempty"`
+	LastError   string          `json:"lastError,omitempty"`
+	SyncedAt    *WindAMSTime    `json:"syncedAt,omitempty"`
+	// Set when the outbox gave the created entity its id, which is only sent when the environment generates ids.
+	GeneratedId bool `json:"generatedId,omitempty"`
+	// The id the services gave the created entity, when not EntityId.  Later operations are rewritten to use it.
+	CreatedId string `json:"createdId,omitempty"`
+}
+
+type ReplayOptions struct {
+	// What to do when the services hold a conflicting change: ConflictSkip (the default) marks the operation as a
+	// conflict and carries on, ConflictOverwrite replaces the server's copy with the recorded one and ConflictFail
+	// stops the replay, leaving the operation pending.
+	OnConflict string
+	// Give up on an operation failing for other reasons after this many attempts, marking it failed, rather than
+	// stopping the replay.  Zero means never give up.
+	MaxAttempts int
+}
+
+type ReplayReport struct {
+	// The operations attempted, in order, with their status afterwards.
+	Operations []OutboxOperation
+}
+
+// A durable queue of Create, Update and Upload operations made while disconnected from the services, replayed in the
+// order they were recorded once a connection is available.  The queue lives in a directory: every change to an
+// operation is appended to outbox.ndjson as a line of JSON, and uploaded content is kept under uploads/.  Close must
+// be called when done.
+type Outbox struct {
+	dir     string
+	mu      sync.Mutex
+	ops     []*OutboxOperation
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// How the outbox creates, updates and checks the version of each type of entity it accepts.
+type outboxEntity struct {
+	newObj     func() interface{}
+	id         func(obj interface{}) **string
+	create     func(env *Environment, obj interface{}) error
+	update     func(env *Environment, obj interface{}) error
+	version    func(env *Environment, id string) (*int64, error)
+	versionOf  func(obj interface{}) *int64
+	setVersion func(obj interface{}, version *int64)
+}
+
+var outboxEntities = map[string]outboxEntity{
+	EntityTypeAssetInspection: {
+		newObj: func() interface{} { return new(AssetInspection) },
+		id:     func(obj interface{}) **string { return &obj.(*AssetInspection).Id },
+		create: func(env *Environment, obj interface{}) error {
+			return env.AssetInspectionServiceClient().Create(obj.(*AssetInspection))
+		},
+		update: func(env *Environment, obj interface{}) error {
+			return env.AssetInspectionServiceClient().Update(obj.(*AssetInspection))
+		},
+		version: func(env *Environment, id string) (*int64, error) {
+			obj, err := env.AssetInspectionServiceClient().Get(id)
+			if err != nil {
+				return nil, err
+			}
+			return obj.Version, nil
+		},
+		versionOf:  func(obj interface{}) *int64 { return obj.(*AssetInspection).Version },
+		setVersion: func(obj interface{}, version *int64) { obj.(*AssetInspection).Version = version },
+	},
+	EntityTypeComponentInspection: {
+		newObj: func() interface{} { return new(ComponentInspection) },
+		id:     func(obj interface{}) **string { return &obj.(*ComponentInspection).Id },
+		create: func(env *Environment, obj interface{}) error {
+			return env.ComponentInspectionServiceClient().Create(obj.(*ComponentInspection))
+		},
+		update: func(env *Environment, obj interface{}) error {
+			return env.ComponentInspectionServiceClient().Update(obj.(*ComponentInspection))
+		},
+		version: func(env *Environment, id string) (*int64, error) {
+			obj, err := env.ComponentInspectionServiceClient().Get(id)
+			if err != nil {
+				return nil, err
+			}
+			return obj.Version, nil
+		},
+		versionOf:  func(obj interface{}) *int64 { return obj.(*ComponentInspection).Version },
+		setVersion: func(obj interface{}, version *int64) { obj.(*ComponentInspection).Version = version },
+	},
+	// Resource metadata and inspection event resources are saved whole and carry no version, so never conflict.  See
+	// ResourceServiceClient.Save and InspectionEventResourceServiceClient.Save.
+	EntityTypeResource: {
+		newObj: func() interface{} { return new(ResourceMetadata) },
+		id:     func(obj interface{}) **string { return &obj.(*ResourceMetadata).ResourceId },
+		create: func(env *Environment, obj interface{}) error {
+			return env.ResourceServiceClient().Save(obj.(*ResourceMetadata))
+		},
+		update: func(env *Environment, obj interface{}) error {
+			return env.ResourceServiceClient().Save(obj.(*ResourceMetadata))
+		},
+	},
+	EntityTypeInspectionEventResource: {
+		newObj: func() interface{} { return new(InspectionEventResource) },
+		id:     func(obj interface{}) **string { return &obj.(*InspectionEventResource).Id },
+		create: func(env *Environment, obj interface{}) error {
+			return env.InspectionEventResourceServiceClient().Save(obj.(*InspectionEventResource))
+		},
+		update: func(env *Environment, obj interface{}) error {
+			return env.InspectionEventResourceServiceClient().Save(obj.(*InspectionEventResource))
+		},
+	},
+}
+
+// Opens the outbox in a directory, creating it if need be.  A truncated final line, as left by an interrupted
+// write, is ignored.
+func OpenOutbox(dir string) (*Outbox, error) {
+	if err := os.MkdirAll(filepath.Join(dir, outboxUploadsDir), 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, outboxLogFile)
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	o := &Outbox{dir: dir}
+	byId := make(map[string]*OutboxOperation)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		op := new(OutboxOperation)
+		if json.Unmarshal(line, op) != nil || op.Id == "" {
+			continue
+		}
+		// Later lines record changes to an operation's status.
+		if prev, ok := byId[op.Id]; ok {
+			*prev = *op
+			continue
+		}
+		byId[op.Id] = op
+		o.ops = append(o.ops, op)
+	}
+	sort.SliceStable(o.ops, func(i, j int) bool { return o.ops[i].Sequence < o.ops[j].Sequence })
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	// Start a fresh line after a truncated one.
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		if _, err = f.Write([]byte("\n")); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	o.file = f
+	o.encoder = json.NewEncoder(f)
+	return o, nil
+}
+
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	o.encoder = nil
+	return err
+}
+
+// Records the creation of an entity.  An entity without an id is given a new one, so that operations recorded later
+// can refer to it.  Unless the environment replayed against generates ids, the services assign the entity's id and
+// the operations which refer to it are rewritten to match.
+func (o *Outbox) Create(entityType string, obj interface{}) error {
+	return o.record(OutboxOperationCreate, entityType, obj)
+}
+
+// Records an update of an entity.  Once an earlier operation on the entity has been replayed, the update is made
+// against the version that operation saved rather than the one recorded, so that successive updates of a copy held
+// offline do not conflict with each other.
+func (o *Outbox) Update(entityType string, obj interface{}) error {
+	return o.record(OutboxOperationUpdate, entityType, obj)
+}
+
+func (o *Outbox) record(operation string, entityType string, obj interface{}) error {
+	handler, ok := outboxEntities[entityType]
+	if !ok {
+		return fmt.Errorf("The outbox does not accept %s entities", entityType)
+	}
+	id := handler.id(obj)
+	generated := *id == nil
+	if generated {
+		if operation != OutboxOperationCreate {
+			return fmt.Errorf("Unable to record an update of a %s without an id", entityType)
+		}
+		newId := NewUUID()
+		*id = &newId
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return o.append(&OutboxOperation{Operation: operation, EntityType: entityType, EntityId: **id, GeneratedId: generated, Entity: data})
+}
+
+// Records the upload of a resource's content, which is read in full and kept until it has been replayed.
+func (o *Outbox) Upload(resourceId string, contentType string, body io.Reader) error {
+	op := &OutboxOperation{
+		Id:          NewUUID(),
+		Operation:   OutboxOperationUpload,
+		EntityType:  EntityTypeResource,
+		EntityId:    resourceId,
+		ContentType: contentType,
+	}
+	f, err := os.Create(o.uploadPath(op.Id))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, body)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(o.uploadPath(op.Id))
+		return err
+	}
+	return o.append(op)
+}
+
+func (o *Outbox) uploadPath(opId string) string {
+	return filepath.Join(o.dir, outboxUploadsDir, opId)
+}
+
+func (o *Outbox) append(op *OutboxOperation) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if op.Id == "" {
+		op.Id = NewUUID()
+	}
+	op.Sequence = 1
+	if len(o.ops) > 0 {
+		op.Sequence = o.ops[len(o.ops)-1].Sequence + 1
+	}
+	op.RecordedAt = WindAMSTime(time.Now())
+	op.Status = OutboxStatusPending
+	if err := o.write(op); err != nil {
+		return err
+	}
+	o.ops = append(o.ops, op)
+	return nil
+}
+
+// Must be called with the lock held.
+func (o *Outbox) write(op *OutboxOperation) error {
+	if o.encoder == nil {
+		return fmt.Errorf("The outbox in %s is closed", o.dir)
+	}
+	if err := o.encoder.Encode(op); err != nil {
+		return err
+	}
+	return o.file.Sync()
+}
+
+// Copies of all the operations in the order they were recorded.
+func (o *Outbox) Operations() []OutboxOperation {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	ops := make([]OutboxOperation, len(o.ops))
+	for i, op := range o.ops {
+		ops[i] = *op
+	}
+	return ops
+}
+
+// The number of operations still to be replayed.
+func (o *Outbox) Pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := 0
+	for _, op := range o.ops {
+		if op.Status == OutboxStatusPending {
+			n++
+		}
+	}
+	return n
+}
+
+// Replays the pending operations in the order they were recorded.  The replay stops at the first operation which
+// fails, other than by conflict or by reaching options.MaxAttempts, since later operations may depend on it; calling
+// Replay again carries on from there.
+func (o *Outbox) Replay(env *Environment, options *ReplayOptions) (*ReplayReport, error) {
+	if options == nil {
+		options = &ReplayOptions{}
+	}
+	report := &ReplayReport{Operations: make([]OutboxOperation, 0)}
+	for _, op := range o.pendingOperations() {
+		createdId, version, err := o.replay(env, op, options)
+		o.mu.Lock()
+		op.Attempts++
+		switch {
+		case err == nil:
+			now := WindAMSTime(time.Now())
+			op.Status, op.LastError, op.SyncedAt = OutboxStatusSynced, "", &now
+			if createdId != op.EntityId {
+				op.CreatedId = createdId
+			}
+		case IsConflict(err) && options.OnConflict != ConflictFail:
+			op.Status, op.LastError = OutboxStatusConflict, err.Error()
+		case !IsConflict(err) && options.MaxAttempts > 0 && op.Attempts >= options.MaxAttempts:
+			op.Status, op.LastError = OutboxStatusFailed, err.Error()
+		default:
+			op.LastError = err.Error()
+		}
+		werr := o.write(op)
+		result := *op
+		o.mu.Unlock()
+		report.Operations = append(report.Operations, result)
+		if werr != nil {
+			return report, werr
+		}
+		switch result.Status {
+		case OutboxStatusPending:
+			return report, fmt.Errorf("Replay stopped at %s of %s %s: %w", result.Operation, result.EntityType, result.EntityId, err)
+		case OutboxStatusSynced:
+			if result.Operation == OutboxOperationUpload {
+				os.Remove(o.uploadPath(result.Id))
+			}
+			id := result.EntityId
+			if result.CreatedId != "" {
+				if err = o.remap(result.EntityType, result.EntityId, result.CreatedId); err != nil {
+					return report, err
+				}
+				id = result.CreatedId
+			}
+			if version != nil {
+				if err = o.advanceVersion(result.EntityType, id, *version); err != nil {
+					return report, err
+				}
+			}
+		default:
+			log.Printf("GOWINDAMS: Outbox %s of %s %s marked %s: %s", result.Operation, result.EntityType, result.EntityId, result.Status, err)
+		}
+	}
+	return report, nil
+}
+
+func (o *Outbox) pendingOperations() []*OutboxOperation {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	ops := make([]*OutboxOperation, 0)
+	for _, op := range o.ops {
+		if op.Status == OutboxStatusPending {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// Returns the id of the entity once saved, which for a create may be one assigned by the services, and its version
+// if the services returned one other than the version recorded.
+func (o *Outbox) replay(env *Environment, op *OutboxOperation, options *ReplayOptions) (string, *int64, error) {
+	if op.Operation == OutboxOperationUpload {
+		f, err := os.Open(o.uploadPath(op.Id))
+		if err != nil {
+			return "", nil, err
+		}
+		defer f.Close()
+		var body io.Reader = f
+		return op.EntityId, nil, env.ResourceServiceClient().Upload(op.EntityId, op.ContentType, &body)
+	}
+	handler, ok := outboxEntities[op.EntityType]
+	if !ok {
+		return "", nil, fmt.Errorf("The outbox does not accept %s entities", op.EntityType)
+	}
+	obj := handler.newObj()
+	if err := json.Unmarshal(op.Entity, obj); err != nil {
+		return "", nil, err
+	}
+	id := handler.id(obj)
+	// Copied, since the saved entity returned by the services is decoded through the same pointer.
+	var recorded *int64
+	if handler.versionOf != nil && handler.versionOf(obj) != nil {
+		v := *handler.versionOf(obj)
+		recorded = &v
+	}
+	var err error
+	if op.Operation == OutboxOperationCreate {
+		if op.GeneratedId && !env.GenerateIds {
+			*id = nil
+		}
+		err = handler.create(env, obj)
+	} else {
+		err = handler.update(env, obj)
+	}
+	if err == nil && *id == nil {
+		err = fmt.Errorf("The services did not return the id of the %s created", op.EntityType)
+	}
+	if IsConflict(err) && options.OnConflict == ConflictOverwrite && handler.version != nil {
+		// Overwrite the server's copy, whether created by an earlier replay or changed by someone else, by updating
+		// against its current version.
+		var version *int64
+		if version, err = handler.version(env, op.EntityId); err != nil {
+			return "", nil, err
+		}
+		*id = &op.EntityId
+		handler.setVersion(obj, version)
+		err = handler.update(env, obj)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if handler.versionOf != nil {
+		if version := handler.versionOf(obj); version != nil && (recorded == nil || *version != *recorded) {
+			return **id, version, nil
+		}
+	}
+	return **id, nil, nil
+}
+
+// Rewrites the pending updates of an entity to be made against the version it was just saved at.  They were recorded
+// against the copy held offline, whose version the earlier operations on the entity have since moved on.
+func (o *Outbox) advanceVersion(entityType string, id string, version int64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, op := range o.ops {
+		if op.Status != OutboxStatusPending || op.Operation != OutboxOperationUpdate || op.EntityType != entityType || op.EntityId != id {
+			continue
+		}
+		var fields map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(op.Entity))
+		dec.UseNumber()
+		if err := dec.Decode(&fields); err != nil {
+			return err
+		}
+		fields["version"] = version
+		entity, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		op.Entity = entity
+		if err = o.write(op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rewrites the pending operations on an entity, or referring to it, to use the id the services gave it in place of
+// the one recorded.  Recorded ids are UUIDs, so any top level field of an entity holding one, or a list holding one,
+// refers to that entity.
+func (o *Outbox) remap(entityType string, recordedId string, createdId string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, op := range o.ops {
+		if op.Status != OutboxStatusPending {
+			continue
+		}
+		changed := op.EntityType == entityType && op.EntityId == recordedId
+		if changed {
+			op.EntityId = createdId
+		}
+		if op.Entity != nil {
+			entity, remapped, err := remapIds(op.Entity, recordedId, createdId)
+			if err != nil {
+				return err
+			}
+			if remapped {
+				op.Entity, changed = entity, true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := o.write(op); err != nil {
+			return err
+		}
+	}
+	log.Printf("GOWINDAMS: Outbox %s %s was created as %s", entityType, recordedId, createdId)
+	return nil
+}
+
+func remapIds(entity json.RawMessage, from string, to string) (json.RawMessage, bool, error) {
+	var fields map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(entity))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return nil, false, err
+	}
+	remapped := false
+	for key, value := range fields {
+		switch v := value.(type) {
+		case string:
+			if v == from {
+				fields[key], remapped = to, true
+			}
+		case []interface{}:
+			for i := range v {
+				if v[i] == from {
+					v[i], remapped = to, true
+				}
+			}
+		}
+	}
+	if !remapped {
+		return entity, false, nil
+	}
+	data, err := json.Marshal(fields)
+	return data, true, err
+}
+
+// Removes synced operations, and those given up on, from the outbox, rewriting its file.
+func (o *Outbox) Purge() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return fmt.Errorf("The outbox in %s is closed", o.dir)
+	}
+	kept := make([]*OutboxOperation, 0)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, op := range o.ops {
+		if op.Status == OutboxStatusPending {
+			kept = append(kept, op)
+			if err := enc.Encode(op); err != nil {
+				return err
+			}
+		}
+	}
+	path := filepath.Join(o.dir, outboxLogFile)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	o.file.Close()
+	o.file, o.encoder = nil, nil
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	o.file, o.encoder = f, json.NewEncoder(f)
+	for _, op := range o.ops {
+		if op.Status != OutboxStatusPending && op.Operation == OutboxOperationUpload {
+			os.Remove(o.uploadPath(op.Id))
+		}
+	}
+	o.ops = kept
+	return nil
+}
+
+func (report *ReplayReport) Count(status string) int {
+	n := 0
+	for _, op := range report.Operations {
+		if op.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// A line per operation attempted, followed by a summary.
+func (report *ReplayReport) String() string {
+	var sb strings.Builder
+	for _, op := range report.Operations {
+		fmt.Fprintf(&sb, "%d: %s %s %s: %s", op.Sequence, op.Operation, op.EntityType, op.EntityId, op.Status)
+		if op.LastError != "" {
+			fmt.Fprintf(&sb, " (%s)", op.LastError)
+		}
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "%d operations: %d synced, %d conflicts, %d failed, %d pending\n", len(report.Operations),
+		report.Count(OutboxStatusSynced), report.Count(OutboxStatusConflict), report.Count(OutboxStatusFailed),
+		report.Count(OutboxStatusPending))
+	return sb.String()
+}
+
+// Clients with the same methods as the service clients which record operations in the outbox rather than calling the
+// services.
+type OutboxAssetInspectionClient struct{ outbox *Outbox }
+type OutboxComponentInspectionClient struct{ outbox *Outbox }
+type OutboxResourceClient struct{ outbox *Outbox }
+type OutboxInspectionEventResourceClient struct{ outbox *Outbox }
+
+func (o *Outbox) AssetInspectionServiceClient() OutboxAssetInspectionClient {
+	return OutboxAssetInspectionClient{outbox: o}
+}
+
+func (o *Outbox) ComponentInspectionServiceClient() OutboxComponentInspectionClient {
+	return OutboxComponentInspectionClient{outbox: o}
+}
+
+func (o *Outbox) ResourceServiceClient() OutboxResourceClient {
+	return OutboxResourceClient{outbox: o}
+}
+
+func (o *Outbox) InspectionEventResourceServiceClient() OutboxInspectionEventResourceClient {
+	return OutboxInspectionEventResourceClient{outbox: o}
+}
+
+func (client OutboxAssetInspectionClient) Create(obj *AssetInspection) error {
+	return client.outbox.Create(EntityTypeAssetInspection, obj)
+}
+
+func (client OutboxAssetInspectionClient) Update(obj *AssetInspection) error {
+	return client.outbox.Update(EntityTypeAssetInspection, obj)
+}
+
+func (client OutboxComponentInspectionClient) Create(obj *ComponentInspection) error {
+	return client.outbox.Create(EntityTypeComponentInspection, obj)
+}
+
+func (client OutboxComponentInspectionClient) Update(obj *ComponentInspection) error {
+	return client.outbox.Update(EntityTypeComponentInspection, obj)
+}
+
+// Resource metadata is recorded as created, with a new resource id, when it has none.
+func (client OutboxResourceClient) Save(rmeta *ResourceMetadata) error {
+	if rmeta.ResourceId == nil {
+		return client.outbox.Create(EntityTypeResource, rmeta)
+	}
+	return client.outbox.Update(EntityTypeResource, rmeta)
+}
+
+func (client OutboxResourceClient) Upload(resourceId string, contentType string, body *io.Reader) error {
+	return client.outbox.Upload(resourceId, contentType, *body)
+}
+
+func (client OutboxInspectionEventResourceClient) Save(obj *InspectionEventResource) error {
+	if obj.Id == nil {
+		return client.outbox.Create(EntityTypeInspectionEventResource, obj)
+	}
+	return client.outbox.Update(EntityTypeInspectionEventResource, obj)
+}
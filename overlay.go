@@ -0,0 +1,239 @@
+package gowindams
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+)
+
+const OverlayFormatPNG = "png"
+const OverlayFormatJPEG = "jpeg"
+const OverlayFormatSVG = "svg"
+
+var OverlayFormats = []string{OverlayFormatPNG, OverlayFormatJPEG, OverlayFormatSVG}
+
+type OverlayOptions struct {
+	// One of OverlayFormats, PNG when empty.
+	Format string
+	// Only draw the polygons recorded for this work order.
+	OrderNumber *string
+	// JPEG quality, DEFAULT_OVERLAY_JPEG_QUALITY when zero.
+	Quality int
+	// For SVG, where viewers find the image.  When empty the image is embedded in the SVG.
+	Href string
+}
+
+const DEFAULT_OVERLAY_JPEG_QUALITY = 90
+
+// A polygon as drawn over an image, in the image's pixels.
+type overlayShape struct {
+	points   [][2]float64
+	label    string
+	severity *int8
+	color    color.RGBA
+}
+
+var severityColors = []color.RGBA{
+	{R: 46, G: 160, B: 67, A: 255},
+	{R: 163, G: 190, B: 40, A: 255},
+	{R: 240, G: 200, B: 0, A: 255},
+	{R: 240, G: 120, B: 0, A: 255},
+	{R: 215, G: 25, B: 28, A: 255},
+}
+
+var unratedColor = color.RGBA{R: 128, G: 128, B: 128, A: 255}
+
+// The color used for a damage severity, from green for 1 to red for 5 and above.  Unrated damage is grey.
+func severityColor(severity *int8) color.RGBA {
+	switch {
+	case severity == nil || *severity <= 0:
+		return unratedColor
+	case int(*severity) > len(severityColors):
+		return severityColors[len(severityColors)-1]
+	default:
+		return severityColors[*severity-1]
+	}
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Maps the polygons onto an image of the given size in pixels.  A polygon without enough of an outline is drawn as a
+// small square around its center.  One with neither, or with a point outside the image, is left out, since it was
+// marked on some other image or in some other coordinate space.
+func overlayShapes(polygons []InspectionEventPolygon, width int, height int) []overlayShape {
+	shapes := make([]overlayShape, 0, len(polygons))
+	w, h := float64(width), float64(height)
+	for i := range polygons {
+		poly := &polygons[i]
+		outline := make([]GeoPoint, 0, len(poly.Geometry))
+		for _, p := range poly.Geometry {
+			if p.HasCoordinates() {
+				outline = append(outline, p)
+			}
+		}
+		if len(outline) < 3 {
+			outline = outline[:0]
+			if poly.Center != nil && poly.Center.HasCoordinates() {
+				outline = append(outline, *poly.Center)
+			}
+		}
+		if len(outline) == 0 {
+			continue
+		}
+		shape := overlayShape{label: stringValue(poly.Name), severity: poly.Severity, color: severityColor(poly.Severity)}
+		for _, p := range outline {
+			shape.points = append(shape.points, [2]float64{*p.Longitude, *p.Latitude})
+		}
+		if !shape.within(w, h) {
+			log.Printf("GOWINDAMS: Skipping polygon %q which lies outside the %dx%d image", shape.label, width, height)
+			continue
+		}
+		if len(shape.points) == 1 {
+			c, r := shape.points[0], math.Max(w, h)/100
+			shape.points = [][2]float64{{c[0] - r, c[1] - r}, {c[0] + r, c[1] - r}, {c[0] + r, c[1] + r}, {c[0] - r, c[1] + r}}
+		}
+		shapes = append(shapes, shape)
+	}
+	return shapes
+}
+
+func (s overlayShape) within(width float64, height float64) bool {
+	for _, p := range s.points {
+		if p[0] < 0 || p[0] > width || p[1] < 0 || p[1] > height {
+			return false
+		}
+	}
+	return true
+}
+
+// The top left corner of the shape's bounding box, where its label is placed.
+func (s overlayShape) labelAnchor() (float64, float64) {
+	x, y := math.Inf(1), math.Inf(1)
+	for _, p := range s.points {
+		x, y = math.Min(x, p[0]), math.Min(y, p[1])
+	}
+	return x, y
+}
+
+// The width of outlines and the height of labels, in pixels, so that both stay legible on large images.
+func overlayMetrics(width int, height int) (float64, float64) {
+	longest := math.Max(float64(width), float64(height))
+	return longest/400 + 1, math.Max(longest/50, 10)
+}
+
+// Writes an SVG drawing the image at href with the polygons over it.  The drawing uses the image's own pixel
+// coordinates, so scales with the image wherever it is displayed.
+func WriteSVGOverlay(w io.Writer, href string, width int, height int, polygons []InspectionEventPolygon) error {
+	stroke, fontSize := overlayMetrics(width, height)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" viewBox="0 0 %d %d" width="100%%">`, width, height)
+	if href != "" {
+		fmt.Fprintf(&sb, `<image xlink:href="%s" x="0" y="0" width="%d" height="%d"/>`, html.EscapeString(href), width, height)
+	}
+	fmt.Fprintf(&sb, `<g stroke-width="%.1f" fill-opacity="0.2" font-family="sans-serif" font-size="%.0f">`, stroke, fontSize)
+	for _, shape := range overlayShapes(polygons, width, height) {
+		c := hexColor(shape.color)
+		points := make([]string, len(shape.points))
+		for i, p := range shape.points {
+			points[i] = fmt.Sprintf("%.1f,%.1f", p[0], p[1])
+		}
+		fmt.Fprintf(&sb, `<polygon points="%s" fill="%s" stroke="%s"/>`, strings.Join(points, " "), c, c)
+		if shape.label != "" {
+			x, y := shape.labelAnchor()
+			fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" fill="%s" stroke="#ffffff" stroke-width="%.1f" paint-order="stroke">%s</text>`,
+				x, math.Max(y-stroke*2, fontSize), c, stroke, html.EscapeString(shape.label))
+		}
+	}
+	sb.WriteString("</g></svg>")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Downloads a resource and writes it with the polygons of its inspection event resources drawn over it.
+func RenderResourceOverlay(env *Environment, resourceId string, w io.Writer, options *OverlayOptions) error {
+	return renderResourceOverlay(environmentSource{env: env}, resourceId, w, options)
+}
+
+// Renders a resource held in a backup, as RenderResourceOverlay.
+func BackupResourceOverlay(backup *Backup, resourceId string, w io.Writer, options *OverlayOptions) error {
+	return renderResourceOverlay(backup, resourceId, w, options)
+}
+
+func renderResourceOverlay(source copySource, resourceId string, w io.Writer, options *OverlayOptions) error {
+	if options == nil {
+		options = &OverlayOptions{}
+	}
+	format := options.Format
+	if format == "" {
+		format = OverlayFormatPNG
+	}
+	if format != OverlayFormatPNG && format != OverlayFormatJPEG && format != OverlayFormatSVG {
+		return fmt.Errorf("Unknown overlay format %q, expected one of %s", format, strings.Join(OverlayFormats, ", "))
+	}
+	polygons, err := resourcePolygons(source, resourceId, options.OrderNumber)
+	if err != nil {
+		return err
+	}
+	body, err := source.download(resourceId)
+	if err != nil {
+		return fmt.Errorf("Unable to download resource %s: %w", resourceId, err)
+	}
+	data, err := ioutil.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return err
+	}
+	log.Printf("GOWINDAMS: Rendering %d polygons over resource %s as %s", len(polygons), resourceId, format)
+	if format == OverlayFormatSVG {
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("Unable to decode resource %s: %w", resourceId, err)
+		}
+		href := options.Href
+		if href == "" {
+			href = "data:" + http.DetectContentType(data) + ";base64," + base64.StdEncoding.EncodeToString(data)
+		}
+		return WriteSVGOverlay(w, href, config.Width, config.Height, polygons)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("Unable to decode resource %s: %w", resourceId, err)
+	}
+	rendered := RenderOverlay(img, polygons)
+	if format == OverlayFormatJPEG {
+		quality := options.Quality
+		if quality <= 0 {
+			quality = DEFAULT_OVERLAY_JPEG_QUALITY
+		}
+		return jpeg.Encode(w, rendered, &jpeg.Options{Quality: quality})
+	}
+	return png.Encode(w, rendered)
+}
+
+// The polygons marked on a resource, from all its inspection event resources or only those of a work order.
+func resourcePolygons(source copySource, resourceId string, orderNumber *string) ([]InspectionEventPolygon, error) {
+	iers, err := source.inspectionEventResources(resourceId)
+	if err != nil {
+		return nil, err
+	}
+	var polygons []InspectionEventPolygon
+	for _, ier := range iers {
+		if orderNumber == nil || ier.OrderNumber == nil || *ier.OrderNumber == *orderNumber {
+			polygons = append(polygons, ier.Polygons...)
+		}
+	}
+	return polygons, nil
+}
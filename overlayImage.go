@@ -0,0 +1,203 @@
+package gowindams
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+	"strings"
+)
+
+const overlayFillAlpha = 0.2
+
+// Draws the polygons over a copy of the image: each outlined and lightly filled in the color of its severity, and
+// labeled with its name.  Labels use a small built in font which only has capital letters, digits and common
+// punctuation, so names are drawn in capitals.
+func RenderOverlay(img image.Image, polygons []InspectionEventPolygon) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	stroke, fontSize := overlayMetrics(bounds.Dx(), bounds.Dy())
+	shapes := overlayShapes(polygons, bounds.Dx(), bounds.Dy())
+	for _, shape := range shapes {
+		fillPolygon(dst, shape.points, shape.color, overlayFillAlpha)
+	}
+	for _, shape := range shapes {
+		strokePolygon(dst, shape.points, stroke, shape.color)
+	}
+	// Labels go on top of every outline so that none is hidden by a neighbouring shape.
+	scale := int(math.Max(1, math.Round(fontSize/glyphHeight)))
+	for _, shape := range shapes {
+		if shape.label == "" {
+			continue
+		}
+		x, y := shape.labelAnchor()
+		top := math.Max(y-stroke*2, float64(glyphHeight*scale)) - float64(glyphHeight*scale)
+		drawLabel(dst, int(math.Round(x)), int(math.Round(top)), scale, shape.label, shape.color)
+	}
+	return dst
+}
+
+// Blends c into the pixels whose centers lie inside the polygon, by the even-odd rule.
+func fillPolygon(dst *image.RGBA, points [][2]float64, c color.RGBA, alpha float64) {
+	if len(points) < 3 {
+		return
+	}
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	b := dst.Bounds()
+	top, bottom := int(math.Max(math.Floor(minY), float64(b.Min.Y))), int(math.Min(math.Ceil(maxY), float64(b.Max.Y)))
+	crossings := make([]float64, 0, len(points))
+	for y := top; y < bottom; y++ {
+		cy := float64(y) + 0.5
+		crossings = crossings[:0]
+		for i := range points {
+			p, q := points[i], points[(i+1)%len(points)]
+			if (p[1] <= cy) != (q[1] <= cy) {
+				crossings = append(crossings, p[0]+(cy-p[1])*(q[0]-p[0])/(q[1]-p[1]))
+			}
+		}
+		sort.Float64s(crossings)
+		for i := 0; i+1 < len(crossings); i += 2 {
+			from := int(math.Max(math.Ceil(crossings[i]-0.5), float64(b.Min.X)))
+			to := int(math.Min(math.Ceil(crossings[i+1]-0.5), float64(b.Max.X)))
+			for x := from; x < to; x++ {
+				blend(dst, x, y, c, alpha)
+			}
+		}
+	}
+}
+
+// Outlines the closed polygon with lines of the given width, each edge drawn as a filled rectangle and each corner
+// rounded off with a disc.
+func strokePolygon(dst *image.RGBA, points [][2]float64, width float64, c color.RGBA) {
+	r := width / 2
+	for i := range points {
+		p, q := points[i], points[(i+1)%len(points)]
+		dx, dy := q[0]-p[0], q[1]-p[1]
+		length := math.Hypot(dx, dy)
+		if length > 0 {
+			nx, ny := -dy/length*r, dx/length*r
+			fillPolygon(dst, [][2]float64{{p[0] + nx, p[1] + ny}, {q[0] + nx, q[1] + ny}, {q[0] - nx, q[1] - ny}, {p[0] - nx, p[1] - ny}}, c, 1)
+		}
+		fillDisc(dst, p, r, c)
+	}
+}
+
+func fillDisc(dst *image.RGBA, center [2]float64, r float64, c color.RGBA) {
+	for y := int(math.Floor(center[1] - r)); y <= int(math.Ceil(center[1]+r)); y++ {
+		for x := int(math.Floor(center[0] - r)); x <= int(math.Ceil(center[0]+r)); x++ {
+			if math.Hypot(float64(x)+0.5-center[0], float64(y)+0.5-center[1]) <= r {
+				blend(dst, x, y, c, 1)
+			}
+		}
+	}
+}
+
+func blend(dst *image.RGBA, x int, y int, c color.RGBA, alpha float64) {
+	if !(image.Point{X: x, Y: y}.In(dst.Rect)) {
+		return
+	}
+	if alpha >= 1 {
+		dst.SetRGBA(x, y, c)
+		return
+	}
+	old := dst.RGBAAt(x, y)
+	mix := func(a uint8, b uint8) uint8 {
+		return uint8(math.Round(float64(a)*(1-alpha) + float64(b)*alpha))
+	}
+	dst.SetRGBA(x, y, color.RGBA{R: mix(old.R, c.R), G: mix(old.G, c.G), B: mix(old.B, c.B), A: mix(old.A, c.A)})
+}
+
+// Draws text with its top left corner at x, y on a white background, each pixel of the font scaled to a square of
+// scale pixels.  The label is moved left when it would run off the right edge of the image.
+func drawLabel(dst *image.RGBA, x int, y int, scale int, text string, c color.RGBA) {
+	text = strings.ToUpper(text)
+	width := (len([]rune(text))*glyphAdvance - 1 + 2) * scale
+	height := (glyphHeight + 2) * scale
+	if x+width > dst.Rect.Max.X {
+		x = dst.Rect.Max.X - width
+	}
+	if x < 0 {
+		x = 0
+	}
+	draw.Draw(dst, image.Rect(x, y, x+width, y+height), image.NewUniform(color.White), image.Point{}, draw.Src)
+	pen := x + scale
+	for _, r := range text {
+		rows, ok := glyphs[r]
+		if !ok {
+			rows = glyphs['?']
+		}
+		for row, bits := range rows {
+			for col := 0; col < glyphWidth; col++ {
+				if bits&(1<<(glyphWidth-1-col)) != 0 {
+					px, py := pen+col*scale, y+(row+1)*scale
+					draw.Draw(dst, image.Rect(px, py, px+scale, py+scale), image.NewUniform(c), image.Point{}, draw.Src)
+				}
+			}
+		}
+		pen += glyphAdvance * scale
+	}
+}
+
+const glyphWidth = 5
+const glyphHeight = 7
+const glyphAdvance = glyphWidth + 1
+
+// A 5 by 7 pixel font.  Each row is a bit mask with the leftmost pixel in the highest of the five bits.
+var glyphs = map[rune][glyphHeight]uint8{
+	' ':  {},
+	'A':  {0x0e, 0x11, 0x11, 0x1f, 0x11, 0x11, 0x11},
+	'B':  {0x1e, 0x11, 0x11, 0x1e, 0x11, 0x11, 0x1e},
+	'C':  {0x0e, 0x11, 0x10, 0x10, 0x10, 0x11, 0x0e},
+	'D':  {0x1e, 0x11, 0x11, 0x11, 0x11, 0x11, 0x1e},
+	'E':  {0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x1f},
+	'F':  {0x1f, 0x10, 0x10, 0x1e, 0x10, 0x10, 0x10},
+	'G':  {0x0e, 0x11, 0x10, 0x17, 0x11, 0x11, 0x0f},
+	'H':  {0x11, 0x11, 0x11, 0x1f, 0x11, 0x11, 0x11},
+	'I':  {0x0e, 0x04, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'J':  {0x07, 0x02, 0x02, 0x02, 0x02, 0x12, 0x0c},
+	'K':  {0x11, 0x12, 0x14, 0x18, 0x14, 0x12, 0x11},
+	'L':  {0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x1f},
+	'M':  {0x11, 0x1b, 0x15, 0x15, 0x11, 0x11, 0x11},
+	'N':  {0x11, 0x11, 0x19, 0x15, 0x13, 0x11, 0x11},
+	'O':  {0x0e, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},
+	'P':  {0x1e, 0x11, 0x11, 0x1e, 0x10, 0x10, 0x10},
+	'Q':  {0x0e, 0x11, 0x11, 0x11, 0x15, 0x12, 0x0d},
+	'R':  {0x1e, 0x11, 0x11, 0x1e, 0x14, 0x12, 0x11},
+	'S':  {0x0f, 0x10, 0x10, 0x0e, 0x01, 0x01, 0x1e},
+	'T':  {0x1f, 0x04, 0x04, 0x04, 0x04, 0x04, 0x04},
+	'U':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x11, 0x0e},
+	'V':  {0x11, 0x11, 0x11, 0x11, 0x11, 0x0a, 0x04},
+	'W':  {0x11, 0x11, 0x11, 0x15, 0x15, 0x15, 0x0a},
+	'X':  {0x11, 0x11, 0x0a, 0x04, 0x0a, 0x11, 0x11},
+	'Y':  {0x11, 0x11, 0x11, 0x0a, 0x04, 0x04, 0x04},
+	'Z':  {0x1f, 0x01, 0x02, 0x04, 0x08, 0x10, 0x1f},
+	'0':  {0x0e, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0e},
+	'1':  {0x04, 0x0c, 0x04, 0x04, 0x04, 0x04, 0x0e},
+	'2':  {0x0e, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1f},
+	'3':  {0x1f, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0e},
+	'4':  {0x02, 0x06, 0x0a, 0x12, 0x1f, 0x02, 0x02},
+	'5':  {0x1f, 0x10, 0x1e, 0x01, 0x01, 0x11, 0x0e},
+	'6':  {0x06, 0x08, 0x10, 0x1e, 0x11, 0x11, 0x0e},
+	'7':  {0x1f, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8':  {0x0e, 0x11, 0x11, 0x0e, 0x11, 0x11, 0x0e},
+	'9':  {0x0e, 0x11, 0x11, 0x0f, 0x01, 0x02, 0x0c},
+	'-':  {0x00, 0x00, 0x00, 0x1f, 0x00, 0x00, 0x00},
+	'.':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x0c},
+	',':  {0x00, 0x00, 0x00, 0x00, 0x0c, 0x04, 0x08},
+	'(':  {0x02, 0x04, 0x08, 0x08, 0x08, 0x04, 0x02},
+	')':  {0x08, 0x04, 0x02, 0x02, 0x02, 0x04, 0x08},
+	'/':  {0x00, 0x01, 0x02, 0x04, 0x08, 0x10, 0x00},
+	':':  {0x00, 0x0c, 0x0c, 0x00, 0x0c, 0x0c, 0x00},
+	'#':  {0x0a, 0x0a, 0x1f, 0x0a, 0x1f, 0x0a, 0x0a},
+	'+':  {0x00, 0x04, 0x04, 0x1f, 0x04, 0x04, 0x00},
+	'_':  {0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1f},
+	'\'': {0x04, 0x04, 0x08, 0x00, 0x00, 0x00, 0x00},
+	'%':  {0x18, 0x19, 0x02, 0x04, 0x08, 0x13, 0x03},
+	'&':  {0x0c, 0x12, 0x14, 0x08, 0x15, 0x12, 0x0d},
+	'?':  {0x0e, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+}
@@ -0,0 +1,226 @@
+package gowindams
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// A minimal PDF writer, enough to lay out reports: text in the standard Helvetica fonts, lines, filled rectangles,
+// polygons and JPEG images.  Coordinates are in points from the bottom left corner of the page.
+type pdfDocument struct {
+	width  float64
+	height float64
+	pages  []*pdfPage
+	images [][]byte
+	sizes  [][2]int
+}
+
+type pdfPage struct {
+	content bytes.Buffer
+	images  map[int]bool
+}
+
+const pdfA4Width = 595.28
+const pdfA4Height = 841.89
+
+const pdfFontRegular = "F1"
+const pdfFontBold = "F2"
+
+func newPDFDocument(width float64, height float64) *pdfDocument {
+	return &pdfDocument{width: width, height: height}
+}
+
+func (d *pdfDocument) addPage() *pdfPage {
+	p := &pdfPage{images: make(map[int]bool)}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Adds a baseline JPEG with three color components, returning its index for pdfPage.image.
+func (d *pdfDocument) addJPEG(data []byte, width int, height int) int {
+	d.images = append(d.images, data)
+	d.sizes = append(d.sizes, [2]int{width, height})
+	return len(d.images) - 1
+}
+
+func (p *pdfPage) text(x float64, y float64, font string, size float64, c color.RGBA, s string) {
+	fmt.Fprintf(&p.content, "BT /%s %.1f Tf %s rg %.2f %.2f Td (%s) Tj ET\n", font, size, pdfColor(c), x, y, pdfString(s))
+}
+
+func (p *pdfPage) line(x1 float64, y1 float64, x2 float64, y2 float64, width float64, c color.RGBA) {
+	fmt.Fprintf(&p.content, "%s RG %.2f w %.2f %.2f m %.2f %.2f l S\n", pdfColor(c), width, x1, y1, x2, y2)
+}
+
+func (p *pdfPage) fillRect(x float64, y float64, w float64, h float64, c color.RGBA) {
+	fmt.Fprintf(&p.content, "%s rg %.2f %.2f %.2f %.2f re f\n", pdfColor(c), x, y, w, h)
+}
+
+func (p *pdfPage) strokePolygon(points [][2]float64, width float64, c color.RGBA) {
+	if len(points) == 0 {
+		return
+	}
+	fmt.Fprintf(&p.content, "%s RG %.2f w 1 j", pdfColor(c), width)
+	for i, pt := range points {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		fmt.Fprintf(&p.content, " %.2f %.2f %s", pt[0], pt[1], op)
+	}
+	p.content.WriteString(" s\n")
+}
+
+func (p *pdfPage) image(index int, x float64, y float64, w float64, h float64) {
+	p.images[index] = true
+	fmt.Fprintf(&p.content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, y, index)
+}
+
+func pdfColor(c color.RGBA) string {
+	return fmt.Sprintf("%.3f %.3f %.3f", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255)
+}
+
+// Encodes text for a PDF string in WinAnsiEncoding, the encoding of the fonts.  Characters it lacks become '?'.
+func pdfString(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		var b byte
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			sb.WriteByte('\\')
+			b = byte(r)
+		case r >= 32 && r < 127, r >= 0xa0 && r <= 0xff:
+			b = byte(r)
+		default:
+			b = pdfWinAnsi[r]
+			if b == 0 {
+				b = '?'
+			}
+		}
+		if b < 32 || b > 126 {
+			fmt.Fprintf(&sb, "\\%03o", b)
+		} else {
+			sb.WriteByte(b)
+		}
+	}
+	return sb.String()
+}
+
+var pdfWinAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96,
+	'—': 0x97, '™': 0x99,
+}
+
+// Widths of the printable ASCII characters in Helvetica, in thousandths of the font size.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// The width of text in points.  Bold text is taken as a little wider than regular text, which is close enough for
+// laying out reports.
+func pdfTextWidth(s string, font string, size float64) float64 {
+	total := 0
+	for _, r := range s {
+		if r >= 32 && r < 127 {
+			total += helveticaWidths[r-32]
+		} else {
+			total += 556
+		}
+	}
+	w := float64(total) * size / 1000
+	if font == pdfFontBold {
+		w *= 1.06
+	}
+	return w
+}
+
+// Breaks text into lines no wider than width, at spaces where possible.
+func pdfWrap(s string, font string, size float64, width float64) []string {
+	lines := make([]string, 0)
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && pdfTextWidth(candidate, font, size) > width {
+				lines = append(lines, line)
+				candidate = word
+			}
+			for pdfTextWidth(candidate, font, size) > width && len([]rune(candidate)) > 1 {
+				runes := []rune(candidate)
+				n := len(runes) - 1
+				for n > 1 && pdfTextWidth(string(runes[:n]), font, size) > width {
+					n--
+				}
+				lines = append(lines, string(runes[:n]))
+				candidate = string(runes[n:])
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Writes the document.  Objects are numbered: 1 the catalog, 2 the page tree, 3 and 4 the fonts, then the images,
+// then a page and its content stream for each page.
+func (d *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	offsets := make([]int, 0)
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s", len(offsets), body)
+		if stream != nil {
+			buf.WriteString("\nstream\n")
+			buf.Write(stream)
+			buf.WriteString("\nendstream")
+		}
+		buf.WriteString("\nendobj\n")
+	}
+	firstImage := 5
+	firstPage := firstImage + len(d.images)
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)), nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>", nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>", nil)
+	for i, data := range d.images {
+		object(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>",
+			d.sizes[i][0], d.sizes[i][1], len(data)), data)
+	}
+	for i, p := range d.pages {
+		xobjects := make([]string, 0, len(p.images))
+		for index := range d.images {
+			if p.images[index] {
+				xobjects = append(xobjects, fmt.Sprintf("/Im%d %d 0 R", index, firstImage+index))
+			}
+		}
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Contents %d 0 R "+
+			"/Resources << /Font << /%s 3 0 R /%s 4 0 R >> /XObject << %s >> >> >>",
+			d.width, d.height, firstPage+2*i+1, pdfFontRegular, pdfFontBold, strings.Join(xobjects, " ")), nil)
+		object(fmt.Sprintf("<< /Length %d >>", p.content.Len()), p.content.Bytes())
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
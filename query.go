@@ -0,0 +1,1035 @@
+package gowindams
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Optional search capabilities of the services.  Filters which the services do not support are applied client side
+// by the query builders, so that application code need not change as the services evolve.
+const SearchFeatureDateRange = "dateRange"
+const SearchFeatureMultiValue = "multiValue"
+const SearchFeatureNamePrefix = "namePrefix"
+const SearchFeatureSort = "sort"
+
+type AssetQuery struct {
+	query
+	client     AssetServiceClient
+	criteria   AssetSearchCriteria
+	types      []string
+	namePrefix *string
+}
+
+func (client AssetServiceClient) Query() *AssetQuery {
+	return &AssetQuery{query: query{env: client.env}, client: client}
+}
+
+func (q *AssetQuery) Site(siteId string) *AssetQuery {
+	q.criteria.SiteId = &siteId
+	return q
+}
+
+func (q *AssetQuery) Name(name string) *AssetQuery {
+	q.criteria.Name = &name
+	return q
+}
+
+func (q *AssetQuery) NamePrefix(prefix string) *AssetQuery {
+	q.namePrefix = &prefix
+	return q
+}
+
+func (q *AssetQuery) SerialNumber(serialNumber string) *AssetQuery {
+	q.criteria.SerialNumber = &serialNumber
+	return q
+}
+
+func (q *AssetQuery) Types(types ...string) *AssetQuery {
+	q.types = types
+	return q
+}
+
+func (q *AssetQuery) Make(value string) *AssetQuery {
+	return q.Where(func(obj *Asset) bool { return stringEquals(obj.Make, value) })
+}
+
+func (q *AssetQuery) Model(value string) *AssetQuery {
+	return q.Where(func(obj *Asset) bool { return stringEquals(obj.Model, value) })
+}
+
+func (q *AssetQuery) Attribute(key string, value string) *AssetQuery {
+	return q.Where(func(obj *Asset) bool { return attributeEquals(obj.Attributes, key, value) })
+}
+
+func (q *AssetQuery) Within(box GeoBoundingBox) *AssetQuery {
+	return q.Where(func(obj *Asset) bool { return obj.Location != nil && box.Contains(*obj.Location) })
+}
+
+func (q *AssetQuery) Where(predicate func(*Asset) bool) *AssetQuery {
+	q.where(func(obj interface{}) bool { return predicate(obj.(*Asset)) })
+	return q
+}
+
+func (q *AssetQuery) OrderBy(field string, direction string) *AssetQuery {
+	q.sort.SortOn(field, direction)
+	return q
+}
+
+// Restricts the results to one page, numbered from zero.  The services do the paging when they can apply every filter
+// and the sort order, otherwise the page is taken from the results once filtered and sorted locally.
+func (q *AssetQuery) Page(page int, pageSize int) *AssetQuery {
+	q.paging(page, pageSize)
+	return q
+}
+
+// The criteria sent to the services, along with the predicates which must be applied to the results locally.  Filters
+// the services cannot apply are withheld from the criteria and matched locally instead.  The other query builders
+// follow the same pattern.
+func (q *AssetQuery) build() (*AssetSearchCriteria, localFilters) {
+	c, withheld := q.criteria, AssetSearchCriteria{}
+	if q.sendsValues(q.types) {
+		c.AssetType, c.AssetTypes = multiValue(q.types)
+	} else {
+		withheld.AssetTypes = q.types
+	}
+	if q.supports(SearchFeatureNamePrefix) {
+		c.NamePrefix = q.namePrefix
+	} else {
+		withheld.NamePrefix = q.namePrefix
+	}
+	local := q.filters(&withheld, func(obj interface{}) bool { return withheld.Matches(obj.(*Asset)) })
+	q.finish(&c.SearchPage, &c.SearchSort, local)
+	return &c, local
+}
+
+func (q *AssetQuery) Criteria() *AssetSearchCriteria {
+	c, _ := q.build()
+	return c
+}
+
+func (q *AssetQuery) Matches(obj *Asset) bool {
+	_, local := q.build()
+	return local.matches(obj)
+}
+
+func (q *AssetQuery) Find() ([]Asset, error) {
+	c, local := q.build()
+	found, err := q.client.Search(c)
+	if err != nil {
+		return nil, err
+	}
+	results, err := q.complete(found, local)
+	if err != nil {
+		return nil, err
+	}
+	return results.([]Asset), nil
+}
+
+type ComponentQuery struct {
+	query
+	client   ComponentServiceClient
+	criteria ComponentSearchCriteria
+	types    []string
+}
+
+func (client ComponentServiceClient) Query() *ComponentQuery {
+	return &ComponentQuery{query: query{env: client.env}, client: client}
+}
+
+func (q *ComponentQuery) Site(siteId string) *ComponentQuery {
+	q.criteria.SiteId = &siteId
+	return q
+}
+
+func (q *ComponentQuery) Asset(assetId string) *ComponentQuery {
+	q.criteria.AssetId = &assetId
+	return q
+}
+
+func (q *ComponentQuery) SerialNumber(serialNumber string) *ComponentQuery {
+	q.criteria.SerialNumber = &serialNumber
+	return q
+}
+
+func (q *ComponentQuery) Types(types ...string) *ComponentQuery {
+	q.types = types
+	return q
+}
+
+func (q *ComponentQuery) Make(value string) *ComponentQuery {
+	return q.Where(func(obj *Component) bool { return stringEquals(obj.Make, value) })
+}
+
+func (q *ComponentQuery) Model(value string) *ComponentQuery {
+	return q.Where(func(obj *Component) bool { return stringEquals(obj.Model, value) })
+}
+
+func (q *ComponentQuery) Attribute(key string, value string) *ComponentQuery {
+	return q.Where(func(obj *Component) bool { return attributeEquals(obj.Attributes, key, value) })
+}
+
+func (q *ComponentQuery) Where(predicate func(*Component) bool) *ComponentQuery {
+	q.where(func(obj interface{}) bool { return predicate(obj.(*Component)) })
+	return q
+}
+
+func (q *ComponentQuery) OrderBy(field string, direction string) *ComponentQuery {
+	q.sort.SortOn(field, direction)
+	return q
+}
+
+func (q *ComponentQuery) Page(page int, pageSize int) *ComponentQuery {
+	q.paging(page, pageSize)
+	return q
+}
+
+func (q *ComponentQuery) build() (*ComponentSearchCriteria, localFilters) {
+	c, withheld := q.criteria, ComponentSearchCriteria{}
+	if q.sendsValues(q.types) {
+		c.ComponentType, c.ComponentTypes = multiValue(q.types)
+	} else {
+		withheld.ComponentTypes = q.types
+	}
+	local := q.filters(&withheld, func(obj interface{}) bool { return withheld.Matches(obj.(*Component)) })
+	q.finish(&c.SearchPage, &c.SearchSort, local)
+	return &c, local
+}
+
+func (q *ComponentQuery) Criteria() *ComponentSearchCriteria {
+	c, _ := q.build()
+	return c
+}
+
+func (q *ComponentQuery) Matches(obj *Component) bool {
+	_, local := q.build()
+	return local.matches(obj)
+}
+
+func (q *ComponentQuery) Find() ([]Component, error) {
+	c, local := q.build()
+	found, err := q.client.Search(c)
+	if err != nil {
+		return nil, err
+	}
+	results, err := q.complete(found, local)
+	if err != nil {
+		return nil, err
+	}
+	return results.([]Component), nil
+}
+
+type AssetInspectionQuery struct {
+	query
+	client   AssetInspectionServiceClient
+	criteria AssetInspectionSearchCriteria
+	statuses []string
+	types    []string
+	from     *WindAMSDate
+	to       *WindAMSDate
+}
+
+func (client AssetInspectionServiceClient) Query() *AssetInspectionQuery {
+	return &AssetInspectionQuery{query: query{env: client.env}, client: client}
+}
+
+func (q *AssetInspectionQuery) Site(siteId string) *AssetInspectionQuery {
+	q.criteria.SiteId = &siteId
+	return q
+}
+
+func (q *AssetInspectionQuery) Asset(assetId string) *AssetInspectionQuery {
+	q.criteria.AssetId = &assetId
+	return q
+}
+
+func (q *AssetInspectionQuery) OrderNumber(orderNumber string) *AssetInspectionQuery {
+	q.criteria.OrderNumber = &orderNumber
+	return q
+}
+
+func (q *AssetInspectionQuery) Statuses(statuses ...string) *AssetInspectionQuery {
+	q.statuses = statuses
+	return q
+}
+
+func (q *AssetInspectionQuery) Types(types ...string) *AssetInspectionQuery {
+	q.types = types
+	return q
+}
+
+// Restricts the results to inspections performed between the dates, inclusive.  Either may be nil.
+func (q *AssetInspectionQuery) InspectedBetween(from *WindAMSDate, to *WindAMSDate) *AssetInspectionQuery {
+	q.from, q.to = from, to
+	return q
+}
+
+func (q *AssetInspectionQuery) Attribute(key string, value string) *AssetInspectionQuery {
+	return q.Where(func(obj *AssetInspection) bool { return attributeEquals(obj.Attributes, key, value) })
+}
+
+func (q *AssetInspectionQuery) Where(predicate func(*AssetInspection) bool) *AssetInspectionQuery {
+	q.where(func(obj interface{}) bool { return predicate(obj.(*AssetInspection)) })
+	return q
+}
+
+func (q *AssetInspectionQuery) OrderBy(field string, direction string) *AssetInspectionQuery {
+	q.sort.SortOn(field, direction)
+	return q
+}
+
+func (q *AssetInspectionQuery) Page(page int, pageSize int) *AssetInspectionQuery {
+	q.paging(page, pageSize)
+	return q
+}
+
+func (q *AssetInspectionQuery) build() (*AssetInspectionSearchCriteria, localFilters) {
+	c, withheld := q.criteria, AssetInspectionSearchCriteria{}
+	if q.sendsValues(q.statuses) {
+		c.Status, c.Statuses = multiValue(q.statuses)
+	} else {
+		withheld.Statuses = q.statuses
+	}
+	if len(q.types) == 0 || q.supports(SearchFeatureMultiValue) {
+		c.Types = q.types
+	} else {
+		withheld.Types = q.types
+	}
+	if q.supports(SearchFeatureDateRange) {
+		c.DateOfInspectionFrom, c.DateOfInspectionTo = q.from, q.to
+	} else {
+		withheld.DateOfInspectionFrom, withheld.DateOfInspectionTo = q.from, q.to
+	}
+	local := q.filters(&withheld, func(obj interface{}) bool { return withheld.Matches(obj.(*AssetInspection)) })
+	q.finish(&c.SearchPage, &c.SearchSort, local)
+	return &c, local
+}
+
+func (q *AssetInspectionQuery) Criteria() *AssetInspectionSearchCriteria {
+	c, _ := q.build()
+	return c
+}
+
+func (q *AssetInspectionQuery) Matches(obj *AssetInspection) bool {
+	_, local := q.build()
+	return local.matches(obj)
+}
+
+func (q *AssetInspectionQuery) Find() ([]AssetInspection, error) {
+	c, local := q.build()
+	found, err := q.client.Search(c)
+	if err != nil {
+		return nil, err
+	}
+	results, err := q.complete(found, local)
+	if err != nil {
+		return nil, err
+	}
+	return results.([]AssetInspection), nil
+}
+
+type ComponentInspectionQuery struct {
+	query
+	client   ComponentInspectionServiceClient
+	criteria ComponentInspectionSearchCriteria
+	statuses []string
+	types    []string
+}
+
+func (client ComponentInspectionServiceClient) Query() *ComponentInspectionQuery {
+	return &ComponentInspectionQuery{query: query{env: client.env}, client: client}
+}
+
+func (q *ComponentInspectionQuery) Site(siteId string) *ComponentInspectionQuery {
+	q.criteria.SiteId = &siteId
+	return q
+}
+
+func (q *ComponentInspectionQuery) Component(componentId string) *ComponentInspectionQuery {
+	q.criteria.ComponentId = &componentId
+	return q
+}
+
+func (q *ComponentInspectionQuery) OrderNumber(orderNumber string) *ComponentInspectionQuery {
+	q.criteria.OrderNumber = &orderNumber
+	return q
+}
+
+// Restricts the results to inspections whose current status is one of those given.
+func (q *ComponentInspectionQuery) Statuses(statuses ...string) *ComponentInspectionQuery {
+	q.statuses = statuses
+	return q
+}
+
+func (q *ComponentInspectionQuery) Types(types ...string) *ComponentInspectionQuery {
+	q.types = types
+	return q
+}
+
+func (q *ComponentInspectionQuery) Attribute(key string, value string) *ComponentInspectionQuery {
+	return q.Where(func(obj *ComponentInspection) bool { return attributeEquals(obj.Attributes, key, value) })
+}
+
+func (q *ComponentInspectionQuery) Where(predicate func(*ComponentInspection) bool) *ComponentInspectionQuery {
+	q.where(func(obj interface{}) bool { return predicate(obj.(*ComponentInspection)) })
+	return q
+}
+
+func (q *ComponentInspectionQuery) OrderBy(field string, direction string) *ComponentInspectionQuery {
+	q.sort.SortOn(field, direction)
+	return q
+}
+
+func (q *ComponentInspectionQuery) Page(page int, pageSize int) *ComponentInspectionQuery {
+	q.paging(page, pageSize)
+	return q
+}
+
+func (q *ComponentInspectionQuery) build() (*ComponentInspectionSearchCriteria, localFilters) {
+	c, withheld := q.criteria, ComponentInspectionSearchCriteria{}
+	if q.sendsValues(q.statuses) {
+		c.Status, c.Statuses = multiValue(q.statuses)
+	} else {
+		withheld.Statuses = q.statuses
+	}
+	if len(q.types) == 0 || q.supports(SearchFeatureMultiValue) {
+		c.Types = q.types
+	} else {
+		withheld.Types = q.types
+	}
+	local := q.filters(&withheld, func(obj interface{}) bool { return withheld.Matches(obj.(*ComponentInspection)) })
+	q.finish(&c.SearchPage, &c.SearchSort, local)
+	return &c, local
+}
+
+func (q *ComponentInspectionQuery) Criteria() *ComponentInspectionSearchCriteria {
+	c, _ := q.build()
+	return c
+}
+
+func (q *ComponentInspectionQuery) Matches(obj *ComponentInspection) bool {
+	_, local := q.build()
+	return local.matches(obj)
+}
+
+func (q *ComponentInspectionQuery) Find() ([]ComponentInspection, error) {
+	c, local := q.build()
+	found, err := q.client.Search(c)
+	if err != nil {
+		return nil, err
+	}
+	results, err := q.complete(found, local)
+	if err != nil {
+		return nil, err
+	}
+	return results.([]ComponentInspection), nil
+}
+
+type ResourceQuery struct {
+	query
+	client       ResourceServiceClient
+	criteria     ResourceSearchCriteria
+	statuses     []string
+	contentTypes []string
+	from         *WindAMSTime
+	to           *WindAMSTime
+}
+
+func (client ResourceServiceClient) Query() *ResourceQuery {
+	return &ResourceQuery{query: query{env: client.env}, client: client}
+}
+
+func (q *ResourceQuery) Site(siteId string) *ResourceQuery {
+	q.criteria.SiteId = &siteId
+	return q
+}
+
+func (q *ResourceQuery) Asset(assetId string) *ResourceQuery {
+	q.criteria.AssetId = &assetId
+	return q
+}
+
+func (q *ResourceQuery) AssetInspection(assetInspectionId string) *ResourceQuery {
+	q.criteria.AssetInspectionId = &assetInspectionId
+	return q
+}
+
+func (q *ResourceQuery) Component(componentId string) *ResourceQuery {
+	q.criteria.ComponentId = &componentId
+	return q
+}
+
+func (q *ResourceQuery) ComponentInspection(componentInspectionId string) *ResourceQuery {
+	q.criteria.ComponentInspectionId = &componentInspectionId
+	return q
+}
+
+func (q *ResourceQuery) OrderNumber(orderNumber string) *ResourceQuery {
+	q.criteria.OrderNumber = &orderNumber
+	return q
+}
+
+func (q *ResourceQuery) Statuses(statuses ...string) *ResourceQuery {
+	q.statuses = statuses
+	return q
+}
+
+func (q *ResourceQuery) ContentTypes(contentTypes ...string) *ResourceQuery {
+	q.contentTypes = contentTypes
+	return q
+}
+
+// Restricts the results to resources captured between the times, inclusive.  Either may be nil.
+func (q *ResourceQuery) CapturedBetween(from *WindAMSTime, to *WindAMSTime) *ResourceQuery {
+	q.from, q.to = from, to
+	return q
+}
+
+func (q *ResourceQuery) Side(side string) *ResourceQuery {
+	return q.Where(func(obj *ResourceMetadata) bool { return obj.Position != nil && stringEquals(obj.Position.Side, side) })
+}
+
+func (q *ResourceQuery) Within(box GeoBoundingBox) *ResourceQuery {
+	return q.Where(func(obj *ResourceMetadata) bool { return obj.Location != nil && box.Contains(*obj.Location) })
+}
+
+func (q *ResourceQuery) Where(predicate func(*ResourceMetadata) bool) *ResourceQuery {
+	q.where(func(obj interface{}) bool { return predicate(obj.(*ResourceMetadata)) })
+	return q
+}
+
+func (q *ResourceQuery) OrderBy(field string, direction string) *ResourceQuery {
+	q.sort.SortOn(field, direction)
+	return q
+}
+
+func (q *ResourceQuery) Page(page int, pageSize int) *ResourceQuery {
+	q.paging(page, pageSize)
+	return q
+}
+
+func (q *ResourceQuery) build() (*ResourceSearchCriteria, localFilters) {
+	c, withheld := q.criteria, ResourceSearchCriteria{}
+	if q.sendsValues(q.statuses) {
+		c.Status, c.Statuses = multiValue(q.statuses)
+	} else {
+		withheld.Statuses = q.statuses
+	}
+	if len(q.contentTypes) == 0 || q.supports(SearchFeatureMultiValue) {
+		c.ContentTypes = q.contentTypes
+	} else {
+		withheld.ContentTypes = q.contentTypes
+	}
+	if q.supports(SearchFeatureDateRange) {
+		c.TimestampFrom, c.TimestampTo = q.from, q.to
+	} else {
+		withheld.TimestampFrom, withheld.TimestampTo = q.from, q.to
+	}
+	local := q.filters(&withheld, func(obj interface{}) bool { return withheld.Matches(obj.(*ResourceMetadata)) })
+	q.finish(&c.SearchPage, &c.SearchSort, local)
+	return &c, local
+}
+
+func (q *ResourceQuery) Criteria() *ResourceSearchCriteria {
+	c, _ := q.build()
+	return c
+}
+
+func (q *ResourceQuery) Matches(obj *ResourceMetadata) bool {
+	_, local := q.build()
+	return local.matches(obj)
+}
+
+func (q *ResourceQuery) Find() ([]ResourceMetadata, error) {
+	c, local := q.build()
+	found, err := q.client.Search(c)
+	if err != nil {
+		return nil, err
+	}
+	results, err := q.complete(found, local)
+	if err != nil {
+		return nil, err
+	}
+	return results.([]ResourceMetadata), nil
+}
+
+type WorkOrderQuery struct {
+	query
+	client   WorkOrderServiceClient
+	criteria WorkOrderSearchCriteria
+	types    []string
+	scopes   []string
+	from     *WindAMSDate
+	to       *WindAMSDate
+}
+
+func (client WorkOrderServiceClient) Query() *WorkOrderQuery {
+	return &WorkOrderQuery{query: query{env: client.env}, client: client}
+}
+
+func (q *WorkOrderQuery) Site(siteId string) *WorkOrderQuery {
+	q.criteria.SiteId = &siteId
+	return q
+}
+
+func (q *WorkOrderQuery) Statuses(statuses ...string) *WorkOrderQuery {
+	q.criteria.Statuses = statuses
+	return q
+}
+
+func (q *WorkOrderQuery) Types(types ...string) *WorkOrderQuery {
+	q.types = types
+	return q
+}
+
+func (q *WorkOrderQuery) Scopes(scopes ...string) *WorkOrderQuery {
+	q.scopes = scopes
+	return q
+}
+
+// Restricts the results to work orders requested between the dates, inclusive.  Either may be nil.
+func (q *WorkOrderQuery) RequestedBetween(from *WindAMSDate, to *WindAMSDate) *WorkOrderQuery {
+	q.from, q.to = from, to
+	return q
+}
+
+func (q *WorkOrderQuery) Where(predicate func(*WorkOrder) bool) *WorkOrderQuery {
+	q.where(func(obj interface{}) bool { return predicate(obj.(*WorkOrder)) })
+	return q
+}
+
+func (q *WorkOrderQuery) OrderBy(field string, direction string) *WorkOrderQuery {
+	q.sort.SortOn(field, direction)
+	return q
+}
+
+func (q *WorkOrderQuery) Page(page int, pageSize int) *WorkOrderQuery {
+	q.paging(page, pageSize)
+	return q
+}
+
+func (q *WorkOrderQuery) build() (*WorkOrderSearchCriteria, localFilters) {
+	c, withheld := q.criteria, WorkOrderSearchCriteria{}
+	if q.sendsValues(q.types) {
+		c.Type, c.Types = multiValue(q.types)
+	} else {
+		withheld.Types = q.types
+	}
+	if len(q.scopes) == 0 || q.supports(SearchFeatureMultiValue) {
+		c.Scopes = q.scopes
+	} else {
+		withheld.Scopes = q.scopes
+	}
+	if q.supports(SearchFeatureDateRange) {
+		c.RequestDateFrom, c.RequestDateTo = q.from, q.to
+	} else {
+		withheld.RequestDateFrom, withheld.RequestDateTo = q.from, q.to
+	}
+	local := q.filters(&withheld, func(obj interface{}) bool { return withheld.Matches(obj.(*WorkOrder)) })
+	q.finish(&c.SearchPage, &c.SearchSort, local)
+	return &c, local
+}
+
+func (q *WorkOrderQuery) Criteria() *WorkOrderSearchCriteria {
+	c, _ := q.build()
+	return c
+}
+
+func (q *WorkOrderQuery) Matches(obj *WorkOrder) bool {
+	_, local := q.build()
+	return local.matches(obj)
+}
+
+func (q *WorkOrderQuery) Find() ([]WorkOrder, error) {
+	c, local := q.build()
+	found, err := q.client.Search(c)
+	if err != nil {
+		return nil, err
+	}
+	results, err := q.complete(found, local)
+	if err != nil {
+		return nil, err
+	}
+	return results.([]WorkOrder), nil
+}
+
+type SiteQuery struct {
+	query
+	client     SiteServiceClient
+	criteria   SiteSearchCriteria
+	namePrefix *string
+}
+
+func (client SiteServiceClient) Query() *SiteQuery {
+	return &SiteQuery{query: query{env: client.env}, client: client}
+}
+
+func (q *SiteQuery) Organization(organizationId string) *SiteQuery {
+	q.criteria.OrganizationId = &organizationId
+	return q
+}
+
+func (q *SiteQuery) NamePrefix(prefix string) *SiteQuery {
+	q.namePrefix = &prefix
+	return q
+}
+
+func (q *SiteQuery) Where(predicate func(*Site) bool) *SiteQuery {
+	q.where(func(obj interface{}) bool { return predicate(obj.(*Site)) })
+	return q
+}
+
+func (q *SiteQuery) OrderBy(field string, direction string) *SiteQuery {
+	q.sort.SortOn(field, direction)
+	return q
+}
+
+func (q *SiteQuery) Page(page int, pageSize int) *SiteQuery {
+	q.paging(page, pageSize)
+	return q
+}
+
+func (q *SiteQuery) build() (*SiteSearchCriteria, localFilters) {
+	c, withheld := q.criteria, SiteSearchCriteria{}
+	if q.supports(SearchFeatureNamePrefix) {
+		c.NamePrefix = q.namePrefix
+	} else {
+		withheld.NamePrefix = q.namePrefix
+	}
+	local := q.filters(&withheld, func(obj interface{}) bool { return withheld.Matches(obj.(*Site)) })
+	q.finish(&c.SearchPage, &c.SearchSort, local)
+	return &c, local
+}
+
+func (q *SiteQuery) Criteria() *SiteSearchCriteria {
+	c, _ := q.build()
+	return c
+}
+
+func (q *SiteQuery) Matches(obj *Site) bool {
+	_, local := q.build()
+	return local.matches(obj)
+}
+
+func (q *SiteQuery) Find() ([]Site, error) {
+	c, local := q.build()
+	found, err := q.client.Search(c)
+	if err != nil {
+		return nil, err
+	}
+	results, err := q.complete(found, local)
+	if err != nil {
+		return nil, err
+	}
+	return results.([]Site), nil
+}
+
+// The paging, sort order and local filters common to the query builders, which each embed it.  Local filters take a
+// pointer to the builder's entity type.
+type query struct {
+	env   *Environment
+	page  SearchPage
+	sort  SearchSort
+	local localFilters
+}
+
+type localFilters []func(interface{}) bool
+
+func (q *query) where(predicate func(interface{}) bool) {
+	q.local = append(q.local, predicate)
+}
+
+func (q *query) paging(page int, pageSize int) {
+	q.page.Page, q.page.PageSize = &page, &pageSize
+}
+
+// The filters added with Where, along with matches when any of the criteria withheld from the services are set.
+// withheld points to the criteria and matches is their Matches method.
+func (q *query) filters(withheld interface{}, matches func(interface{}) bool) localFilters {
+	local := append(localFilters{}, q.local...)
+	if !reflect.ValueOf(withheld).Elem().IsZero() {
+		local = append(local, matches)
+	}
+	return local
+}
+
+func (q *query) supports(feature string) bool {
+	return q.env.SupportsSearchFeature(feature)
+}
+
+// Whether the services can take a multi-valued filter.  A single value always fits the original single-valued criteria
+// field, several values need the multiValue feature.
+func (q *query) sendsValues(values []string) bool {
+	return len(values) <= 1 || q.supports(SearchFeatureMultiValue)
+}
+
+// Completes built criteria with the sort order and page when the services are able to apply them.
+func (q *query) finish(page *SearchPage, order *SearchSort, local localFilters) {
+	if q.supports(SearchFeatureSort) {
+		*order = q.sort
+	}
+	if !q.pagedLocally(local) {
+		*page = q.page
+	}
+}
+
+// A page of the services' results is not a page of the query's once filters or sorting are applied locally.
+func (q *query) pagedLocally(local localFilters) bool {
+	return q.page.PageSize != nil &&
+		(len(local) > 0 || (q.sort.SortBy != nil && !q.supports(SearchFeatureSort)))
+}
+
+// Applies to a slice of search results whatever the services could not: the local filters, sort order and paging.
+func (q *query) complete(found interface{}, local localFilters) (interface{}, error) {
+	v := reflect.ValueOf(found)
+	results := reflect.MakeSlice(v.Type(), 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		if local.matches(v.Index(i).Addr().Interface()) {
+			results = reflect.Append(results, v.Index(i))
+		}
+	}
+	if err := sortLocally(q.env, results.Interface(), q.sort); err != nil {
+		return nil, err
+	}
+	if q.pagedLocally(local) {
+		lo, hi := q.page.bounds(results.Len())
+		results = results.Slice(lo, hi)
+	}
+	return results.Interface(), nil
+}
+
+func (local localFilters) matches(obj interface{}) bool {
+	for _, predicate := range local {
+		if !predicate(obj) {
+			return false
+		}
+	}
+	return true
+}
+
+// Splits the values of a multi-valued filter between a criteria's single and multi-valued fields.
+func multiValue(values []string) (*string, []string) {
+	switch len(values) {
+	case 0:
+		return nil, nil
+	case 1:
+		v := values[0]
+		return &v, nil
+	default:
+		return nil, values
+	}
+}
+
+// The Matches methods report whether an entity satisfies the criteria as the services would apply them, for filtering
+// locally what the services cannot and for searching a mirror.  Unset criteria match everything.
+
+func (c *SiteSearchCriteria) Matches(obj *Site) bool {
+	return optionalEquals(obj.OrganizationId, c.OrganizationId) &&
+		(c.NamePrefix == nil || stringHasPrefix(obj.Name, *c.NamePrefix))
+}
+
+func (c *WorkOrderSearchCriteria) Matches(obj *WorkOrder) bool {
+	return optionalEquals(obj.SiteId, c.SiteId) &&
+		optionalIn(obj.Status, c.Statuses) &&
+		optionalEquals(obj.Type, c.Type) &&
+		optionalIn(obj.Type, c.Types) &&
+		optionalIn(obj.Scope, c.Scopes) &&
+		((c.RequestDateFrom == nil && c.RequestDateTo == nil) || dateInRange(obj.RequestDate, c.RequestDateFrom, c.RequestDateTo))
+}
+
+func (c *AssetSearchCriteria) Matches(obj *Asset) bool {
+	return optionalEquals(obj.Id, c.AssetId) &&
+		optionalEquals(obj.SiteId, c.SiteId) &&
+		optionalEquals(obj.Type, c.AssetType) &&
+		optionalIn(obj.Type, c.AssetTypes) &&
+		optionalEquals(obj.Name, c.Name) &&
+		(c.NamePrefix == nil || stringHasPrefix(obj.Name, *c.NamePrefix)) &&
+		optionalEquals(obj.SerialNumber, c.SerialNumber)
+}
+
+func (c *ComponentSearchCriteria) Matches(obj *Component) bool {
+	return optionalEquals(obj.AssetId, c.AssetId) &&
+		optionalEquals(obj.Id, c.ComponentId) &&
+		optionalEquals(obj.SerialNumber, c.SerialNumber) &&
+		optionalEquals(obj.SiteId, c.SiteId) &&
+		optionalEquals(obj.Type, c.ComponentType) &&
+		optionalIn(obj.Type, c.ComponentTypes)
+}
+
+func (c *AssetInspectionSearchCriteria) Matches(obj *AssetInspection) bool {
+	return optionalEquals(obj.AssetId, c.AssetId) &&
+		optionalEquals(obj.SiteId, c.SiteId) &&
+		optionalEquals(obj.OrderNumber, c.OrderNumber) &&
+		optionalEquals(obj.Status, c.Status) &&
+		optionalIn(obj.Status, c.Statuses) &&
+		optionalIn(obj.Type, c.Types) &&
+		((c.DateOfInspectionFrom == nil && c.DateOfInspectionTo == nil) ||
+			dateInRange(obj.DateOfInspection, c.DateOfInspectionFrom, c.DateOfInspectionTo))
+}
+
+// Statuses are matched against the current status, the latest in the status history.
+func (c *ComponentInspectionSearchCriteria) Matches(obj *ComponentInspection) bool {
+	status := obj.CurrentStatus()
+	return optionalEquals(obj.ComponentId, c.ComponentId) &&
+		optionalEquals(obj.SiteId, c.SiteId) &&
+		optionalEquals(obj.OrderNumber, c.OrderNumber) &&
+		optionalEquals(status, c.Status) &&
+		optionalIn(status, c.Statuses) &&
+		optionalIn(obj.Type, c.Types)
+}
+
+func (c *ResourceSearchCriteria) Matches(obj *ResourceMetadata) bool {
+	return optionalEquals(obj.AssetId, c.AssetId) &&
+		optionalEquals(obj.AssetInspectionId, c.AssetInspectionId) &&
+		optionalEquals(obj.ComponentId, c.ComponentId) &&
+		optionalEquals(obj.ComponentInspectionId, c.ComponentInspectionId) &&
+		optionalIn(obj.ContentType, c.ContentTypes) &&
+		optionalEquals(obj.SourceResourceId, c.SourceResourceId) &&
+		optionalEquals(obj.OrderNumber, c.OrderNumber) &&
+		(c.Pass == nil || (obj.Pass != nil && *obj.Pass == *c.Pass)) &&
+		optionalEquals(obj.SiteId, c.SiteId) &&
+		optionalEquals(obj.Status, c.Status) &&
+		optionalIn(obj.Status, c.Statuses) &&
+		((c.TimestampFrom == nil && c.TimestampTo == nil) || timeInRange(obj.Timestamp, c.TimestampFrom, c.TimestampTo))
+}
+
+func (c *InspectionEventResourceSearchCriteria) Matches(obj *InspectionEventResource) bool {
+	return optionalEquals(obj.InspectionEventId, c.InspectionEventId) &&
+		optionalEquals(obj.ResourceId, c.ResourceId)
+}
+
+func optionalEquals(value *string, expected *string) bool {
+	return expected == nil || stringEquals(value, *expected)
+}
+
+func optionalIn(value *string, values []string) bool {
+	return len(values) == 0 || stringIn(value, values)
+}
+
+func stringEquals(value *string, expected string) bool {
+	return value != nil && *value == expected
+}
+
+func stringIn(value *string, values []string) bool {
+	if value == nil {
+		return false
+	}
+	for _, v := range values {
+		if *value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func stringHasPrefix(value *string, prefix string) bool {
+	return value != nil && strings.HasPrefix(*value, prefix)
+}
+
+func attributeEquals(attributes map[string]string, key string, value string) bool {
+	v, ok := attributes[key]
+	return ok && v == value
+}
+
+func dateInRange(value *WindAMSDate, from *WindAMSDate, to *WindAMSDate) bool {
+	if value == nil {
+		return false
+	}
+	t := value.Time()
+	return (from == nil || !t.Before(from.Time())) && (to == nil || !t.After(to.Time()))
+}
+
+func timeInRange(value *WindAMSTime, from *WindAMSTime, to *WindAMSTime) bool {
+	if value == nil {
+		return false
+	}
+	t := value.Time()
+	return (from == nil || !t.Before(from.Time())) && (to == nil || !t.After(to.Time()))
+}
+
+// Sorts a slice of entities on the field with the given JSON name when the services are unable to.  Nil values sort
+// first in ascending order.
+func sortLocally(env *Environment, slice interface{}, order SearchSort) error {
+	if order.SortBy == nil || env.SupportsSearchFeature(SearchFeatureSort) {
+		return nil
+	}
+	return sortSlice(slice, order)
+}
+
+func sortSlice(slice interface{}, order SearchSort) error {
+	if order.SortBy == nil {
+		return nil
+	}
+	v := reflect.ValueOf(slice)
+	index, ok := jsonFieldIndex(v.Type().Elem(), *order.SortBy)
+	if !ok {
+		return fmt.Errorf("Unable to sort %s on unknown field \"%s\"", v.Type().Elem().Name(), *order.SortBy)
+	}
+	descending := order.SortDirection != nil && strings.EqualFold(*order.SortDirection, SortDescending)
+	sort.SliceStable(slice, func(i, j int) bool {
+		c := compareValues(v.Index(i).Field(index), v.Index(j).Field(index))
+		if descending {
+			return c > 0
+		}
+		return c < 0
+	})
+	return nil
+}
+
+func jsonFieldIndex(t reflect.Type, name string) (int, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if tag == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func compareValues(a reflect.Value, b reflect.Value) int {
+	if a.Kind() == reflect.Ptr {
+		switch {
+		case a.IsNil() && b.IsNil():
+			return 0
+		case a.IsNil():
+			return -1
+		case b.IsNil():
+			return 1
+		}
+		a, b = a.Elem(), b.Elem()
+	}
+	if a.Type().ConvertibleTo(timeType) && a.Kind() == reflect.Struct {
+		ta := a.Convert(timeType).Interface().(time.Time)
+		tb := b.Convert(timeType).Interface().(time.Time)
+		switch {
+		case ta.Before(tb):
+			return -1
+		case ta.After(tb):
+			return 1
+		}
+		return 0
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareFloats(float64(a.Int()), float64(b.Int()))
+	case reflect.Float32, reflect.Float64:
+		return compareFloats(a.Float(), b.Float())
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	}
+	return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+}
+
+func compareFloats(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,149 @@
+package gowindams
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Set as the Err of a mismatch which was not updated because the asset inspection's status changed after it was
+// compared.
+var ErrStatusChanged = errors.New("The status changed while reconciling")
+
+type ReconcileOptions struct {
+	// Save the expected status to each asset inspection found to be out of step.
+	Update      bool
+	Parallelism int
+}
+
+// An asset inspection whose status does not match the one expected from its component inspections and resources.
+type AssetInspectionMismatch struct {
+	AssetInspection *AssetInspection
+	Current         string
+	Expected        string
+	Updated         bool
+	Err             error
+}
+
+func (m AssetInspectionMismatch) String() string {
+	return fmt.Sprintf("%s: %s, expected %s", stringValue(m.AssetInspection.Id), m.Current, m.Expected)
+}
+
+// The status an asset inspection should have given its component inspections and the number of resources captured
+// for it:
+//   - Pending until any resources have been uploaded or a component inspection has been started,
+//   - Uploaded once resources exist but no component inspection has a status,
+//   - In_Process while any component inspection is short of approval,
+//   - Processed once every component inspection has been approved or locked,
+//   - Released once every component inspection has been transmitted.
+func ExpectedAssetInspectionStatus(componentInspections []*ComponentInspection, resourceCount int) string {
+	least := -1
+	started := false
+	for _, ci := range componentInspections {
+		i := componentInspectionStatusIndex(stringValue(ci.CurrentStatus()))
+		if i >= 0 {
+			started = true
+		}
+		if least == -1 || i < least {
+			least = i
+		}
+	}
+	switch {
+	case !started && resourceCount == 0:
+		return ASSET_INSPECTION_STATUS_PENDING
+	case !started:
+		return ASSET_INSPECTION_STATUS_UPLOADED
+	case least < componentInspectionStatusIndex(COMP_INSPECTION_STATUS_APPROVED):
+		return ASSET_INSPECTION_STATUS_IN_PROCESS
+	case least < componentInspectionStatusIndex(COMP_INSPECTION_STATUS_TRANSMITTED):
+		return ASSET_INSPECTION_STATUS_PROCESSED
+	default:
+		return ASSET_INSPECTION_STATUS_RELEASED
+	}
+}
+
+// Compares the status of every asset inspection in a tree, which should have been loaded to SiteTreeDepthResources,
+// with the status expected from its component inspections and resources.
+func ReconcileSiteTree(tree *SiteTree) []AssetInspectionMismatch {
+	mismatches := make([]AssetInspectionMismatch, 0)
+	for _, asset := range tree.Assets {
+		for _, ain := range asset.Inspections {
+			cis := make([]*ComponentInspection, len(ain.ComponentInspections))
+			resourceCount := len(ain.Resources)
+			for i, cin := range ain.ComponentInspections {
+				cis[i] = cin.ComponentInspection
+				resourceCount += len(cin.Resources)
+			}
+			expected := ExpectedAssetInspectionStatus(cis, resourceCount)
+			current := stringValue(ain.AssetInspection.Status)
+			if current != expected {
+				mismatches = append(mismatches, AssetInspectionMismatch{
+					AssetInspection: ain.AssetInspection,
+					Current:         current,
+					Expected:        expected,
+				})
+			}
+		}
+	}
+	return mismatches
+}
+
+// Reconciles the asset inspections for a single work order.
+func ReconcileOrder(env *Environment, orderNumber string, options *ReconcileOptions) ([]AssetInspectionMismatch, error) {
+	wo, err := env.WorkOrderServiceClient().Get(orderNumber)
+	if err != nil {
+		return nil, err
+	}
+	if wo == nil || wo.SiteId == nil {
+		return nil, fmt.Errorf("The work order %s has no site", orderNumber)
+	}
+	return reconcile(env, *wo.SiteId, &orderNumber, options)
+}
+
+// Reconciles every asset inspection at a site.
+func ReconcileSite(env *Environment, siteId string, options *ReconcileOptions) ([]AssetInspectionMismatch, error) {
+	return reconcile(env, siteId, nil, options)
+}
+
+func reconcile(env *Environment, siteId string, orderNumber *string, options *ReconcileOptions) ([]AssetInspectionMismatch, error) {
+	if options == nil {
+		options = &ReconcileOptions{}
+	}
+	tree, err := LoadSiteTree(env, siteId, &SiteTreeOptions{
+		Depth:       SiteTreeDepthResources,
+		OrderNumber: orderNumber,
+		Parallelism: options.Parallelism,
+	})
+	if err != nil {
+		return nil, err
+	}
+	mismatches := ReconcileSiteTree(tree)
+	log.Printf("GOWINDAMS: Found %d asset inspections with an unexpected status at site %s", len(mismatches), siteId)
+	if !options.Update {
+		return mismatches, nil
+	}
+	g := newWorkGroup(options.Parallelism)
+	for i := range mismatches {
+		m := &mismatches[i]
+		g.Go(func() error {
+			expected := m.Expected
+			_, m.Err = env.AssetInspectionServiceClient().Modify(stringValue(m.AssetInspection.Id), func(ai *AssetInspection) error {
+				// Someone else has moved the inspection on since the tree was loaded, so the expected status may be
+				// out of date too.
+				if stringValue(ai.Status) != m.Current {
+					return ErrStatusChanged
+				}
+				ai.Status = &expected
+				return nil
+			}, DEFAULT_UPDATE_ATTEMPTS)
+			m.Updated = m.Err == nil
+			if m.Err == ErrStatusChanged {
+				log.Printf("GOWINDAMS: Skipping asset inspection %s whose status changed from %s", stringValue(m.AssetInspection.Id), m.Current)
+			} else if m.Err != nil {
+				log.Printf("GOWINDAMS: Unable to update the status of asset inspection %s: %s", stringValue(m.AssetInspection.Id), m.Err)
+			}
+			return nil
+		})
+	}
+	return mismatches, g.Wait()
+}
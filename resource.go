@@ -1,6 +1,7 @@
 package gowindams
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -74,15 +75,21 @@ type ResourceMetadata struct {
 }
 
 type ResourceSearchCriteria struct {
-	AssetId               *string `json:"assetId"`
-	AssetInspectionId     *string `json:"assetInspectionId"`
-	ComponentId           *string `json:"componentId"`
-	ComponentInspectionId *string `json:"componentInspectionId"`
-	SourceResourceId      *string `json:"sourceResourceId"`
-	OrderNumber           *string `json:"orderNumber"`
-	Pass                  *int32  `json:"pass"`
-	SiteId                *string `json:"siteId"`
-	Status                *string `json:"status"`
+	SearchPage
+	SearchSort
+	AssetId               *string      `json:"assetId"`
+	AssetInspectionId     *string      `json:"assetInspectionId"`
+	ComponentId           *string      `json:"componentId"`
+	ComponentInspectionId *string      `json:"componentInspectionId"`
+	ContentTypes          []string     `json:"contentTypes,omitempty"`
+	SourceResourceId      *string      `json:"sourceResourceId"`
+	OrderNumber           *string      `json:"orderNumber"`
+	Pass                  *int32       `json:"pass"`
+	SiteId                *string      `json:"siteId"`
+	Status                *string      `json:"status"`
+	Statuses              []string     `json:"statuses,omitempty"`
+	TimestampFrom         *WindAMSTime `json:"timestampFrom,omitempty"`
+	TimestampTo           *WindAMSTime `json:"timestampTo,omitempty"`
 }
 
 const ResourceStatusArchived = "Archived"
@@ -97,11 +104,20 @@ type ResourceServiceClient struct {
 
 const resourceRootURI = "%s/resource"
 const resourceScaleURI = resourceRootURI + "/%s/scale"
+const resourceDeleteURI = resourceRootURI + "/%s"
 const resourceGetURI = resourceRootURI + "/%s"
 const resourceSaveURI = resourceRootURI
 const resourceSearchURI = resourceRootURI + "/search"
 const resourceUpDownloadURI = "%s/multimedia/%s"
 
+// Deletes the resource's metadata.  The binary uploaded for it is left in place by the services.
+func (client ResourceServiceClient) Delete(resourceId string) error {
+	log.Printf("Deleting resource metadata for %s", resourceId)
+	url := fmt.Sprintf(resourceDeleteURI, client.env.ServiceURI, resourceId)
+	err := executeRestCall(client.env, "DELETE", url, nil, nil)
+	return err
+}
+
 func (client ResourceServiceClient) Get(resourceId string) (*ResourceMetadata, error) {
 	log.Printf("Loading resource metadata for %s", resourceId)
 	url := fmt.Sprintf(resourceGetURI, client.env.ServiceURI, resourceId)
@@ -110,13 +126,15 @@ func (client ResourceServiceClient) Get(resourceId string) (*ResourceMetadata, e
 	return result, err
 }
 
+// Creates or replaces the resource's metadata.  Resource metadata carries no version, so the save sends no If-Match
+// precondition and never fails with a ConflictError: the last save wins.
 func (client ResourceServiceClient) Save(rmeta *ResourceMetadata) error {
 	data, err := json.Marshal(rmeta)
 	if err != nil {
 		return err
 	}
 	url := fmt.Sprintf(resourceSaveURI, client.env.ServiceURI)
-	err = executeRestCall(client.env, "POST", url, data, nil)
+	err = executeSaveCall(client.env, "POST", url, data, rmeta)
 	return err
 }
 
@@ -142,6 +160,31 @@ func (client ResourceServiceClient) Search(criteria *ResourceSearchCriteria) ([]
 	return results, err
 }
 
+type ResourceIterator struct {
+	*searchIterator
+	value *ResourceMetadata
+}
+
+func (it *ResourceIterator) Next() bool {
+	it.value = new(ResourceMetadata)
+	return it.next(it.value)
+}
+
+func (it *ResourceIterator) Value() *ResourceMetadata {
+	return it.value
+}
+
+func (client ResourceServiceClient) Iterate(ctx context.Context, criteria *ResourceSearchCriteria, pageSize int) *ResourceIterator {
+	c := ResourceSearchCriteria{}
+	if criteria != nil {
+		c = *criteria
+	}
+	url := fmt.Sprintf(resourceSearchURI, client.env.ServiceURI)
+	return &ResourceIterator{
+		searchIterator: newSearchIterator(ctx, client.env, url, &c, &c.SearchPage, pageSize),
+	}
+}
+
 func (client ResourceServiceClient) Download(resourceId string) (*io.ReadCloser, error) {
 	url := fmt.Sprintf(resourceUpDownloadURI, client.env.ServiceURI, resourceId)
 	resp, err := http.Get(url)
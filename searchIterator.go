@@ -0,0 +1,191 @@
+package gowindams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+)
+
+const DEFAULT_PAGE_SIZE = 500
+
+const SortAscending = "ASC"
+const SortDescending = "DESC"
+
+// Paging parameters, embedded in each of the search criteria types.  Pages are numbered from zero.  When left unset
+// the services return all matching results.
+type SearchPage struct {
+	Page     *int `json:"page,omitempty"`
+	PageSize *int `json:"pageSize,omitempty"`
+}
+
+// The slice of n sorted results making up the requested page, all of them when no page size is set.
+func (p SearchPage) bounds(n int) (int, int) {
+	if p.PageSize == nil || *p.PageSize <= 0 {
+		return 0, n
+	}
+	page := 0
+	if p.Page != nil && *p.Page > 0 {
+		page = *p.Page
+	}
+	lo := page * *p.PageSize
+	if lo > n {
+		lo = n
+	}
+	hi := lo + *p.PageSize
+	if hi > n {
+		hi = n
+	}
+	return lo, hi
+}
+
+// Sort order, embedded in each of the search criteria types.  SortBy is the JSON name of the field to sort on.
+type SearchSort struct {
+	SortBy        *string `json:"sortBy,omitempty"`
+	SortDirection *string `json:"sortDirection,omitempty"`
+}
+
+func (s *SearchSort) SortOn(field string, direction string) {
+	s.SortBy = &field
+	s.SortDirection = &direction
+}
+
+// Lazily fetches search results a page at a time and decodes the JSON array in each response one element at a time.
+// The typed iterators returned by each client's Iterate method wrap this, each working on its own copy of the criteria.
+// Iteration ends when a page holds fewer results than the page size, or when a page repeats the previous one, which
+// covers services that ignore the paging parameters and return everything at once.
+type searchIterator struct {
+	ctx      context.Context
+	env      *Environment
+	url      string
+	criteria interface{}
+	page     *SearchPage
+	pageSize int
+
+	body      io.ReadCloser
+	dec       *json.Decoder
+	pageCount int
+	lastFirst json.RawMessage
+	done      bool
+	err       error
+}
+
+func newSearchIterator(ctx context.Context, env *Environment, url string, criteria interface{}, page *SearchPage, pageSize int) *searchIterator {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if pageSize <= 0 {
+		pageSize = DEFAULT_PAGE_SIZE
+	}
+	first := 0
+	if page.Page != nil {
+		first = *page.Page
+	}
+	page.Page = &first
+	page.PageSize = &pageSize
+	return &searchIterator{
+		ctx:      ctx,
+		env:      env,
+		url:      url,
+		criteria: criteria,
+		page:     page,
+		pageSize: pageSize,
+	}
+}
+
+// Decodes the next result into v, returning false when there are no more results or an error occurred.
+func (it *searchIterator) next(v interface{}) bool {
+	for !it.done && it.err == nil {
+		if it.dec == nil {
+			if it.err = it.fetch(); it.err != nil {
+				break
+			}
+		}
+		if it.dec.More() {
+			if it.err = it.ctx.Err(); it.err != nil {
+				break
+			}
+			var raw json.RawMessage
+			if it.err = it.dec.Decode(&raw); it.err != nil {
+				break
+			}
+			if it.pageCount == 0 {
+				// A service which ignores the paging parameters returns the same first result for every page.
+				if *it.page.Page > 0 && bytes.Equal(raw, it.lastFirst) {
+					it.done = true
+					break
+				}
+				it.lastFirst = raw
+			}
+			if it.err = json.Unmarshal(raw, v); it.err != nil {
+				break
+			}
+			it.pageCount++
+			return true
+		}
+		// End of the current page
+		if _, it.err = it.dec.Token(); it.err != nil {
+			break
+		}
+		it.closeBody()
+		if it.pageCount != it.pageSize {
+			it.done = true
+		} else {
+			next := *it.page.Page + 1
+			it.page.Page = &next
+		}
+	}
+	it.closeBody()
+	return false
+}
+
+func (it *searchIterator) fetch() error {
+	data, err := json.Marshal(it.criteria)
+	if err != nil {
+		return err
+	}
+	resp, err := openRestCall(it.ctx, it.env, "POST", it.url, data, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		log.Printf("GOWINDAMS: Got status code %d for POST against %s: %s\n", resp.StatusCode, it.url, string(body))
+		return fmt.Errorf("Received response %d searching %s: %s", resp.StatusCode, it.url, string(body))
+	}
+	it.body = resp.Body
+	it.dec = json.NewDecoder(resp.Body)
+	it.pageCount = 0
+	tok, err := it.dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("Expected a JSON array of search results from %s, got %v", it.url, tok)
+	}
+	return nil
+}
+
+func (it *searchIterator) closeBody() {
+	if it.body != nil {
+		it.body.Close()
+		it.body = nil
+	}
+	it.dec = nil
+}
+
+func (it *searchIterator) Err() error {
+	return it.err
+}
+
+// Releases the connection held by a partially consumed iterator.
+func (it *searchIterator) Close() error {
+	it.done = true
+	it.closeBody()
+	return nil
+}
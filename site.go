@@ -1,6 +1,7 @@
 package gowindams
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -10,13 +11,18 @@ type Site struct {
 	Id             *string `json:"id"`
 	Name           *string `json:"name"`
 	OrganizationId *string `json:"organizationId"`
+	Version        *int64  `json:"version,omitempty"`
 }
 
 type SiteSearchCriteria struct {
+	SearchPage
+	SearchSort
 	OrganizationId *string `json:"organizationId"`
+	NamePrefix     *string `json:"namePrefix,omitempty"`
 }
 
 const siteRootURI = "%s/site"
+const siteDeleteURI = siteRootURI + "/%s"
 const siteGetURI = siteRootURI + "/%s"
 const siteSaveURI = siteRootURI
 const siteSearchURI = siteRootURI + "/search"
@@ -26,12 +32,23 @@ type SiteServiceClient struct {
 }
 
 func (client SiteServiceClient) Create(obj *Site) error {
+	if obj.Id == nil && client.env.GenerateIds {
+		id := NewUUID()
+		obj.Id = &id
+	}
 	data, err := json.Marshal(obj)
 	if err != nil {
 		return err
 	}
 	url := fmt.Sprintf(siteSaveURI, client.env.ServiceURI)
-	err = executeRestCall(client.env, "PUT", url, data, nil)
+	err = executeSaveCall(client.env, "PUT", url, data, obj)
+	return err
+}
+
+func (client SiteServiceClient) Delete(id string) error {
+	log.Printf("Deleting site %s", id)
+	url := fmt.Sprintf(siteDeleteURI, client.env.ServiceURI, id)
+	err := executeRestCall(client.env, "DELETE", url, nil, nil)
 	return err
 }
 
@@ -54,12 +71,37 @@ func (client SiteServiceClient) Search(criteria *SiteSearchCriteria) ([]Site, er
 	return results, err
 }
 
+type SiteIterator struct {
+	*searchIterator
+	value *Site
+}
+
+func (it *SiteIterator) Next() bool {
+	it.value = new(Site)
+	return it.next(it.value)
+}
+
+func (it *SiteIterator) Value() *Site {
+	return it.value
+}
+
+func (client SiteServiceClient) Iterate(ctx context.Context, criteria *SiteSearchCriteria, pageSize int) *SiteIterator {
+	c := SiteSearchCriteria{}
+	if criteria != nil {
+		c = *criteria
+	}
+	url := fmt.Sprintf(siteSearchURI, client.env.ServiceURI)
+	return &SiteIterator{
+		searchIterator: newSearchIterator(ctx, client.env, url, &c, &c.SearchPage, pageSize),
+	}
+}
+
 func (client SiteServiceClient) Update(obj *Site) error {
 	data, err := json.Marshal(obj)
 	if err != nil {
 		return err
 	}
 	url := fmt.Sprintf(siteSaveURI, client.env.ServiceURI)
-	err = executeRestCall(client.env, "POST", url, data, nil)
+	err = executeUpdateCall(client.env, url, data, obj, obj.Version)
 	return err
 }
@@ -0,0 +1,294 @@
+package gowindams
+
+import (
+	"log"
+	"sync"
+)
+
+// How far down the hierarchy LoadSiteTree descends.
+const (
+	SiteTreeDepthAll         = 0
+	SiteTreeDepthAssets      = 1
+	SiteTreeDepthComponents  = 2
+	SiteTreeDepthInspections = 3
+	SiteTreeDepthResources   = 4
+)
+
+type SiteTreeOptions struct {
+	Depth       int
+	OrderNumber *string
+	Parallelism int
+}
+
+type SiteTree struct {
+	Site   *Site
+	Assets []*AssetNode
+
+	assets               map[string]*AssetNode
+	components           map[string]*ComponentNode
+	assetInspections     map[string]*AssetInspectionNode
+	componentInspections map[string]*ComponentInspectionNode
+	resources            map[string]*ResourceNode
+	allResources         []*ResourceNode
+}
+
+type AssetNode struct {
+	Asset       *Asset
+	Tree        *SiteTree
+	Components  []*ComponentNode
+	Inspections []*AssetInspectionNode
+}
+
+type ComponentNode struct {
+	Component   *Component
+	Asset       *AssetNode
+	Inspections []*ComponentInspectionNode
+}
+
+type AssetInspectionNode struct {
+	AssetInspection      *AssetInspection
+	Asset                *AssetNode
+	ComponentInspections []*ComponentInspectionNode
+	// Resources belonging to the asset inspection which are not associated with one of its component inspections.
+	Resources []*ResourceNode
+}
+
+type ComponentInspectionNode struct {
+	ComponentInspection *ComponentInspection
+	Component           *ComponentNode
+	AssetInspection     *AssetInspectionNode
+	Resources           []*ResourceNode
+}
+
+type ResourceNode struct {
+	Resource            *ResourceMetadata
+	Asset               *AssetNode
+	AssetInspection     *AssetInspectionNode
+	ComponentInspection *ComponentInspectionNode
+}
+
+// Fetches a site and its descendants, issuing the searches for each level of the hierarchy concurrently.
+func LoadSiteTree(env *Environment, siteId string, options *SiteTreeOptions) (*SiteTree, error) {
+	if options == nil {
+		options = &SiteTreeOptions{}
+	}
+	depth := options.Depth
+	if depth == SiteTreeDepthAll {
+		depth = SiteTreeDepthResources
+	}
+	log.Printf("GOWINDAMS: Loading site tree for %s to depth %d", siteId, depth)
+
+	var mu sync.Mutex
+	var site *Site
+	var assets []Asset
+	var components []Component
+	var assetInspections []AssetInspection
+	var componentInspections []ComponentInspection
+	var resources []ResourceMetadata
+
+	g := newWorkGroup(options.Parallelism)
+	g.Go(func() error {
+		s, err := env.SiteServiceClient().Get(siteId)
+		site = s
+		return err
+	})
+	g.Go(func() error {
+		a, err := env.AssetServiceClient().Search(&AssetSearchCriteria{SiteId: &siteId})
+		assets = a
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	if depth >= SiteTreeDepthComponents {
+		g = newWorkGroup(options.Parallelism)
+		for i := range assets {
+			assetId := assets[i].Id
+			if assetId == nil {
+				continue
+			}
+			g.Go(func() error {
+				found, err := env.ComponentServiceClient().Search(&ComponentSearchCriteria{AssetId: assetId})
+				mu.Lock()
+				components = append(components, found...)
+				mu.Unlock()
+				return err
+			})
+			if depth >= SiteTreeDepthInspections {
+				g.Go(func() error {
+					criteria := AssetInspectionSearchCriteria{AssetId: assetId, OrderNumber: options.OrderNumber}
+					found, err := env.AssetInspectionServiceClient().Search(&criteria)
+					mu.Lock()
+					assetInspections = append(assetInspections, found...)
+					mu.Unlock()
+					return err
+				})
+			}
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+	}
+
+	if depth >= SiteTreeDepthInspections {
+		g = newWorkGroup(options.Parallelism)
+		for i := range components {
+			componentId := components[i].Id
+			if componentId == nil {
+				continue
+			}
+			g.Go(func() error {
+				criteria := ComponentInspectionSearchCriteria{ComponentId: componentId, OrderNumber: options.OrderNumber}
+				found, err := env.ComponentInspectionServiceClient().Search(&criteria)
+				mu.Lock()
+				componentInspections = append(componentInspections, found...)
+				mu.Unlock()
+				return err
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+	}
+
+	if depth >= SiteTreeDepthResources {
+		// Searching by asset inspection picks up the resources of its component inspections as well, so component
+		// inspections are only searched individually when they are not part of a loaded asset inspection.
+		loaded := make(map[string]bool)
+		searches := make([]ResourceSearchCriteria, 0, len(assetInspections))
+		for i := range assetInspections {
+			if assetInspections[i].Id != nil {
+				loaded[*assetInspections[i].Id] = true
+				searches = append(searches, ResourceSearchCriteria{AssetInspectionId: assetInspections[i].Id, OrderNumber: options.OrderNumber})
+			}
+		}
+		for i := range componentInspections {
+			if componentInspections[i].Id != nil && !loaded[stringValue(componentInspections[i].AssetInspectionId)] {
+				searches = append(searches, ResourceSearchCriteria{ComponentInspectionId: componentInspections[i].Id, OrderNumber: options.OrderNumber})
+			}
+		}
+		g = newWorkGroup(options.Parallelism)
+		for i := range searches {
+			criteria := &searches[i]
+			g.Go(func() error {
+				found, err := env.ResourceServiceClient().Search(criteria)
+				mu.Lock()
+				resources = append(resources, found...)
+				mu.Unlock()
+				return err
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+	}
+
+	return BuildSiteTree(site, assets, components, assetInspections, componentInspections, resources), nil
+}
+
+// Links already loaded entities into a navigable tree.  Entities whose parent is not present are dropped, as are
+// duplicate resources.
+func BuildSiteTree(site *Site, assets []Asset, components []Component, assetInspections []AssetInspection, componentInspections []ComponentInspection, resources []ResourceMetadata) *SiteTree {
+	tree := &SiteTree{
+		Site:                 site,
+		assets:               make(map[string]*AssetNode),
+		components:           make(map[string]*ComponentNode),
+		assetInspections:     make(map[string]*AssetInspectionNode),
+		componentInspections: make(map[string]*ComponentInspectionNode),
+		resources:            make(map[string]*ResourceNode),
+	}
+	for i := range assets {
+		node := &AssetNode{Asset: &assets[i], Tree: tree}
+		tree.Assets = append(tree.Assets, node)
+		if assets[i].Id != nil {
+			tree.assets[*assets[i].Id] = node
+		}
+	}
+	for i := range components {
+		parent := tree.Asset(stringValue(components[i].AssetId))
+		if parent == nil {
+			continue
+		}
+		node := &ComponentNode{Component: &components[i], Asset: parent}
+		parent.Components = append(parent.Components, node)
+		if components[i].Id != nil {
+			tree.components[*components[i].Id] = node
+		}
+	}
+	for i := range assetInspections {
+		parent := tree.Asset(stringValue(assetInspections[i].AssetId))
+		if parent == nil {
+			continue
+		}
+		node := &AssetInspectionNode{AssetInspection: &assetInspections[i], Asset: parent}
+		parent.Inspections = append(parent.Inspections, node)
+		if assetInspections[i].Id != nil {
+			tree.assetInspections[*assetInspections[i].Id] = node
+		}
+	}
+	for i := range componentInspections {
+		parent := tree.Component(stringValue(componentInspections[i].ComponentId))
+		if parent == nil {
+			continue
+		}
+		node := &ComponentInspectionNode{ComponentInspection: &componentInspections[i], Component: parent}
+		parent.Inspections = append(parent.Inspections, node)
+		if ai := tree.AssetInspection(stringValue(componentInspections[i].AssetInspectionId)); ai != nil {
+			node.AssetInspection = ai
+			ai.ComponentInspections = append(ai.ComponentInspections, node)
+		}
+		if componentInspections[i].Id != nil {
+			tree.componentInspections[*componentInspections[i].Id] = node
+		}
+	}
+	for i := range resources {
+		rmeta := &resources[i]
+		if rmeta.ResourceId != nil && tree.resources[*rmeta.ResourceId] != nil {
+			continue
+		}
+		node := &ResourceNode{
+			Resource:            rmeta,
+			Asset:               tree.Asset(stringValue(rmeta.AssetId)),
+			AssetInspection:     tree.AssetInspection(stringValue(rmeta.AssetInspectionId)),
+			ComponentInspection: tree.ComponentInspection(stringValue(rmeta.ComponentInspectionId)),
+		}
+		if node.ComponentInspection != nil {
+			node.ComponentInspection.Resources = append(node.ComponentInspection.Resources, node)
+		} else if node.AssetInspection != nil {
+			node.AssetInspection.Resources = append(node.AssetInspection.Resources, node)
+		} else {
+			continue
+		}
+		tree.allResources = append(tree.allResources, node)
+		if rmeta.ResourceId != nil {
+			tree.resources[*rmeta.ResourceId] = node
+		}
+	}
+	return tree
+}
+
+func (tree *SiteTree) Asset(id string) *AssetNode {
+	return tree.assets[id]
+}
+
+func (tree *SiteTree) Component(id string) *ComponentNode {
+	return tree.components[id]
+}
+
+func (tree *SiteTree) AssetInspection(id string) *AssetInspectionNode {
+	return tree.assetInspections[id]
+}
+
+func (tree *SiteTree) ComponentInspection(id string) *ComponentInspectionNode {
+	return tree.componentInspections[id]
+}
+
+func (tree *SiteTree) Resource(id string) *ResourceNode {
+	return tree.resources[id]
+}
+
+// All resources linked into the tree.
+func (tree *SiteTree) Resources() []*ResourceNode {
+	return tree.allResources
+}
@@ -0,0 +1,97 @@
+package gowindams
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Rows of text read from a spreadsheet, with the first row used as the header.
+type Table struct {
+	Header []string
+	Rows   [][]string
+
+	numbers []int
+}
+
+// The spreadsheet row number of a row, counting the header as row 1.  Blank rows are skipped when reading, so this
+// may be more than the index plus two.
+func (t *Table) RowNumber(row int) int {
+	if row < len(t.numbers) {
+		return t.numbers[row]
+	}
+	return row + 2
+}
+
+// The index of the named column, ignoring case and surrounding space, or -1.
+func (t *Table) Column(name string) int {
+	for i, h := range t.Header {
+		if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(name)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// The value of a column in a row, empty if the row is short.
+func (t *Table) Value(row int, column int) string {
+	if column < 0 || column >= len(t.Rows[row]) {
+		return ""
+	}
+	return strings.TrimSpace(t.Rows[row][column])
+}
+
+func newTable(records [][]string) (*Table, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("The table has no header row")
+	}
+	t := &Table{Header: records[0], Rows: make([][]string, 0, len(records)-1)}
+	for i, r := range records[1:] {
+		empty := true
+		for _, v := range r {
+			if strings.TrimSpace(v) != "" {
+				empty = false
+				break
+			}
+		}
+		if !empty {
+			t.Rows = append(t.Rows, r)
+			t.numbers = append(t.numbers, i+2)
+		}
+	}
+	return t, nil
+}
+
+func ReadCSVTable(r io.Reader) (*Table, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	// Spreadsheet programs often begin CSV exports with a byte order mark.
+	if len(records) > 0 && len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
+	}
+	return newTable(records)
+}
+
+// Reads a .csv or .xlsx file.  For workbooks the named sheet is read, or the first sheet if sheet is empty.
+func ReadTableFile(path string, sheet string) (*Table, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlsx":
+		return ReadXLSXTable(path, sheet)
+	case ".csv", ".txt":
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return ReadCSVTable(f)
+	default:
+		return nil, fmt.Errorf("Unable to read %s, expected a .csv or .xlsx file", path)
+	}
+}
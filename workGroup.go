@@ -0,0 +1,56 @@
+package gowindams
+
+import "sync"
+
+const DEFAULT_PARALLELISM = 8
+
+// Runs functions concurrently with at most a fixed number in flight, remembering the first error encountered.  Once
+// an error has been recorded, functions which have not yet started are skipped.
+type workGroup struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+	mu  sync.Mutex
+	err error
+}
+
+func newWorkGroup(parallelism int) *workGroup {
+	if parallelism <= 0 {
+		parallelism = DEFAULT_PARALLELISM
+	}
+	return &workGroup{
+		sem: make(chan struct{}, parallelism),
+	}
+}
+
+// Runs f once a slot is free, blocking the caller until then so that queuing a large number of functions does not
+// start a goroutine for each.  Functions must not themselves call Go on the same group.
+func (g *workGroup) Go(f func() error) {
+	g.sem <- struct{}{}
+	if g.failed() {
+		<-g.sem
+		return
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() { <-g.sem }()
+		if err := f(); err != nil {
+			g.mu.Lock()
+			if g.err == nil {
+				g.err = err
+			}
+			g.mu.Unlock()
+		}
+	}()
+}
+
+func (g *workGroup) Wait() error {
+	g.wg.Wait()
+	return g.err
+}
+
+func (g *workGroup) failed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err != nil
+}
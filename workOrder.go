@@ -1,25 +1,34 @@
 package gowindams
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 )
 
 type WorkOrder struct {
-	OrderNumber *string `json:"orderNumber"`
-	Description *string `json:"description"`
-	RequestDate *string `json:"requestDate"`
-	Scope       *string `json:"scope"`
-	SiteId      *string `json:"siteId"`
-	Status      *string `json:"status"`
-	Type        *string `json:"type"`
+	OrderNumber   *string       `json:"orderNumber"`
+	Description   *string       `json:"description"`
+	RequestDate   *WindAMSDate  `json:"requestDate"`
+	Scope         *string       `json:"scope"`
+	SiteId        *string       `json:"siteId"`
+	Status        *string       `json:"status"`
+	StatusHistory []StatusEvent `json:"statusHistory,omitempty"`
+	Type          *string       `json:"type"`
+	Version       *int64        `json:"version,omitempty"`
 }
 
 type WorkOrderSearchCriteria struct {
-	SiteId      *string  `json:"siteId"`
-	Statuses    []string `json:"statuses"`
-	Type        *string  `json:"workOrderType"`
+	SearchPage
+	SearchSort
+	SiteId          *string      `json:"siteId"`
+	Statuses        []string     `json:"statuses"`
+	Type            *string      `json:"workOrderType"`
+	Types           []string     `json:"workOrderTypes,omitempty"`
+	Scopes          []string     `json:"scopes,omitempty"`
+	RequestDateFrom *WindAMSDate `json:"requestDateFrom,omitempty"`
+	RequestDateTo   *WindAMSDate `json:"requestDateTo,omitempty"`
 }
 
 const WORK_ORDER_SCOPE_ADHOC = "adHoc"
@@ -41,6 +50,7 @@ const WORK_ORDER_STATUS_REQUESTED = "Requested"
 const WORK_ORDER_TYPE = "TurbineBladeInspection"
 
 const woRootURI = "%s/workOrder"
+const woDeleteURI = woRootURI + "/%s"
 const woGetURI = woRootURI + "/%s"
 const woSaveURI = woRootURI
 const woSearchURI = woRootURI + "/search"
@@ -55,7 +65,14 @@ func (client WorkOrderServiceClient) Create(obj *WorkOrder) error {
 		return err
 	}
 	url := fmt.Sprintf(woSaveURI, client.env.ServiceURI)
-	err = executeRestCall(client.env, "PUT", url, data, nil)
+	err = executeSaveCall(client.env, "PUT", url, data, obj)
+	return err
+}
+
+func (client WorkOrderServiceClient) Delete(orderNumber string) error {
+	log.Printf("Deleting work order %s", orderNumber)
+	url := fmt.Sprintf(woDeleteURI, client.env.ServiceURI, orderNumber)
+	err := executeRestCall(client.env, "DELETE", url, nil, nil)
 	return err
 }
 
@@ -78,12 +95,37 @@ func (client WorkOrderServiceClient) Search(criteria *WorkOrderSearchCriteria) (
 	return results, err
 }
 
+type WorkOrderIterator struct {
+	*searchIterator
+	value *WorkOrder
+}
+
+func (it *WorkOrderIterator) Next() bool {
+	it.value = new(WorkOrder)
+	return it.next(it.value)
+}
+
+func (it *WorkOrderIterator) Value() *WorkOrder {
+	return it.value
+}
+
+func (client WorkOrderServiceClient) Iterate(ctx context.Context, criteria *WorkOrderSearchCriteria, pageSize int) *WorkOrderIterator {
+	c := WorkOrderSearchCriteria{}
+	if criteria != nil {
+		c = *criteria
+	}
+	url := fmt.Sprintf(woSearchURI, client.env.ServiceURI)
+	return &WorkOrderIterator{
+		searchIterator: newSearchIterator(ctx, client.env, url, &c, &c.SearchPage, pageSize),
+	}
+}
+
 func (client WorkOrderServiceClient) Update(obj *WorkOrder) error {
 	data, err := json.Marshal(obj)
 	if err != nil {
 		return err
 	}
 	url := fmt.Sprintf(woSaveURI, client.env.ServiceURI)
-	err = executeRestCall(client.env, "POST", url, data, nil)
+	err = executeUpdateCall(client.env, url, data, obj, obj.Version)
 	return err
 }
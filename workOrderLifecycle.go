@@ -0,0 +1,136 @@
+package gowindams
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The order in which a work order moves through its statuses.
+var WorkOrderStatusOrder = []string{
+	WORK_ORDER_STATUS_REQUESTED,
+	WORK_ORDER_STATUS_ONSITE,
+	WORK_ORDER_STATUS_IMAGES_UPLOADED,
+	WORK_ORDER_STATUS_IMAGES_PROCESSED,
+	WORK_ORDER_STATUS_COMPLETED,
+}
+
+// Asset inspection statuses by how far along they are, used to check that every inspection under a work order has
+// reached a given point.
+var assetInspectionStatusRank = map[string]int{
+	ASSET_INSPECTION_STATUS_PENDING:    0,
+	ASSET_INSPECTION_STATUS_UPLOADED:   1,
+	ASSET_INSPECTION_STATUS_IN_PROCESS: 2,
+	ASSET_INSPECTION_STATUS_PROCESSED:  3,
+	ASSET_INSPECTION_STATUS_RELEASED:   4,
+}
+
+// The asset inspection status every inspection under a work order must have reached before the work order can move to
+// a status.
+var workOrderPreconditions = map[string]string{
+	WORK_ORDER_STATUS_IMAGES_UPLOADED:  ASSET_INSPECTION_STATUS_UPLOADED,
+	WORK_ORDER_STATUS_IMAGES_PROCESSED: ASSET_INSPECTION_STATUS_PROCESSED,
+	WORK_ORDER_STATUS_COMPLETED:        ASSET_INSPECTION_STATUS_RELEASED,
+}
+
+type TransitionError struct {
+	EntityType string
+	Id         string
+	From       string
+	To         string
+}
+
+func (e *TransitionError) Error() string {
+	from := e.From
+	if from == "" {
+		from = "no status"
+	}
+	return fmt.Sprintf("The %s %s cannot move from %s to %s", e.EntityType, e.Id, from, e.To)
+}
+
+type PreconditionError struct {
+	EntityType string
+	Id         string
+	To         string
+	Reasons    []string
+}
+
+func (e *PreconditionError) Error() string {
+	return fmt.Sprintf("The %s %s cannot move to %s: %s", e.EntityType, e.Id, e.To, strings.Join(e.Reasons, "; "))
+}
+
+func workOrderStatusIndex(status string) int {
+	for i, s := range WorkOrderStatusOrder {
+		if s == status {
+			return i
+		}
+	}
+	return -1
+}
+
+// Checks that a work order may move from one status to another.  Work orders move forward one status at a time, and a
+// work order without a status may only become Requested.  A work order may also return from Onsite to Requested, as
+// happens when a visit is rescheduled.
+func ValidateWorkOrderTransition(orderNumber string, from *string, to string) error {
+	current := stringValue(from)
+	i := workOrderStatusIndex(current)
+	j := workOrderStatusIndex(to)
+	allowed := j >= 0 && ((current == "" && j == 0) || (i >= 0 && j == i+1) ||
+		(current == WORK_ORDER_STATUS_ONSITE && to == WORK_ORDER_STATUS_REQUESTED))
+	if !allowed {
+		return &TransitionError{EntityType: EntityTypeWorkOrder, Id: orderNumber, From: current, To: to}
+	}
+	return nil
+}
+
+// Checks that the asset inspections under a work order allow it to move to a status, returning a *PreconditionError
+// listing each inspection holding it back.
+func CheckWorkOrderPreconditions(orderNumber string, to string, inspections []AssetInspection) error {
+	required, ok := workOrderPreconditions[to]
+	if !ok {
+		return nil
+	}
+	reasons := make([]string, 0)
+	if len(inspections) == 0 {
+		reasons = append(reasons, "there are no asset inspections for the order")
+	}
+	for _, ai := range inspections {
+		rank, known := assetInspectionStatusRank[stringValue(ai.Status)]
+		if !known || rank < assetInspectionStatusRank[required] {
+			reasons = append(reasons, fmt.Sprintf("asset inspection %s is %s rather than %s", stringValue(ai.Id), stringValue(ai.Status), required))
+		}
+	}
+	if len(reasons) > 0 {
+		return &PreconditionError{EntityType: EntityTypeWorkOrder, Id: orderNumber, To: to, Reasons: reasons}
+	}
+	return nil
+}
+
+// Moves a work order to a new status after validating the transition and checking the asset inspections for the
+// order.  A status event recording the time and the environment's UserId is appended to the work order's history.
+func (client WorkOrderServiceClient) Transition(orderNumber string, toStatus string) (*WorkOrder, error) {
+	wo, err := client.Get(orderNumber)
+	if err != nil {
+		return nil, err
+	}
+	if err = ValidateWorkOrderTransition(orderNumber, wo.Status, toStatus); err != nil {
+		return nil, err
+	}
+	if _, ok := workOrderPreconditions[toStatus]; ok {
+		inspections, err := client.env.AssetInspectionServiceClient().Search(&AssetInspectionSearchCriteria{OrderNumber: &orderNumber})
+		if err != nil {
+			return nil, err
+		}
+		if err = CheckWorkOrderPreconditions(orderNumber, toStatus, inspections); err != nil {
+			return nil, err
+		}
+	}
+	// The status is checked again in case the work order moved while the inspections were checked.
+	return client.Modify(orderNumber, func(wo *WorkOrder) error {
+		if err := ValidateWorkOrderTransition(orderNumber, wo.Status, toStatus); err != nil {
+			return err
+		}
+		wo.Status = &toStatus
+		wo.StatusHistory = append(wo.StatusHistory, newStatusEvent(client.env, toStatus))
+		return nil
+	}, DEFAULT_UPDATE_ATTEMPTS)
+}
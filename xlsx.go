@@ -0,0 +1,198 @@
+package gowindams
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Just enough of the Office Open XML spreadsheet format to read cell text from a worksheet.  Formulas are read as their
+// cached values and formatting is ignored, so dates arrive as serial day numbers; see ExcelSerialDate.
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RId  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		Id     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var sb strings.Builder
+	for _, r := range t.Runs {
+		sb.WriteString(r.T)
+	}
+	return sb.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string   `xml:"r,attr"`
+			T      string   `xml:"t,attr"`
+			V      string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func ReadXLSXTable(filePath string, sheet string) (*Table, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return readXLSX(&zr.Reader, sheet)
+}
+
+// Reads a workbook held in memory or in any other io.ReaderAt.
+func ReadXLSXTableFrom(r io.ReaderAt, size int64, sheet string) (*Table, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return readXLSX(zr, sheet)
+}
+
+func readXLSX(zr *zip.Reader, sheet string) (*Table, error) {
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	var workbook xlsxWorkbook
+	if err := readXLSXPart(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var rels xlsxRelationships
+	if err := readXLSXPart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := readXLSXPart(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	rId := ""
+	for _, s := range workbook.Sheets {
+		if sheet == "" || strings.EqualFold(s.Name, sheet) {
+			rId = s.RId
+			break
+		}
+	}
+	if rId == "" {
+		return nil, fmt.Errorf("The workbook has no sheet named %q", sheet)
+	}
+	target := ""
+	for _, rel := range rels.Relationships {
+		if rel.Id == rId {
+			target = rel.Target
+		}
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+	var ws xlsxWorksheet
+	if err := readXLSXPart(files, target, &ws); err != nil {
+		return nil, err
+	}
+
+	records := make([][]string, 0, len(ws.Rows))
+	for _, row := range ws.Rows {
+		// Rows and cells may be omitted when empty, so place each by its reference.
+		for row.R > len(records)+1 {
+			records = append(records, nil)
+		}
+		record := make([]string, 0, len(row.Cells))
+		for i, c := range row.Cells {
+			col := i
+			if c.R != "" {
+				if col, _ = xlsxColumnIndex(c.R); col < 0 {
+					return nil, fmt.Errorf("Invalid cell reference %q", c.R)
+				}
+			}
+			for len(record) < col {
+				record = append(record, "")
+			}
+			var value string
+			switch c.T {
+			case "s":
+				n, err := strconv.Atoi(c.V)
+				if err != nil || n < 0 || n >= len(shared.Items) {
+					return nil, fmt.Errorf("Invalid shared string %q in cell %s", c.V, c.R)
+				}
+				value = shared.Items[n].String()
+			case "inlineStr":
+				value = c.Inline.String()
+			case "b":
+				value = map[string]string{"0": "false", "1": "true"}[c.V]
+			default:
+				value = c.V
+			}
+			record = append(record, value)
+		}
+		records = append(records, record)
+	}
+	return newTable(records)
+}
+
+func readXLSXPart(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("The workbook is missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// The zero based column and the row number of a cell reference such as "AB12", or -1 if it is invalid.
+func xlsxColumnIndex(ref string) (int, int) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	row, err := strconv.Atoi(ref[i:])
+	if i == 0 || err != nil {
+		return -1, -1
+	}
+	return col - 1, row
+}
+
+// Converts a spreadsheet serial day number, as dates are stored in workbooks, to a date.
+func ExcelSerialDate(serial float64) time.Time {
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).Add(time.Duration(serial * 24 * float64(time.Hour))).Round(time.Second)
+}